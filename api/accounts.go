@@ -1,7 +1,6 @@
 package api
 
 import (
-	"database/sql"
 	"errors"
 	"net/http"
 
@@ -9,7 +8,6 @@ import (
 	"github.com/WanCodeBase/GinModule/token"
 
 	"github.com/gin-gonic/gin"
-	"github.com/lib/pq"
 )
 
 type createAccountReq struct {
@@ -19,7 +17,7 @@ type createAccountReq struct {
 func (server *Server) createAccount(ctx *gin.Context) {
 	var req createAccountReq
 	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
 		return
 	}
 	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
@@ -29,14 +27,7 @@ func (server *Server) createAccount(ctx *gin.Context) {
 		Currency: req.Currency,
 	})
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch pqErr.Code.Name() {
-			case "unique_violation", "foreign_key_violation":
-				ctx.JSON(http.StatusForbidden, errResponse(err))
-				return
-			}
-		}
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		ctx.JSON(statusErrResponse(err))
 		return
 	}
 	ctx.JSON(http.StatusOK, account)
@@ -49,22 +40,18 @@ type getAccountReq struct {
 func (server *Server) getAccount(ctx *gin.Context) {
 	var req getAccountReq
 	if err := ctx.ShouldBindUri(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
 		return
 	}
 	account, err := server.store.GetAccount(ctx, req.ID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errResponse(err))
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		ctx.JSON(statusErrResponse(err))
 		return
 	}
 	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 	if payload.Username != account.Owner {
 		err := errors.New("account owner is not match")
-		ctx.JSON(http.StatusUnauthorized, errResponse(err))
+		ctx.JSON(errResponse(http.StatusUnauthorized, err))
 		return
 	}
 	ctx.JSON(http.StatusOK, account)
@@ -78,7 +65,7 @@ type listAccountReq struct {
 func (server *Server) listAccount(ctx *gin.Context) {
 	var req listAccountReq
 	if err := ctx.ShouldBindQuery(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
 		return
 	}
 	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
@@ -88,7 +75,7 @@ func (server *Server) listAccount(ctx *gin.Context) {
 		Offset: req.PageID,
 	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		ctx.JSON(statusErrResponse(err))
 		return
 	}
 
@@ -36,7 +36,7 @@ func TestGetAccountApi(t *testing.T) {
 			name:      "OK",
 			accountID: account.ID,
 			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				setAuthorization(t, request, tokenMaker, username, time.Minute, authorizationHeaderType)
+				setAuthorization(t, request, tokenMaker, username, util.DepositorRole, time.Minute, authorizationHeaderType)
 			},
 			stubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(1).Return(account, nil)
@@ -50,10 +50,10 @@ func TestGetAccountApi(t *testing.T) {
 			name:      "NotFound",
 			accountID: account.ID,
 			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				setAuthorization(t, request, tokenMaker, username, time.Minute, authorizationHeaderType)
+				setAuthorization(t, request, tokenMaker, username, util.DepositorRole, time.Minute, authorizationHeaderType)
 			},
 			stubs: func(store *mockdb.MockStore) {
-				store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(1).Return(db.Account{}, db.ErrNotFound)
 			},
 			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusNotFound, recorder.Code)
@@ -63,7 +63,7 @@ func TestGetAccountApi(t *testing.T) {
 			name:      "InternalError",
 			accountID: account.ID,
 			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				setAuthorization(t, request, tokenMaker, username, time.Minute, authorizationHeaderType)
+				setAuthorization(t, request, tokenMaker, username, util.DepositorRole, time.Minute, authorizationHeaderType)
 			},
 			stubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(1).Return(db.Account{}, sql.ErrTxDone)
@@ -76,7 +76,7 @@ func TestGetAccountApi(t *testing.T) {
 			name:      "BadRequest",
 			accountID: -1,
 			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				setAuthorization(t, request, tokenMaker, username, time.Minute, authorizationHeaderType)
+				setAuthorization(t, request, tokenMaker, username, util.DepositorRole, time.Minute, authorizationHeaderType)
 			},
 			stubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), -1).Times(0).Return(db.Account{}, nil)
@@ -89,7 +89,7 @@ func TestGetAccountApi(t *testing.T) {
 			name:      "ExpiredAuth",
 			accountID: account.ID,
 			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				setAuthorization(t, request, tokenMaker, username, -time.Minute, authorizationHeaderType)
+				setAuthorization(t, request, tokenMaker, username, util.DepositorRole, -time.Minute, authorizationHeaderType)
 			},
 			stubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), -1).Times(0).Return(db.Account{}, nil)
@@ -102,7 +102,7 @@ func TestGetAccountApi(t *testing.T) {
 			name:      "WrongUser",
 			accountID: account.ID,
 			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				setAuthorization(t, request, tokenMaker, "user", time.Minute, authorizationHeaderType)
+				setAuthorization(t, request, tokenMaker, "user", util.DepositorRole, time.Minute, authorizationHeaderType)
 			},
 			stubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(1).Return(db.Account{}, nil)
@@ -149,7 +149,7 @@ func TestCreateAccountApi(t *testing.T) {
 				"currency": account.Currency,
 			},
 			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				setAuthorization(t, request, tokenMaker, username, time.Minute, authorizationHeaderType)
+				setAuthorization(t, request, tokenMaker, username, util.DepositorRole, time.Minute, authorizationHeaderType)
 			},
 			stubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
@@ -171,7 +171,7 @@ func TestCreateAccountApi(t *testing.T) {
 				"currency": "AUS",
 			},
 			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				setAuthorization(t, request, tokenMaker, username, time.Minute, authorizationHeaderType)
+				setAuthorization(t, request, tokenMaker, username, util.DepositorRole, time.Minute, authorizationHeaderType)
 			},
 			stubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
@@ -192,7 +192,7 @@ func TestCreateAccountApi(t *testing.T) {
 				"currency": account.Currency,
 			},
 			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				setAuthorization(t, request, tokenMaker, username, time.Minute, authorizationHeaderType)
+				setAuthorization(t, request, tokenMaker, username, util.DepositorRole, time.Minute, authorizationHeaderType)
 			},
 			stubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
@@ -213,7 +213,7 @@ func TestCreateAccountApi(t *testing.T) {
 				"currency": account.Currency,
 			},
 			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				setAuthorization(t, request, tokenMaker, username, -time.Minute, authorizationHeaderType)
+				setAuthorization(t, request, tokenMaker, username, util.DepositorRole, -time.Minute, authorizationHeaderType)
 			},
 			stubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
@@ -279,7 +279,7 @@ func TestListAccountsApi(t *testing.T) {
 				PageSize: int32(n),
 			},
 			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				setAuthorization(t, request, tokenMaker, lastUsername, time.Minute, authorizationHeaderType)
+				setAuthorization(t, request, tokenMaker, lastUsername, util.DepositorRole, time.Minute, authorizationHeaderType)
 			},
 			stubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
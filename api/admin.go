@@ -0,0 +1,169 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/token"
+	"github.com/gin-gonic/gin"
+)
+
+// admin handlers read any resource, the caller's role is checked by requireRole
+
+func (server *Server) adminGetUser(ctx *gin.Context) {
+	var req getUserReq
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
+		return
+	}
+	user, err := server.store.GetUser(ctx, req.Username)
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, userResponse(user))
+}
+
+func (server *Server) adminGetAccount(ctx *gin.Context) {
+	var req getAccountReq
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
+		return
+	}
+	account, err := server.store.GetAccount(ctx, req.ID)
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, account)
+}
+
+type adminSetOverdraftLimitReq struct {
+	OverdraftLimit *int64 `json:"overdraft_limit" binding:"required,min=0"`
+}
+
+// adminSetOverdraftLimit sets how far the balance of an account may go below zero.
+// A limit below the current debt of the account is rejected.
+func (server *Server) adminSetOverdraftLimit(ctx *gin.Context) {
+	var uriReq getAccountReq
+	if err := ctx.ShouldBindUri(&uriReq); err != nil {
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
+		return
+	}
+	var req adminSetOverdraftLimitReq
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
+		return
+	}
+
+	account, err := server.store.UpdateAccountOverdraftLimit(ctx, db.UpdateAccountOverdraftLimitParams{
+		ID:             uriReq.ID,
+		OverdraftLimit: *req.OverdraftLimit,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrInsufficientFunds) {
+			err = fmt.Errorf("overdraft limit is below the current debt of the account: %w", err)
+		}
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, account)
+}
+
+type adminListAccountsReq struct {
+	Owner    string `form:"owner" binding:"required,alphanum"`
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=1,max=10"`
+}
+
+func (server *Server) adminListAccounts(ctx *gin.Context) {
+	var req adminListAccountsReq
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
+		return
+	}
+	accounts, err := server.store.ListAccounts(ctx, db.ListAccountsParams{
+		Owner:  req.Owner,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, accounts)
+}
+
+type getTransferReq struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (server *Server) adminGetTransfer(ctx *gin.Context) {
+	var req getTransferReq
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
+		return
+	}
+	transfer, err := server.store.GetTransfer(ctx, req.ID)
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, transfer)
+}
+
+// adminGetTxStats reports how often database transactions were retried after serialization failures and deadlocks
+func (server *Server) adminGetTxStats(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, server.store.TxStats())
+}
+
+type adminListLockoutsReq struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=1,max=10"`
+}
+
+// adminListLockouts lists the login lockouts of a user, newest first
+func (server *Server) adminListLockouts(ctx *gin.Context) {
+	var uriReq getUserReq
+	if err := ctx.ShouldBindUri(&uriReq); err != nil {
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
+		return
+	}
+	var req adminListLockoutsReq
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
+		return
+	}
+	lockouts, err := server.store.ListLoginLockouts(ctx, db.ListLoginLockoutsParams{
+		Username: uriReq.Username,
+		Limit:    req.PageSize,
+		Offset:   (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, lockouts)
+}
+
+// adminUnlockUser lifts the login lockout and back-off of a user before it expires
+func (server *Server) adminUnlockUser(ctx *gin.Context) {
+	var req getUserReq
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
+		return
+	}
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	result, err := server.store.UnlockUserTx(ctx, db.UnlockUserTxParams{
+		Username:   req.Username,
+		UnlockedBy: payload.Username,
+	})
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
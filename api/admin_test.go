@@ -0,0 +1,373 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/WanCodeBase/GinModule/db/mock"
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/token"
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminGetAccountAPI(t *testing.T) {
+	account := randomAccount(util.RandomOwner())
+
+	testCases := []struct {
+		name      string
+		setAuth   func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		stubs     func(store *mockdb.MockStore)
+		checkResp func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				setAuthorization(t, request, tokenMaker, "admin", util.AdminRole, time.Minute, authorizationHeaderType)
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(1).Return(account, nil)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder.Body, account)
+			},
+		},
+		{
+			name: "NotFound",
+			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				setAuthorization(t, request, tokenMaker, "admin", util.AdminRole, time.Minute, authorizationHeaderType)
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(1).Return(db.Account{}, db.ErrNotFound)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "Depositor",
+			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				setAuthorization(t, request, tokenMaker, account.Owner, util.DepositorRole, time.Minute, authorizationHeaderType)
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:    "NoAuth",
+			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			c.stubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/admin/accounts/%d", account.ID)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+			c.setAuth(t, request, server.tokenMaker)
+
+			server.router.ServeHTTP(recorder, request)
+			c.checkResp(t, recorder)
+		})
+	}
+}
+
+func TestAdminGetUserAndTransferAPI(t *testing.T) {
+	user, _ := randomUser()
+	transfer := db.Transfer{
+		ID:            util.RandomInt(1, 1000),
+		FromAccountID: util.RandomInt(1, 1000),
+		ToAccountID:   util.RandomInt(1, 1000),
+		Amount:        util.RandomMoney(),
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+	store.EXPECT().GetTransfer(gomock.Any(), transfer.ID).Times(1).Return(transfer, nil)
+	server := newTestServer(t, store)
+
+	for _, url := range []string{
+		fmt.Sprintf("/admin/users/%s", user.Username),
+		fmt.Sprintf("/admin/transfers/%d", transfer.ID),
+	} {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, err)
+		setAuthorization(t, request, server.tokenMaker, "admin", util.AdminRole, time.Minute, authorizationHeaderType)
+
+		server.router.ServeHTTP(recorder, request)
+		require.Equal(t, http.StatusOK, recorder.Code)
+	}
+}
+
+func TestAdminUnlockUserAPI(t *testing.T) {
+	username := util.RandomOwner()
+
+	testCases := []struct {
+		name      string
+		setAuth   func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		stubs     func(store *mockdb.MockStore)
+		checkResp func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				setAuthorization(t, request, tokenMaker, "admin", util.AdminRole, time.Minute, authorizationHeaderType)
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UnlockUserTx(gomock.Any(), db.UnlockUserTxParams{
+					Username:   username,
+					UnlockedBy: "admin",
+				}).Times(1).Return(db.UnlockUserTxResult{UnlockedLockouts: 1}, nil)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `{"unlocked_lockouts":1}`, recorder.Body.String())
+			},
+		},
+		{
+			name: "InternalError",
+			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				setAuthorization(t, request, tokenMaker, "admin", util.AdminRole, time.Minute, authorizationHeaderType)
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UnlockUserTx(gomock.Any(), gomock.Any()).Times(1).Return(db.UnlockUserTxResult{}, sql.ErrConnDone)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "Depositor",
+			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				setAuthorization(t, request, tokenMaker, username, util.DepositorRole, time.Minute, authorizationHeaderType)
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UnlockUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			c.stubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/admin/users/%s/unlock", username)
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+
+			c.setAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			c.checkResp(t, recorder)
+		})
+	}
+}
+
+func TestAdminListLockoutsAPI(t *testing.T) {
+	username := util.RandomOwner()
+	lockouts := []db.LoginLockout{
+		{ID: 2, Username: username, ClientIp: "127.0.0.1", FailedAttempts: 5},
+		{ID: 1, Username: username, ClientIp: "127.0.0.1", FailedAttempts: 5},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ListLoginLockouts(gomock.Any(), db.ListLoginLockoutsParams{
+		Username: username,
+		Limit:    5,
+		Offset:   0,
+	}).Times(1).Return(lockouts, nil)
+	server := newTestServer(t, store)
+
+	recorder := httptest.NewRecorder()
+	url := fmt.Sprintf("/admin/users/%s/lockouts?page_id=1&page_size=5", username)
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	setAuthorization(t, request, server.tokenMaker, "admin", util.AdminRole, time.Minute, authorizationHeaderType)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), `"failed_attempts":5`)
+}
+
+func TestAdminGetTxStatsAPI(t *testing.T) {
+	stats := db.TxStats{Retries: 7, Recovered: 3, Exhausted: 1}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().TxStats().Times(1).Return(stats)
+	server := newTestServer(t, store)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/admin/stats/tx", nil)
+	require.NoError(t, err)
+	setAuthorization(t, request, server.tokenMaker, "admin", util.AdminRole, time.Minute, authorizationHeaderType)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var got db.TxStats
+	err = json.Unmarshal(recorder.Body.Bytes(), &got)
+	require.NoError(t, err)
+	require.Equal(t, stats, got)
+}
+
+func TestAdminSetOverdraftLimitAPI(t *testing.T) {
+	account := randomAccount(util.RandomOwner())
+
+	testCases := []struct {
+		name      string
+		body      gin.H
+		role      string
+		stubs     func(store *mockdb.MockStore)
+		checkResp func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"overdraft_limit": 500},
+			role: util.AdminRole,
+			stubs: func(store *mockdb.MockStore) {
+				updated := account
+				updated.OverdraftLimit = 500
+				store.EXPECT().UpdateAccountOverdraftLimit(gomock.Any(), db.UpdateAccountOverdraftLimitParams{
+					ID:             account.ID,
+					OverdraftLimit: 500,
+				}).Times(1).Return(updated, nil)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got db.Account
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.EqualValues(t, 500, got.OverdraftLimit)
+			},
+		},
+		{
+			name: "NoOverdraft",
+			body: gin.H{"overdraft_limit": 0},
+			role: util.AdminRole,
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateAccountOverdraftLimit(gomock.Any(), db.UpdateAccountOverdraftLimitParams{
+					ID:             account.ID,
+					OverdraftLimit: 0,
+				}).Times(1).Return(account, nil)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "BelowDebt",
+			body: gin.H{"overdraft_limit": 0},
+			role: util.AdminRole,
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateAccountOverdraftLimit(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.Account{}, db.ErrInsufficientFunds)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "NegativeLimit",
+			body: gin.H{"overdraft_limit": -1},
+			role: util.AdminRole,
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateAccountOverdraftLimit(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			body: gin.H{"overdraft_limit": 500},
+			role: util.AdminRole,
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateAccountOverdraftLimit(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.Account{}, db.ErrNotFound)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "Depositor",
+			body: gin.H{"overdraft_limit": 500},
+			role: util.DepositorRole,
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateAccountOverdraftLimit(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			c.stubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			body, err := json.Marshal(c.body)
+			require.NoError(t, err)
+			url := fmt.Sprintf("/admin/accounts/%d/overdraft_limit", account.ID)
+			request, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(body))
+			require.NoError(t, err)
+			setAuthorization(t, request, server.tokenMaker, account.Owner, c.role, time.Minute, authorizationHeaderType)
+
+			server.router.ServeHTTP(recorder, request)
+			c.checkResp(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/token"
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/gin-gonic/gin"
+)
+
+// api keys look like sbk_<prefix>_<secret>, only the prefix is stored in clear
+const (
+	apiKeyTag         = "sbk"
+	apiKeyPrefixBytes = 8
+	apiKeySecretBytes = 32
+	apiKeySeparator   = "_"
+)
+
+var (
+	errInvalidAPIKey = errors.New("api key is invalid")
+	errRevokedAPIKey = errors.New("api key has been revoked")
+)
+
+type apiKeyResp struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	KeyPrefix string    `json:"key_prefix"`
+	Scopes    []string  `json:"scopes"`
+	IsRevoked bool      `json:"is_revoked"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func apiKeyResponse(apiKey db.ApiKey) apiKeyResp {
+	return apiKeyResp{
+		ID:        apiKey.ID,
+		Name:      apiKey.Name,
+		KeyPrefix: apiKey.KeyPrefix,
+		Scopes:    apiKey.Scopes,
+		IsRevoked: apiKey.IsRevoked,
+		CreatedAt: apiKey.CreatedAt,
+	}
+}
+
+type createAPIKeyReq struct {
+	Name   string   `json:"name" binding:"required"`
+	Scopes []string `json:"scopes" binding:"required,min=1,dive,scope"`
+}
+
+type createAPIKeyResp struct {
+	Key    string     `json:"key"`
+	APIKey apiKeyResp `json:"api_key"`
+}
+
+// createAPIKey returns the full key, which is never shown again
+func (server *Server) createAPIKey(ctx *gin.Context) {
+	var req createAPIKeyReq
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
+		return
+	}
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	for _, scope := range req.Scopes {
+		if scope == util.TokensIntrospectScope && payload.Role != util.AdminRole {
+			err := fmt.Errorf("only admins can grant scope %s", scope)
+			ctx.JSON(errResponse(http.StatusForbidden, err))
+			return
+		}
+	}
+
+	prefix, err := util.RandomSecret(apiKeyPrefixBytes)
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	secret, err := util.RandomSecret(apiKeySecretBytes)
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	key := strings.Join([]string{apiKeyTag, prefix, secret}, apiKeySeparator)
+
+	apiKey, err := server.store.CreateAPIKey(ctx, db.CreateAPIKeyParams{
+		Username:  payload.Username,
+		Name:      req.Name,
+		KeyPrefix: prefix,
+		HashedKey: util.HashSecret(key),
+		Scopes:    req.Scopes,
+	})
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, createAPIKeyResp{
+		Key:    key,
+		APIKey: apiKeyResponse(apiKey),
+	})
+}
+
+func (server *Server) listAPIKeys(ctx *gin.Context) {
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	apiKeys, err := server.store.ListAPIKeys(ctx, payload.Username)
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	resp := make([]apiKeyResp, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		resp = append(resp, apiKeyResponse(apiKey))
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type revokeAPIKeyReq struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (server *Server) revokeAPIKey(ctx *gin.Context) {
+	var req revokeAPIKeyReq
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
+		return
+	}
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	apiKey, err := server.store.RevokeAPIKey(ctx, db.RevokeAPIKeyParams{
+		ID:       req.ID,
+		Username: payload.Username,
+	})
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, apiKeyResponse(apiKey))
+}
+
+// verifyAPIKey returns the stored api key matching key,
+// errInvalidAPIKey or errRevokedAPIKey if the key cannot be used
+func verifyAPIKey(ctx *gin.Context, store db.Store, key string) (db.ApiKey, error) {
+	parts := strings.Split(key, apiKeySeparator)
+	if len(parts) != 3 || parts[0] != apiKeyTag {
+		return db.ApiKey{}, errInvalidAPIKey
+	}
+
+	apiKey, err := store.GetAPIKeyByPrefix(ctx, parts[1])
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return db.ApiKey{}, errInvalidAPIKey
+		}
+		return db.ApiKey{}, fmt.Errorf("get api key failed:%w", err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(apiKey.HashedKey), []byte(util.HashSecret(key))) != 1 {
+		return db.ApiKey{}, errInvalidAPIKey
+	}
+	if apiKey.IsRevoked {
+		return db.ApiKey{}, errRevokedAPIKey
+	}
+	return apiKey, nil
+}
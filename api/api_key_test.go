@@ -0,0 +1,333 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/WanCodeBase/GinModule/db/mock"
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/token"
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func randomAPIKey(t *testing.T, username string, scopes ...string) (string, db.ApiKey) {
+	prefix, err := util.RandomSecret(apiKeyPrefixBytes)
+	require.NoError(t, err)
+	secret, err := util.RandomSecret(apiKeySecretBytes)
+	require.NoError(t, err)
+
+	key := fmt.Sprintf("%s_%s_%s", apiKeyTag, prefix, secret)
+	return key, db.ApiKey{
+		ID:        util.RandomInt(1, 1000),
+		Username:  username,
+		Name:      util.RandomString(6),
+		KeyPrefix: prefix,
+		HashedKey: util.HashSecret(key),
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+	}
+}
+
+func TestCreateAPIKeyAPI(t *testing.T) {
+	username := util.RandomOwner()
+
+	testCases := []struct {
+		name      string
+		body      gin.H
+		setAuth   func(t *testing.T, request *http.Request, tokenMaker token.Maker, store *mockdb.MockStore)
+		stubs     func(store *mockdb.MockStore)
+		checkResp func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"name": "reconciliation", "scopes": []string{util.AccountsReadScope}},
+			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, store *mockdb.MockStore) {
+				setAuthorization(t, request, tokenMaker, username, util.DepositorRole, time.Minute, authorizationHeaderType)
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateAPIKeyParams) (db.ApiKey, error) {
+						require.Equal(t, username, arg.Username)
+						require.Equal(t, []string{util.AccountsReadScope}, arg.Scopes)
+						return db.ApiKey{
+							ID:        1,
+							Username:  arg.Username,
+							Name:      arg.Name,
+							KeyPrefix: arg.KeyPrefix,
+							HashedKey: arg.HashedKey,
+							Scopes:    arg.Scopes,
+						}, nil
+					})
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp createAPIKeyResp
+				err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+				require.NoError(t, err)
+				require.Contains(t, resp.Key, resp.APIKey.KeyPrefix)
+				require.NotContains(t, recorder.Body.String(), "hashed_key")
+			},
+		},
+		{
+			name: "UnsupportedScope",
+			body: gin.H{"name": "reconciliation", "scopes": []string{"accounts:delete"}},
+			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, store *mockdb.MockStore) {
+				setAuthorization(t, request, tokenMaker, username, util.DepositorRole, time.Minute, authorizationHeaderType)
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NoScopes",
+			body: gin.H{"name": "reconciliation", "scopes": []string{}},
+			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, store *mockdb.MockStore) {
+				setAuthorization(t, request, tokenMaker, username, util.DepositorRole, time.Minute, authorizationHeaderType)
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "IntrospectScopeByAdmin",
+			body: gin.H{"name": "gateway", "scopes": []string{util.TokensIntrospectScope}},
+			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, store *mockdb.MockStore) {
+				setAuthorization(t, request, tokenMaker, username, util.AdminRole, time.Minute, authorizationHeaderType)
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(1).Return(db.ApiKey{ID: 1}, nil)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "IntrospectScopeByDepositor",
+			body: gin.H{"name": "gateway", "scopes": []string{util.TokensIntrospectScope}},
+			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, store *mockdb.MockStore) {
+				setAuthorization(t, request, tokenMaker, username, util.DepositorRole, time.Minute, authorizationHeaderType)
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "AuthorizedByAPIKey",
+			body: gin.H{"name": "reconciliation", "scopes": []string{util.AccountsReadScope}},
+			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, store *mockdb.MockStore) {
+				key, apiKey := randomAPIKey(t, username, util.AccountsReadScope, util.AccountsWriteScope)
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), apiKey.KeyPrefix).Times(1).Return(apiKey, nil)
+				request.Header.Set(authorizationHeaderKey, "ApiKey "+key)
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			c.stubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			body, err := json.Marshal(c.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/api_keys", bytes.NewReader(body))
+			require.NoError(t, err)
+			c.setAuth(t, request, server.tokenMaker, store)
+
+			server.router.ServeHTTP(recorder, request)
+			c.checkResp(t, recorder)
+		})
+	}
+}
+
+func TestRevokeAPIKeyAPI(t *testing.T) {
+	username := util.RandomOwner()
+	_, apiKey := randomAPIKey(t, username, util.AccountsReadScope)
+
+	testCases := []struct {
+		name      string
+		stubs     func(store *mockdb.MockStore)
+		checkResp func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			stubs: func(store *mockdb.MockStore) {
+				revoked := apiKey
+				revoked.IsRevoked = true
+				store.EXPECT().
+					RevokeAPIKey(gomock.Any(), db.RevokeAPIKeyParams{ID: apiKey.ID, Username: username}).
+					Times(1).
+					Return(revoked, nil)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RevokeAPIKey(gomock.Any(), db.RevokeAPIKeyParams{ID: apiKey.ID, Username: username}).
+					Times(1).
+					Return(db.ApiKey{}, db.ErrNotFound)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			c.stubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/api_keys/%d", apiKey.ID)
+			request, err := http.NewRequest(http.MethodDelete, url, nil)
+			require.NoError(t, err)
+			setAuthorization(t, request, server.tokenMaker, username, util.DepositorRole, time.Minute, authorizationHeaderType)
+
+			server.router.ServeHTTP(recorder, request)
+			c.checkResp(t, recorder)
+		})
+	}
+}
+
+func TestAPIKeyAuthorization(t *testing.T) {
+	username := util.RandomOwner()
+	account := randomAccount(username)
+
+	testCases := []struct {
+		name      string
+		buildKey  func(t *testing.T) (string, db.ApiKey)
+		stubs     func(store *mockdb.MockStore, apiKey db.ApiKey)
+		checkResp func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildKey: func(t *testing.T) (string, db.ApiKey) {
+				return randomAPIKey(t, username, util.AccountsReadScope)
+			},
+			stubs: func(store *mockdb.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), apiKey.KeyPrefix).Times(1).Return(apiKey, nil)
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(1).Return(account, nil)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "MissingScope",
+			buildKey: func(t *testing.T) (string, db.ApiKey) {
+				return randomAPIKey(t, username, util.TransfersWriteScope)
+			},
+			stubs: func(store *mockdb.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), apiKey.KeyPrefix).Times(1).Return(apiKey, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "Revoked",
+			buildKey: func(t *testing.T) (string, db.ApiKey) {
+				key, apiKey := randomAPIKey(t, username, util.AccountsReadScope)
+				apiKey.IsRevoked = true
+				return key, apiKey
+			},
+			stubs: func(store *mockdb.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), apiKey.KeyPrefix).Times(1).Return(apiKey, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "WrongSecret",
+			buildKey: func(t *testing.T) (string, db.ApiKey) {
+				_, apiKey := randomAPIKey(t, username, util.AccountsReadScope)
+				return fmt.Sprintf("%s_%s_%s", apiKeyTag, apiKey.KeyPrefix, "wrong"), apiKey
+			},
+			stubs: func(store *mockdb.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), apiKey.KeyPrefix).Times(1).Return(apiKey, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "UnknownKey",
+			buildKey: func(t *testing.T) (string, db.ApiKey) {
+				return randomAPIKey(t, username, util.AccountsReadScope)
+			},
+			stubs: func(store *mockdb.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), apiKey.KeyPrefix).Times(1).Return(db.ApiKey{}, db.ErrNotFound)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			key, apiKey := c.buildKey(t)
+			c.stubs(store, apiKey)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/account/%d", account.ID)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, "ApiKey "+key)
+
+			server.router.ServeHTTP(recorder, request)
+			c.checkResp(t, recorder)
+		})
+	}
+}
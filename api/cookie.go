@@ -0,0 +1,140 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultAccessTokenCookie  = "access_token"
+	defaultRefreshTokenCookie = "refresh_token"
+	defaultCSRFCookie         = "csrf_token"
+
+	csrfHeaderKey  = "X-CSRF-Token"
+	csrfTokenBytes = 32
+
+	// the refresh token is only sent to the endpoint that renews the access token
+	refreshTokenCookiePath = "/tokens/renew_access"
+)
+
+var errInvalidCSRFToken = errors.New("csrf token is missing or does not match")
+
+// authCookies is the cookie mode of the web dashboard. Login sets the tokens as HttpOnly cookies,
+// out of reach of scripts, and a csrf cookie the dashboard reads and echoes in the X-CSRF-Token header
+// of every state-changing request. A cross-site form can send the cookies but cannot read the csrf cookie.
+type authCookies struct {
+	accessTokenName  string
+	refreshTokenName string
+	csrfName         string
+	domain           string
+	sameSite         http.SameSite
+}
+
+// newAuthCookies returns nil unless AUTH_COOKIE_ENABLED is set
+func newAuthCookies(config util.Config) (*authCookies, error) {
+	if !config.AuthCookieEnabled {
+		return nil, nil
+	}
+
+	cookies := &authCookies{
+		accessTokenName:  config.AuthCookieName,
+		refreshTokenName: defaultRefreshTokenCookie,
+		csrfName:         defaultCSRFCookie,
+		domain:           config.AuthCookieDomain,
+	}
+	if len(cookies.accessTokenName) == 0 {
+		cookies.accessTokenName = defaultAccessTokenCookie
+	}
+
+	switch strings.ToLower(config.AuthCookieSameSite) {
+	case "lax", "":
+		cookies.sameSite = http.SameSiteLaxMode
+	case "strict":
+		cookies.sameSite = http.SameSiteStrictMode
+	case "none":
+		cookies.sameSite = http.SameSiteNoneMode
+	default:
+		return nil, fmt.Errorf("unsupported cookie same site mode %q", config.AuthCookieSameSite)
+	}
+	return cookies, nil
+}
+
+// SetLogin sets the tokens of a login and a new csrf token
+func (c *authCookies) SetLogin(ctx *gin.Context, resp loginUserResp) error {
+	csrfToken, err := util.RandomSecret(csrfTokenBytes)
+	if err != nil {
+		return err
+	}
+	c.set(ctx, c.accessTokenName, resp.AccessToken, "/", resp.AccessTokenExpiresAt, true)
+	c.set(ctx, c.refreshTokenName, resp.RefreshToken, refreshTokenCookiePath, resp.RefreshTokenExpiresAt, true)
+	// the csrf cookie lives as long as the session, scripts have to read it
+	c.set(ctx, c.csrfName, csrfToken, "/", resp.RefreshTokenExpiresAt, false)
+	return nil
+}
+
+// SetAccessToken replaces the access token after a renewal
+func (c *authCookies) SetAccessToken(ctx *gin.Context, accessToken string, expiresAt time.Time) {
+	c.set(ctx, c.accessTokenName, accessToken, "/", expiresAt, true)
+}
+
+// Clear deletes every cookie of the login
+func (c *authCookies) Clear(ctx *gin.Context) {
+	c.set(ctx, c.accessTokenName, "", "/", time.Unix(0, 0), true)
+	c.set(ctx, c.refreshTokenName, "", refreshTokenCookiePath, time.Unix(0, 0), true)
+	c.set(ctx, c.csrfName, "", "/", time.Unix(0, 0), false)
+}
+
+func (c *authCookies) set(ctx *gin.Context, name, value, path string, expiresAt time.Time, httpOnly bool) {
+	maxAge := int(time.Until(expiresAt).Seconds())
+	if maxAge <= 0 {
+		maxAge = -1
+	}
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   c.domain,
+		Expires:  expiresAt,
+		MaxAge:   maxAge,
+		Secure:   true,
+		HttpOnly: httpOnly,
+		SameSite: c.sameSite,
+	})
+}
+
+// AccessToken returns the access token of the cookie, if any
+func (c *authCookies) AccessToken(ctx *gin.Context) (string, bool) {
+	accessToken, err := ctx.Cookie(c.accessTokenName)
+	return accessToken, err == nil && len(accessToken) > 0
+}
+
+// RefreshToken returns the refresh token of the cookie, if any
+func (c *authCookies) RefreshToken(ctx *gin.Context) (string, bool) {
+	refreshToken, err := ctx.Cookie(c.refreshTokenName)
+	return refreshToken, err == nil && len(refreshToken) > 0
+}
+
+// CheckCSRF requires the X-CSRF-Token header to match the csrf cookie on state-changing requests
+func (c *authCookies) CheckCSRF(ctx *gin.Context) error {
+	switch ctx.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return nil
+	}
+
+	csrfCookie, err := ctx.Cookie(c.csrfName)
+	if err != nil || len(csrfCookie) == 0 {
+		return errInvalidCSRFToken
+	}
+	csrfHeader := ctx.GetHeader(csrfHeaderKey)
+	if subtle.ConstantTimeCompare([]byte(csrfCookie), []byte(csrfHeader)) != 1 {
+		return errInvalidCSRFToken
+	}
+	return nil
+}
@@ -0,0 +1,316 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/WanCodeBase/GinModule/db/mock"
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/token"
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func newCookieTestServer(t *testing.T, store db.Store) *Server {
+	config := newTestConfig()
+	config.AuthCookieEnabled = true
+	config.AuthCookieSameSite = "strict"
+
+	server, err := NewServer(config, store)
+	require.NoError(t, err)
+	return server
+}
+
+func responseCookies(recorder *httptest.ResponseRecorder) map[string]*http.Cookie {
+	cookies := make(map[string]*http.Cookie)
+	for _, cookie := range recorder.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	return cookies
+}
+
+func TestNewAuthCookies(t *testing.T) {
+	cookies, err := newAuthCookies(util.Config{})
+	require.NoError(t, err)
+	require.Nil(t, cookies)
+
+	cookies, err = newAuthCookies(util.Config{AuthCookieEnabled: true, AuthCookieName: "session"})
+	require.NoError(t, err)
+	require.Equal(t, "session", cookies.accessTokenName)
+	require.Equal(t, http.SameSiteLaxMode, cookies.sameSite)
+
+	_, err = newAuthCookies(util.Config{AuthCookieEnabled: true, AuthCookieSameSite: "sometimes"})
+	require.Error(t, err)
+}
+
+func TestLoginCookieAPI(t *testing.T) {
+	user, password := randomUser()
+	hashedPassword, err := util.HashedPassword(password)
+	require.NoError(t, err)
+	user.HashedPassword = hashedPassword
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	expectLoginAllowed(store)
+	store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+	store.EXPECT().DeleteLoginThrottle(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+	store.EXPECT().GetUserTOTP(gomock.Any(), user.Username).Times(1).Return(db.UserTotp{}, db.ErrNotFound)
+	store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, nil)
+	expectLoginSuccess(store)
+
+	server := newCookieTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	body, err := json.Marshal(gin.H{"username": user.Username, "password": password})
+	require.NoError(t, err)
+	request, err := http.NewRequest(http.MethodPost, "/user/login", bytes.NewReader(body))
+	require.NoError(t, err)
+	setClientInfo(request)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	// scripts of the page cannot read the tokens
+	require.NotContains(t, recorder.Body.String(), `"access_token":`)
+	require.NotContains(t, recorder.Body.String(), `"refresh_token":`)
+
+	cookies := responseCookies(recorder)
+	accessCookie := cookies[defaultAccessTokenCookie]
+	require.NotNil(t, accessCookie)
+	_, err = server.tokenMaker.VerifyToken(accessCookie.Value)
+	require.NoError(t, err)
+	require.Equal(t, "/", accessCookie.Path)
+	require.True(t, accessCookie.HttpOnly)
+	require.True(t, accessCookie.Secure)
+	require.Equal(t, http.SameSiteStrictMode, accessCookie.SameSite)
+
+	refreshCookie := cookies[defaultRefreshTokenCookie]
+	require.NotNil(t, refreshCookie)
+	refreshPayload, err := server.tokenMaker.VerifyToken(refreshCookie.Value)
+	require.NoError(t, err)
+	require.Equal(t, refreshTokenPurpose, refreshPayload.Purpose)
+	require.Equal(t, refreshTokenCookiePath, refreshCookie.Path)
+	require.True(t, refreshCookie.HttpOnly)
+
+	csrfCookie := cookies[defaultCSRFCookie]
+	require.NotNil(t, csrfCookie)
+	require.NotEmpty(t, csrfCookie.Value)
+	require.False(t, csrfCookie.HttpOnly)
+	require.True(t, csrfCookie.Secure)
+}
+
+func TestCookieAuthorizationAPI(t *testing.T) {
+	user, _ := randomUser()
+	session := randomUserSession(user.Username)
+	csrfToken := util.RandomString(32)
+
+	testCases := []struct {
+		name      string
+		method    string
+		url       string
+		setCookie func(t *testing.T, request *http.Request, server *Server)
+		stubs     func(store *mockdb.MockStore)
+		checkResp func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "ReadWithoutCSRFToken",
+			method: http.MethodGet,
+			url:    "/user/sessions",
+			setCookie: func(t *testing.T, request *http.Request, server *Server) {
+				accessToken, _, err := server.tokenMaker.CreateToken(user.Username, util.DepositorRole, time.Minute)
+				require.NoError(t, err)
+				request.AddCookie(&http.Cookie{Name: defaultAccessTokenCookie, Value: accessToken})
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListUserSessions(gomock.Any(), user.Username).Times(1).Return([]db.Session{}, nil)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "WriteWithCSRFToken",
+			method: http.MethodPost,
+			url:    "/user/logout",
+			setCookie: func(t *testing.T, request *http.Request, server *Server) {
+				accessToken, _, err := server.tokenMaker.CreateToken(user.Username, util.DepositorRole, time.Minute,
+					token.WithSessionID(session.ID))
+				require.NoError(t, err)
+				request.AddCookie(&http.Cookie{Name: defaultAccessTokenCookie, Value: accessToken})
+				request.AddCookie(&http.Cookie{Name: defaultCSRFCookie, Value: csrfToken})
+				request.Header.Set(csrfHeaderKey, csrfToken)
+			},
+			stubs: func(store *mockdb.MockStore) {
+				// the refresh cookie is not sent to logout, the session of the access token is blocked
+				arg := db.BlockUserSessionParams{ID: session.ID, Username: user.Username}
+				store.EXPECT().BlockUserSession(gomock.Any(), arg).Times(1).Return(session, nil)
+				store.EXPECT().CreateRevokedToken(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				// logging out deletes the cookies
+				accessCookie := responseCookies(recorder)[defaultAccessTokenCookie]
+				require.NotNil(t, accessCookie)
+				require.Empty(t, accessCookie.Value)
+				require.Negative(t, accessCookie.MaxAge)
+			},
+		},
+		{
+			name:   "WriteWithoutCSRFToken",
+			method: http.MethodPost,
+			url:    "/user/logout",
+			setCookie: func(t *testing.T, request *http.Request, server *Server) {
+				accessToken, _, err := server.tokenMaker.CreateToken(user.Username, util.DepositorRole, time.Minute)
+				require.NoError(t, err)
+				request.AddCookie(&http.Cookie{Name: defaultAccessTokenCookie, Value: accessToken})
+				request.AddCookie(&http.Cookie{Name: defaultCSRFCookie, Value: csrfToken})
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateRevokedToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "WriteWithMismatchedCSRFToken",
+			method: http.MethodPost,
+			url:    "/user/logout",
+			setCookie: func(t *testing.T, request *http.Request, server *Server) {
+				accessToken, _, err := server.tokenMaker.CreateToken(user.Username, util.DepositorRole, time.Minute)
+				require.NoError(t, err)
+				request.AddCookie(&http.Cookie{Name: defaultAccessTokenCookie, Value: accessToken})
+				request.AddCookie(&http.Cookie{Name: defaultCSRFCookie, Value: csrfToken})
+				request.Header.Set(csrfHeaderKey, util.RandomString(32))
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateRevokedToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "InvalidAccessTokenCookie",
+			method: http.MethodGet,
+			url:    "/user/sessions",
+			setCookie: func(t *testing.T, request *http.Request, server *Server) {
+				request.AddCookie(&http.Cookie{Name: defaultAccessTokenCookie, Value: "invalid"})
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListUserSessions(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "NoCookie",
+			method: http.MethodGet,
+			url:    "/user/sessions",
+			setCookie: func(t *testing.T, request *http.Request, server *Server) {
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListUserSessions(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			c.stubs(store)
+
+			server := newCookieTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(c.method, c.url, nil)
+			require.NoError(t, err)
+			c.setCookie(t, request, server)
+
+			server.router.ServeHTTP(recorder, request)
+			c.checkResp(t, recorder)
+		})
+	}
+}
+
+func TestRenewAccessTokenCookieAPI(t *testing.T) {
+	user, _ := randomUser()
+	csrfToken := util.RandomString(32)
+
+	testCases := []struct {
+		name      string
+		csrfToken string
+		checkResp func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "OK",
+			csrfToken: csrfToken,
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp renewAccessTokenResp
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.Empty(t, resp.AccessToken)
+				require.NotContains(t, recorder.Body.String(), `"access_token":`)
+				require.False(t, resp.AccessTokenExpiresAt.IsZero())
+				accessCookie := responseCookies(recorder)[defaultAccessTokenCookie]
+				require.NotNil(t, accessCookie)
+				require.NotEmpty(t, accessCookie.Value)
+				require.True(t, accessCookie.HttpOnly)
+			},
+		},
+		{
+			name:      "NoCSRFToken",
+			csrfToken: "",
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Empty(t, recorder.Result().Cookies())
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newCookieTestServer(t, store)
+			refreshToken, session := randomSession(t, server.tokenMaker, user.Username, time.Hour)
+			if len(c.csrfToken) > 0 {
+				store.EXPECT().GetSession(gomock.Any(), session.ID).Times(1).Return(session, nil)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+			} else {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Any()).Times(0)
+			}
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, "/tokens/renew_access", nil)
+			require.NoError(t, err)
+			setClientInfo(request)
+			request.AddCookie(&http.Cookie{Name: defaultRefreshTokenCookie, Value: refreshToken})
+			request.AddCookie(&http.Cookie{Name: defaultCSRFCookie, Value: csrfToken})
+			if len(c.csrfToken) > 0 {
+				request.Header.Set(csrfHeaderKey, c.csrfToken)
+			}
+
+			server.router.ServeHTTP(recorder, request)
+			c.checkResp(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/gin-gonic/gin"
+)
+
+// error codes of every error body, clients branch on them instead of on messages
+const (
+	errCodeBadRequest           = "bad_request"
+	errCodeUnauthorized         = "unauthorized"
+	errCodeForbidden            = "forbidden"
+	errCodeTooManyRequests      = "too_many_requests"
+	errCodeNotFound             = "not_found"
+	errCodeConflict             = "conflict"
+	errCodeInsufficientFunds    = "insufficient_funds"
+	errCodeCurrencyMismatch     = "currency_mismatch"
+	errCodeSerializationFailure = "serialization_failure"
+	errCodeInternal             = "internal"
+)
+
+// statusErrCodes are the codes of the statuses a handler rejects a request with itself
+var statusErrCodes = map[int]string{
+	http.StatusBadRequest:      errCodeBadRequest,
+	http.StatusUnauthorized:    errCodeUnauthorized,
+	http.StatusForbidden:       errCodeForbidden,
+	http.StatusNotFound:        errCodeNotFound,
+	http.StatusConflict:        errCodeConflict,
+	http.StatusTooManyRequests: errCodeTooManyRequests,
+}
+
+// errInternal is the message of every internal server error, the cause is only logged
+var errInternal = errors.New("internal server error")
+
+// domainErrs are the domain errors of the store with their status and code
+var domainErrs = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{db.ErrNotFound, http.StatusNotFound, errCodeNotFound},
+	{db.ErrConflict, http.StatusConflict, errCodeConflict},
+	{db.ErrInsufficientFunds, http.StatusUnprocessableEntity, errCodeInsufficientFunds},
+	{db.ErrCurrencyMismatch, http.StatusUnprocessableEntity, errCodeCurrencyMismatch},
+	// the transaction was rolled back, the client may retry
+	{db.ErrSerialization, http.StatusServiceUnavailable, errCodeSerializationFailure},
+}
+
+// errResponse is the status and body of a request the handler rejects with status because of err, for ctx.JSON.
+// Every error body goes through errResponse or statusErrResponse, so it always has a code.
+func errResponse(status int, err error) (int, gin.H) {
+	code, ok := statusErrCodes[status]
+	if !ok {
+		return statusErrResponse(err)
+	}
+	return status, gin.H{"error": err.Error(), "code": code}
+}
+
+// statusErrResponse is the status and body of a request that failed with err, for ctx.JSON.
+// Domain errors of the store get their status and code, any other error is an internal server error
+// whose message may hold driver or SQL details, it is logged and the client gets a generic one.
+func statusErrResponse(err error) (int, gin.H) {
+	for _, domainErr := range domainErrs {
+		if errors.Is(err, domainErr.err) {
+			return domainErr.status, gin.H{"error": err.Error(), "code": domainErr.code}
+		}
+	}
+	log.Println("internal server error:", err)
+	return http.StatusInternalServerError, gin.H{"error": errInternal.Error(), "code": errCodeInternal}
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusErrResponse(t *testing.T) {
+	testCases := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{db.ErrNotFound, http.StatusNotFound, errCodeNotFound},
+		{db.ErrConflict, http.StatusConflict, errCodeConflict},
+		{fmt.Errorf("transfer failed: %w", db.ErrInsufficientFunds), http.StatusUnprocessableEntity, errCodeInsufficientFunds},
+		{db.ErrCurrencyMismatch, http.StatusUnprocessableEntity, errCodeCurrencyMismatch},
+		{db.ErrSerialization, http.StatusServiceUnavailable, errCodeSerializationFailure},
+		{sql.ErrConnDone, http.StatusInternalServerError, errCodeInternal},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.code, func(t *testing.T) {
+			status, body := statusErrResponse(c.err)
+			require.Equal(t, c.status, status)
+			require.Equal(t, c.code, body["code"])
+			if c.status == http.StatusInternalServerError {
+				// the cause is only logged
+				require.Equal(t, errInternal.Error(), body["error"])
+				return
+			}
+			require.Equal(t, c.err.Error(), body["error"])
+		})
+	}
+}
+
+func TestErrResponse(t *testing.T) {
+	err := errors.New("request rejected")
+
+	testCases := []struct {
+		status int
+		code   string
+	}{
+		{http.StatusBadRequest, errCodeBadRequest},
+		{http.StatusUnauthorized, errCodeUnauthorized},
+		{http.StatusForbidden, errCodeForbidden},
+		{http.StatusNotFound, errCodeNotFound},
+		{http.StatusTooManyRequests, errCodeTooManyRequests},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.code, func(t *testing.T) {
+			status, body := errResponse(c.status, err)
+			require.Equal(t, c.status, status)
+			require.Equal(t, c.code, body["code"])
+			require.Equal(t, err.Error(), body["error"])
+		})
+	}
+
+	// a status without a code of its own is an internal server error
+	status, body := errResponse(http.StatusInternalServerError, sql.ErrConnDone)
+	require.Equal(t, http.StatusInternalServerError, status)
+	require.Equal(t, errCodeInternal, body["code"])
+	require.Equal(t, errInternal.Error(), body["error"])
+}
+
+func TestValidationErrResponse(t *testing.T) {
+	server := newTestServer(t, nil)
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodPost, "/user", strings.NewReader(`{"username":"#"}`))
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	var body struct {
+		Error  string            `json:"error"`
+		Code   string            `json:"code"`
+		Fields map[string]string `json:"fields"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	require.Equal(t, errCodeBadRequest, body.Code)
+	require.NotEmpty(t, body.Error)
+	require.Contains(t, body.Fields, "username")
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/token"
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/gin-gonic/gin"
+)
+
+var errImpersonationForbidden = errors.New("not allowed while impersonating a user")
+
+type impersonateUserResp struct {
+	AccessToken          string    `json:"access_token"`
+	AccessTokenExpiresAt time.Time `json:"access_token_expires_at"`
+	User                 userResp  `json:"user"`
+}
+
+// adminImpersonateUser issues an access token that acts as the user, so support staff see what the customer sees.
+// The token has the role of the user and names the admin as actor. It has no refresh token
+// and belongs to the session of the admin, blocking that session revokes it too.
+func (server *Server) adminImpersonateUser(ctx *gin.Context) {
+	var req getUserReq
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
+		return
+	}
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.store.GetUser(ctx, req.Username)
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	// acting as another admin would hide who did what on the admin routes
+	if user.Role == util.AdminRole {
+		err := errors.New("admins cannot be impersonated")
+		ctx.JSON(errResponse(http.StatusForbidden, err))
+		return
+	}
+
+	accessToken, accessPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, server.impersonationDuration(),
+		token.WithActor(payload.Username), token.WithSessionID(payload.SessionID))
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, impersonateUserResp{
+		AccessToken:          accessToken,
+		AccessTokenExpiresAt: accessPayload.ExpireAt,
+		User:                 userResponse(user),
+	})
+	recordImpersonation(ctx, server.store, payload.Username, user.Username)
+}
+
+// impersonationDuration is IMPERSONATION_DURATION, at most the access token duration
+// the revocation cache is sized for
+func (server *Server) impersonationDuration() time.Duration {
+	duration := server.config.ImpersonationDuration
+	if duration <= 0 || duration > server.config.TokenExpiredDuration {
+		return server.config.TokenExpiredDuration
+	}
+	return duration
+}
+
+type adminListImpersonationsReq struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=1,max=10"`
+}
+
+// adminListImpersonations lists the requests admins made as a user, newest first
+func (server *Server) adminListImpersonations(ctx *gin.Context) {
+	var uriReq getUserReq
+	if err := ctx.ShouldBindUri(&uriReq); err != nil {
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
+		return
+	}
+	var req adminListImpersonationsReq
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
+		return
+	}
+	events, err := server.store.ListImpersonationEvents(ctx, db.ListImpersonationEventsParams{
+		Username: uriReq.Username,
+		Limit:    req.PageSize,
+		Offset:   (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, events)
+}
+
+// forbidImpersonation aborts requests of impersonation tokens, for routes that move money or manage credentials.
+// It must run after authMiddleware.
+func forbidImpersonation() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		if payload.IsImpersonated() {
+			ctx.AbortWithStatusJSON(errResponse(http.StatusForbidden, errImpersonationForbidden))
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// recordImpersonation adds a handled request of actor as username to the audit trail.
+// Auditing is best effort, a failure is logged and does not fail the request.
+func recordImpersonation(ctx *gin.Context, store db.Store, actor, username string) {
+	_, err := store.CreateImpersonationEvent(ctx, db.CreateImpersonationEventParams{
+		Actor:      actor,
+		Username:   username,
+		Method:     ctx.Request.Method,
+		Path:       ctx.Request.URL.Path,
+		StatusCode: int32(ctx.Writer.Status()),
+		ClientIp:   ctx.ClientIP(),
+	})
+	if err != nil {
+		log.Println("record impersonation failed:", err)
+	}
+}
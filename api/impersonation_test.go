@@ -0,0 +1,272 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/WanCodeBase/GinModule/db/mock"
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/token"
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+type eqImpersonationEventMatcher struct {
+	actor      string
+	username   string
+	statusCode int32
+}
+
+func (e eqImpersonationEventMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.CreateImpersonationEventParams)
+	return ok && arg.Actor == e.actor && arg.Username == e.username && arg.StatusCode == e.statusCode
+}
+
+func (e eqImpersonationEventMatcher) String() string {
+	return fmt.Sprintf("is %s acting as %s with status %d", e.actor, e.username, e.statusCode)
+}
+
+// expectImpersonationEvent stubs recording a request of actor as username in the audit trail
+func expectImpersonationEvent(store *mockdb.MockStore, actor, username string, statusCode int) {
+	store.EXPECT().
+		CreateImpersonationEvent(gomock.Any(), eqImpersonationEventMatcher{actor, username, int32(statusCode)}).
+		Times(1).
+		Return(db.ImpersonationEvent{}, nil)
+}
+
+// setImpersonation sets an impersonation token of actor as username
+func setImpersonation(t *testing.T, request *http.Request, tokenMaker token.Maker, actor, username string) {
+	impersonationToken, _, err := tokenMaker.CreateToken(username, util.DepositorRole, time.Minute, token.WithActor(actor))
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationHeaderType, impersonationToken))
+}
+
+func TestAdminImpersonateUserAPI(t *testing.T) {
+	admin := util.RandomOwner()
+	user, _ := randomUser()
+	user.Role = util.DepositorRole
+
+	testCases := []struct {
+		name      string
+		username  string
+		setAuth   func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		stubs     func(store *mockdb.MockStore)
+		checkResp func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker)
+	}{
+		{
+			name:     "OK",
+			username: user.Username,
+			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				setAuthorization(t, request, tokenMaker, admin, util.AdminRole, time.Minute, authorizationHeaderType)
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+				expectImpersonationEvent(store, admin, user.Username, http.StatusOK)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp impersonateUserResp
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.Equal(t, user.Username, resp.User.Username)
+
+				payload, err := tokenMaker.VerifyToken(resp.AccessToken)
+				require.NoError(t, err)
+				require.Equal(t, user.Username, payload.Username)
+				require.Equal(t, util.DepositorRole, payload.Role)
+				require.Equal(t, admin, payload.Actor)
+				require.True(t, payload.IsImpersonated())
+			},
+		},
+		{
+			name:     "NotAdmin",
+			username: user.Username,
+			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				setAuthorization(t, request, tokenMaker, admin, util.DepositorRole, time.Minute, authorizationHeaderType)
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateImpersonationEvent(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "AdminTarget",
+			username: user.Username,
+			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				setAuthorization(t, request, tokenMaker, admin, util.AdminRole, time.Minute, authorizationHeaderType)
+			},
+			stubs: func(store *mockdb.MockStore) {
+				otherAdmin := user
+				otherAdmin.Role = util.AdminRole
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(otherAdmin, nil)
+				store.EXPECT().CreateImpersonationEvent(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "UserNotFound",
+			username: user.Username,
+			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				setAuthorization(t, request, tokenMaker, admin, util.AdminRole, time.Minute, authorizationHeaderType)
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(db.User{}, db.ErrNotFound)
+				store.EXPECT().CreateImpersonationEvent(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			c.stubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/admin/users/%s/impersonate", c.username)
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+			c.setAuth(t, request, server.tokenMaker)
+
+			server.router.ServeHTTP(recorder, request)
+			c.checkResp(t, recorder, server.tokenMaker)
+		})
+	}
+}
+
+func TestImpersonatedRequestAPI(t *testing.T) {
+	admin := util.RandomOwner()
+	user, _ := randomUser()
+	account := randomAccount(user.Username)
+
+	testCases := []struct {
+		name      string
+		method    string
+		url       string
+		body      gin.H
+		stubs     func(store *mockdb.MockStore)
+		revoke    func(server *Server)
+		checkResp func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "ReadAsUser",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/account/%d", account.ID),
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), account.ID).Times(1).Return(account, nil)
+				expectImpersonationEvent(store, admin, user.Username, http.StatusOK)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder.Body, account)
+			},
+		},
+		{
+			name:   "Transfer",
+			method: http.MethodPost,
+			url:    "/transfer",
+			body: gin.H{
+				"from_account_id": account.ID,
+				"to_account_id":   account.ID + 1,
+				"amount":          10,
+				"currency":        account.Currency,
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+				expectImpersonationEvent(store, admin, user.Username, http.StatusForbidden)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "ChangePassword",
+			method: http.MethodPut,
+			url:    "/user/password",
+			body:   gin.H{"current_password": "secret123", "new_password": "Secret1234"},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				expectImpersonationEvent(store, admin, user.Username, http.StatusForbidden)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "AdminRoute",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/admin/users/%s", user.Username),
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				expectImpersonationEvent(store, admin, user.Username, http.StatusForbidden)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "ActorLoggedOut",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/account/%d", account.ID),
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateImpersonationEvent(gomock.Any(), gomock.Any()).Times(0)
+			},
+			revoke: func(server *Server) {
+				server.revocations.PasswordChanged(admin, time.Now().Add(time.Second))
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			c.stubs(store)
+
+			server := newTestServer(t, store)
+			if c.revoke != nil {
+				c.revoke(server)
+			}
+			recorder := httptest.NewRecorder()
+
+			var body []byte
+			if c.body != nil {
+				var err error
+				body, err = json.Marshal(c.body)
+				require.NoError(t, err)
+			}
+			request, err := http.NewRequest(c.method, c.url, bytes.NewReader(body))
+			require.NoError(t, err)
+			setImpersonation(t, request, server.tokenMaker, admin, user.Username)
+
+			server.router.ServeHTTP(recorder, request)
+			c.checkResp(t, recorder)
+		})
+	}
+}
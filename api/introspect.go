@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// introspectTokenReq is form encoded as RFC 7662 requires, token_type_hint is accepted and ignored
+type introspectTokenReq struct {
+	Token         string `form:"token" binding:"required"`
+	TokenTypeHint string `form:"token_type_hint"`
+}
+
+// introspectTokenResp is the RFC 7662 response, an inactive token has no other member
+type introspectTokenResp struct {
+	Active    bool   `json:"active"`
+	Subject   string `json:"sub,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Role      string `json:"role,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	NotBefore int64  `json:"nbf,omitempty"`
+	TokenID   string `json:"jti,omitempty"`
+	Issuer    string `json:"iss,omitempty"`
+	Audience  string `json:"aud,omitempty"`
+	// Actor is the admin acting as the subject of an impersonation token, as in RFC 8693
+	Actor *introspectActor `json:"act,omitempty"`
+}
+
+type introspectActor struct {
+	Subject string `json:"sub"`
+}
+
+// introspectToken tells an api client like the gateway whether a token is valid and not revoked.
+// It does not say why a token is inactive, that would help guessing tokens.
+func (server *Server) introspectToken(ctx *gin.Context) {
+	var req introspectTokenReq
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
+		return
+	}
+	ctx.Header("Cache-Control", "no-store")
+
+	payload, err := server.tokenMaker.VerifyToken(req.Token)
+	if err != nil || len(payload.Purpose) > 0 || server.revocations.IsRevoked(payload) {
+		ctx.JSON(http.StatusOK, introspectTokenResp{Active: false})
+		return
+	}
+
+	resp := introspectTokenResp{
+		Active:    true,
+		Subject:   payload.Username,
+		Username:  payload.Username,
+		Role:      payload.Role,
+		ExpiresAt: payload.ExpireAt.Unix(),
+		IssuedAt:  payload.IssuedAt.Unix(),
+		TokenID:   payload.ID.String(),
+		Issuer:    payload.Issuer,
+		Audience:  payload.Audience,
+	}
+	if !payload.NotBefore.IsZero() {
+		resp.NotBefore = payload.NotBefore.Unix()
+	}
+	if payload.IsImpersonated() {
+		resp.Actor = &introspectActor{Subject: payload.Actor}
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
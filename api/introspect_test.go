@@ -0,0 +1,167 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/WanCodeBase/GinModule/db/mock"
+	"github.com/WanCodeBase/GinModule/token"
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntrospectTokenAPI(t *testing.T) {
+	username := util.RandomOwner()
+	clientOwner := util.RandomOwner()
+
+	testCases := []struct {
+		name      string
+		buildForm func(t *testing.T, server *Server) url.Values
+		setAuth   func(t *testing.T, request *http.Request, tokenMaker token.Maker, store *mockdb.MockStore)
+		checkResp func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Active",
+			buildForm: func(t *testing.T, server *Server) url.Values {
+				accessToken, _, err := server.tokenMaker.CreateToken(username, util.DepositorRole, time.Minute)
+				require.NoError(t, err)
+				return url.Values{"token": {accessToken}, "token_type_hint": {"access_token"}}
+			},
+			setAuth: setIntrospectAuthorization(clientOwner, util.TokensIntrospectScope),
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "no-store", recorder.Header().Get("Cache-Control"))
+
+				var resp introspectTokenResp
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.True(t, resp.Active)
+				require.Equal(t, username, resp.Subject)
+				require.Equal(t, util.DepositorRole, resp.Role)
+				require.NotEmpty(t, resp.TokenID)
+				require.WithinDuration(t, time.Now().Add(time.Minute), time.Unix(resp.ExpiresAt, 0), time.Second*2)
+				require.WithinDuration(t, time.Now(), time.Unix(resp.IssuedAt, 0), time.Second*2)
+			},
+		},
+		{
+			name: "Expired",
+			buildForm: func(t *testing.T, server *Server) url.Values {
+				accessToken, _, err := server.tokenMaker.CreateToken(username, util.DepositorRole, -time.Minute)
+				require.NoError(t, err)
+				return url.Values{"token": {accessToken}}
+			},
+			setAuth:   setIntrospectAuthorization(clientOwner, util.TokensIntrospectScope),
+			checkResp: requireInactiveToken,
+		},
+		{
+			name: "Revoked",
+			buildForm: func(t *testing.T, server *Server) url.Values {
+				accessToken, payload, err := server.tokenMaker.CreateToken(username, util.DepositorRole, time.Minute)
+				require.NoError(t, err)
+				server.revocations.PasswordChanged(username, payload.IssuedAt.Add(time.Second))
+				return url.Values{"token": {accessToken}}
+			},
+			setAuth:   setIntrospectAuthorization(clientOwner, util.TokensIntrospectScope),
+			checkResp: requireInactiveToken,
+		},
+		{
+			name: "RefreshToken",
+			buildForm: func(t *testing.T, server *Server) url.Values {
+				refreshToken, _, err := server.tokenMaker.CreateToken(username, util.DepositorRole, time.Hour, token.WithPurpose(refreshTokenPurpose))
+				require.NoError(t, err)
+				return url.Values{"token": {refreshToken}, "token_type_hint": {"refresh_token"}}
+			},
+			setAuth:   setIntrospectAuthorization(clientOwner, util.TokensIntrospectScope),
+			checkResp: requireInactiveToken,
+		},
+		{
+			name: "Malformed",
+			buildForm: func(t *testing.T, server *Server) url.Values {
+				return url.Values{"token": {"invalid"}}
+			},
+			setAuth:   setIntrospectAuthorization(clientOwner, util.TokensIntrospectScope),
+			checkResp: requireInactiveToken,
+		},
+		{
+			name: "NoToken",
+			buildForm: func(t *testing.T, server *Server) url.Values {
+				return url.Values{}
+			},
+			setAuth: setIntrospectAuthorization(clientOwner, util.TokensIntrospectScope),
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "MissingScope",
+			buildForm: func(t *testing.T, server *Server) url.Values {
+				return url.Values{"token": {"invalid"}}
+			},
+			setAuth: setIntrospectAuthorization(clientOwner, util.AccountsReadScope),
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "BearerToken",
+			buildForm: func(t *testing.T, server *Server) url.Values {
+				return url.Values{"token": {"invalid"}}
+			},
+			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, store *mockdb.MockStore) {
+				setAuthorization(t, request, tokenMaker, clientOwner, util.AdminRole, time.Minute, authorizationHeaderType)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			buildForm: func(t *testing.T, server *Server) url.Values {
+				return url.Values{"token": {"invalid"}}
+			},
+			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, store *mockdb.MockStore) {
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			form := c.buildForm(t, server)
+			request, err := http.NewRequest(http.MethodPost, "/oauth/introspect", strings.NewReader(form.Encode()))
+			require.NoError(t, err)
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			c.setAuth(t, request, server.tokenMaker, store)
+
+			server.router.ServeHTTP(recorder, request)
+			c.checkResp(t, recorder)
+		})
+	}
+}
+
+func setIntrospectAuthorization(username string, scopes ...string) func(t *testing.T, request *http.Request, tokenMaker token.Maker, store *mockdb.MockStore) {
+	return func(t *testing.T, request *http.Request, tokenMaker token.Maker, store *mockdb.MockStore) {
+		key, apiKey := randomAPIKey(t, username, scopes...)
+		store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), apiKey.KeyPrefix).Times(1).Return(apiKey, nil)
+		request.Header.Set(authorizationHeaderKey, "ApiKey "+key)
+	}
+}
+
+func requireInactiveToken(t *testing.T, recorder *httptest.ResponseRecorder) {
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"active":false}`, recorder.Body.String())
+}
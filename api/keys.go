@@ -0,0 +1,21 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const keySetCacheControl = "public, max-age=300"
+
+// getJWKS lists the public keys that verify our tokens
+func (server *Server) getJWKS(ctx *gin.Context) {
+	ctx.Header("Cache-Control", keySetCacheControl)
+	ctx.JSON(http.StatusOK, server.keyring.JWKS())
+}
+
+// getPASERK lists the same keys in PASERK format for PASETO libraries
+func (server *Server) getPASERK(ctx *gin.Context) {
+	ctx.Header("Cache-Control", keySetCacheControl)
+	ctx.JSON(http.StatusOK, server.keyring.PASERK())
+}
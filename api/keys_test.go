@@ -0,0 +1,68 @@
+package api
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/WanCodeBase/GinModule/mail"
+	"github.com/WanCodeBase/GinModule/token"
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestPrivateKey(t *testing.T) string {
+	_, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+
+	file := filepath.Join(t.TempDir(), "token_private.pem")
+	err = os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+	require.NoError(t, err)
+	return file
+}
+
+func TestJWKSAPI(t *testing.T) {
+	config := util.Config{
+		TokenType:            token.TypePasetoPublic,
+		TokenPrivateKeyFile:  writeTestPrivateKey(t),
+		TokenExpiredDuration: time.Minute,
+		MailSender:           mail.SenderMemory,
+	}
+	server, err := NewServer(config, nil)
+	require.NoError(t, err)
+
+	httpServer := httptest.NewServer(server.router)
+	defer httpServer.Close()
+
+	accessToken, _, err := server.tokenMaker.CreateToken("user", util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	verifier := token.NewJWKSVerifier(httpServer.URL + "/.well-known/jwks.json")
+	payload, err := verifier.VerifyToken(accessToken)
+	require.NoError(t, err)
+	require.Equal(t, "user", payload.Username)
+
+	resp, err := http.Get(httpServer.URL + "/.well-known/paserk.json")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestJWKSAPISymmetricMode(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/token"
+	"github.com/gin-gonic/gin"
+)
+
+// outcomes of a login attempt in the audit trail
+const (
+	loginOutcomeSuccess         = "success"
+	loginOutcomeBadPassword     = "bad_password"
+	loginOutcomeUnknownUser     = "unknown_user"
+	loginOutcomeThrottled       = "throttled"
+	loginOutcomeTOTPRequired    = "totp_required"
+	loginOutcomeBadSecondFactor = "bad_second_factor"
+	loginOutcomeBadPasskey      = "bad_passkey"
+	loginOutcomeBadMagicLink    = "bad_magic_link"
+)
+
+// login notifiers accepted by the LOGIN_NOTIFIER setting
+const (
+	LoginNotifierEmail = "email"
+	LoginNotifierLog   = "log"
+	LoginNotifierNone  = "none"
+)
+
+// LoginNotifier tells a user about a successful login from an ip and user agent
+// combination never seen before for that user
+type LoginNotifier interface {
+	NotifyNewDevice(ctx context.Context, user db.User, event db.LoginEvent) error
+}
+
+// NewLoginNotifier creates the notifier of kind, email by default
+func NewLoginNotifier(kind string, store db.Store) (LoginNotifier, error) {
+	switch kind {
+	case LoginNotifierEmail, "":
+		return &emailLoginNotifier{store: store}, nil
+	case LoginNotifierLog:
+		return logLoginNotifier{}, nil
+	case LoginNotifierNone:
+		return noLoginNotifier{}, nil
+	}
+	return nil, fmt.Errorf("unsupported login notifier %q", kind)
+}
+
+// emailLoginNotifier queues the notification in the email outbox
+type emailLoginNotifier struct {
+	store db.Store
+}
+
+func (n *emailLoginNotifier) NotifyNewDevice(ctx context.Context, user db.User, event db.LoginEvent) error {
+	_, err := n.store.CreateOutboxEmail(ctx, db.CreateOutboxEmailParams{
+		ToAddress: user.Email,
+		Subject:   "New login to your account",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"your account %s was logged in to from a new device:\n\n"+
+			"Time: %s\nIP address: %s\nBrowser: %s\n\n"+
+			"If it was not you, change your password and log out the device from your sessions.\n",
+			user.FullName, user.Username, event.CreatedAt.UTC().Format(time.RFC1123), event.ClientIp, event.UserAgent),
+	})
+	return err
+}
+
+type logLoginNotifier struct{}
+
+func (logLoginNotifier) NotifyNewDevice(_ context.Context, user db.User, event db.LoginEvent) error {
+	log.Printf("new login of %s from %s (%s)", user.Username, event.ClientIp, event.UserAgent)
+	return nil
+}
+
+type noLoginNotifier struct{}
+
+func (noLoginNotifier) NotifyNewDevice(context.Context, db.User, db.LoginEvent) error {
+	return nil
+}
+
+// recordLogin adds an attempt to the audit trail. It is best effort,
+// the login does not fail if the event cannot be stored.
+func (server *Server) recordLogin(ctx *gin.Context, username, outcome string) (db.LoginEvent, error) {
+	event, err := server.store.CreateLoginEvent(ctx, db.CreateLoginEventParams{
+		Username:  username,
+		ClientIp:  ctx.ClientIP(),
+		UserAgent: ctx.Request.UserAgent(),
+		Outcome:   outcome,
+	})
+	if err != nil {
+		log.Println("record login failed:", err)
+	}
+	return event, err
+}
+
+// recordLoginSuccess adds a successful login to the audit trail and notifies the user
+// if it comes from a new device. The first login of a user is not notified.
+func (server *Server) recordLoginSuccess(ctx *gin.Context, user db.User) {
+	history, err := server.store.GetLoginHistory(ctx, db.GetLoginHistoryParams{
+		Username:  user.Username,
+		ClientIp:  ctx.ClientIP(),
+		UserAgent: ctx.Request.UserAgent(),
+	})
+	if err != nil {
+		log.Println("get login history failed:", err)
+	}
+
+	event, err := server.recordLogin(ctx, user.Username, loginOutcomeSuccess)
+	if err != nil || !history.HasLoggedIn || history.IsKnownDevice {
+		return
+	}
+	if err = server.loginNotifier.NotifyNewDevice(ctx, user, event); err != nil {
+		log.Println("notify new login failed:", err)
+	}
+}
+
+type listLoginsReq struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=1,max=10"`
+}
+
+type loginEventResp struct {
+	ClientIP  string    `json:"client_ip"`
+	UserAgent string    `json:"user_agent"`
+	Outcome   string    `json:"outcome"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// listLogins lists the login attempts on the authorized user, newest first
+func (server *Server) listLogins(ctx *gin.Context) {
+	var req listLoginsReq
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
+		return
+	}
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	events, err := server.store.ListLoginEvents(ctx, db.ListLoginEventsParams{
+		Username: payload.Username,
+		Limit:    req.PageSize,
+		Offset:   (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	resp := make([]loginEventResp, 0, len(events))
+	for _, event := range events {
+		resp = append(resp, loginEventResp{
+			ClientIP:  event.ClientIp,
+			UserAgent: event.UserAgent,
+			Outcome:   event.Outcome,
+			CreatedAt: event.CreatedAt,
+		})
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,246 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/WanCodeBase/GinModule/db/mock"
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+type eqLoginOutcomeMatcher string
+
+func (e eqLoginOutcomeMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.CreateLoginEventParams)
+	return ok && arg.Outcome == string(e)
+}
+
+func (e eqLoginOutcomeMatcher) String() string {
+	return fmt.Sprintf("has outcome %s", string(e))
+}
+
+// expectLoginEvent stubs recording a login attempt with outcome in the audit trail
+func expectLoginEvent(store *mockdb.MockStore, outcome string) {
+	store.EXPECT().CreateLoginEvent(gomock.Any(), eqLoginOutcomeMatcher(outcome)).Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreateLoginEventParams) (db.LoginEvent, error) {
+			return db.LoginEvent{
+				Username:  arg.Username,
+				ClientIp:  arg.ClientIp,
+				UserAgent: arg.UserAgent,
+				Outcome:   arg.Outcome,
+				CreatedAt: time.Now(),
+			}, nil
+		})
+}
+
+// expectLoginSuccess stubs the audit of a successful login from a known device
+func expectLoginSuccess(store *mockdb.MockStore) {
+	store.EXPECT().GetLoginHistory(gomock.Any(), gomock.Any()).Times(1).
+		Return(db.GetLoginHistoryRow{HasLoggedIn: true, IsKnownDevice: true}, nil)
+	expectLoginEvent(store, loginOutcomeSuccess)
+}
+
+type recordingLoginNotifier struct {
+	events []db.LoginEvent
+}
+
+func (n *recordingLoginNotifier) NotifyNewDevice(_ context.Context, _ db.User, event db.LoginEvent) error {
+	n.events = append(n.events, event)
+	return nil
+}
+
+func TestLoginNewDeviceAPI(t *testing.T) {
+	user, password := randomUser()
+	hashedPassword, err := util.HashedPassword(password)
+	require.NoError(t, err)
+	user.HashedPassword = hashedPassword
+
+	testCases := []struct {
+		name     string
+		history  db.GetLoginHistoryRow
+		notified bool
+	}{
+		{
+			name:     "NewDevice",
+			history:  db.GetLoginHistoryRow{HasLoggedIn: true, IsKnownDevice: false},
+			notified: true,
+		},
+		{
+			name:     "KnownDevice",
+			history:  db.GetLoginHistoryRow{HasLoggedIn: true, IsKnownDevice: true},
+			notified: false,
+		},
+		{
+			name:     "FirstLogin",
+			history:  db.GetLoginHistoryRow{HasLoggedIn: false, IsKnownDevice: false},
+			notified: false,
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			expectLoginAllowed(store)
+			store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+			store.EXPECT().DeleteLoginThrottle(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			store.EXPECT().GetUserTOTP(gomock.Any(), user.Username).Times(1).Return(db.UserTotp{}, db.ErrNotFound)
+			store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, nil)
+			store.EXPECT().GetLoginHistory(gomock.Any(), db.GetLoginHistoryParams{
+				Username:  user.Username,
+				ClientIp:  testClientIP,
+				UserAgent: testUserAgent,
+			}).Times(1).Return(c.history, nil)
+			expectLoginEvent(store, loginOutcomeSuccess)
+
+			server := newTestServer(t, store)
+			notifier := &recordingLoginNotifier{}
+			server.loginNotifier = notifier
+			recorder := httptest.NewRecorder()
+
+			body, err := json.Marshal(gin.H{"username": user.Username, "password": password})
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/user/login", bytes.NewReader(body))
+			require.NoError(t, err)
+			setClientInfo(request)
+
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusOK, recorder.Code)
+
+			if !c.notified {
+				require.Empty(t, notifier.events)
+				return
+			}
+			require.Len(t, notifier.events, 1)
+			require.Equal(t, testClientIP, notifier.events[0].ClientIp)
+			require.Equal(t, testUserAgent, notifier.events[0].UserAgent)
+		})
+	}
+}
+
+func TestEmailLoginNotifier(t *testing.T) {
+	user, _ := randomUser()
+	event := db.LoginEvent{
+		Username:  user.Username,
+		ClientIp:  testClientIP,
+		UserAgent: testUserAgent,
+		Outcome:   loginOutcomeSuccess,
+		CreatedAt: time.Now(),
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().CreateOutboxEmail(gomock.Any(), gomock.Any()).Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreateOutboxEmailParams) (db.EmailOutbox, error) {
+			require.Equal(t, user.Email, arg.ToAddress)
+			require.Contains(t, arg.Body, testClientIP)
+			require.Contains(t, arg.Body, testUserAgent)
+			return db.EmailOutbox{}, nil
+		})
+
+	notifier, err := NewLoginNotifier(LoginNotifierEmail, store)
+	require.NoError(t, err)
+	require.NoError(t, notifier.NotifyNewDevice(context.Background(), user, event))
+
+	for _, kind := range []string{LoginNotifierLog, LoginNotifierNone} {
+		notifier, err = NewLoginNotifier(kind, store)
+		require.NoError(t, err)
+		require.NoError(t, notifier.NotifyNewDevice(context.Background(), user, event))
+	}
+
+	_, err = NewLoginNotifier("unknown", store)
+	require.Error(t, err)
+}
+
+func TestListLoginsAPI(t *testing.T) {
+	username := util.RandomOwner()
+	events := []db.LoginEvent{
+		{ID: 2, Username: username, ClientIp: testClientIP, UserAgent: testUserAgent, Outcome: loginOutcomeSuccess, CreatedAt: time.Now()},
+		{ID: 1, Username: username, ClientIp: testClientIP, UserAgent: testUserAgent, Outcome: loginOutcomeBadPassword, CreatedAt: time.Now().Add(-time.Minute)},
+	}
+
+	testCases := []struct {
+		name      string
+		query     string
+		stubs     func(store *mockdb.MockStore)
+		checkResp func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "page_id=2&page_size=5",
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListLoginEvents(gomock.Any(), db.ListLoginEventsParams{
+					Username: username,
+					Limit:    5,
+					Offset:   5,
+				}).Times(1).Return(events, nil)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp []loginEventResp
+				err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+				require.NoError(t, err)
+				require.Len(t, resp, 2)
+				require.Equal(t, loginOutcomeSuccess, resp[0].Outcome)
+				require.Equal(t, loginOutcomeBadPassword, resp[1].Outcome)
+				require.Equal(t, testClientIP, resp[1].ClientIP)
+			},
+		},
+		{
+			name:  "InvalidPageSize",
+			query: "page_id=1&page_size=100",
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListLoginEvents(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			query: "page_id=1&page_size=5",
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListLoginEvents(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			c.stubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/user/logins?"+c.query, nil)
+			require.NoError(t, err)
+			setAuthorization(t, request, server.tokenMaker, username, util.DepositorRole, time.Minute, authorizationHeaderType)
+
+			server.router.ServeHTTP(recorder, request)
+			c.checkResp(t, recorder)
+		})
+	}
+}
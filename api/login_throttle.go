@@ -0,0 +1,196 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultLoginMaxFailedAttempts   = 5
+	defaultLoginIPMaxFailedAttempts = 20
+	defaultLoginBackoffBase         = time.Second
+	defaultLoginLockoutDuration     = 15 * time.Minute
+)
+
+var errTooManyLoginAttempts = errors.New("too many failed login attempts, try again later")
+
+// loginThrottle slows down password guessing. Every failed attempt of a username doubles
+// the wait before the next one, until the username is locked out for lockoutDuration.
+// A client ip is only blocked once it reaches its own, higher, threshold,
+// so one user mistyping a password does not lock out a whole NAT.
+// Failures older than lockoutDuration are forgotten.
+type loginThrottle struct {
+	store db.Store
+
+	maxFailedAttempts   int32
+	ipMaxFailedAttempts int32
+	backoffBase         time.Duration
+	lockoutDuration     time.Duration
+}
+
+func newLoginThrottle(store db.Store, config util.Config) *loginThrottle {
+	throttle := &loginThrottle{
+		store:               store,
+		maxFailedAttempts:   config.LoginMaxFailedAttempts,
+		ipMaxFailedAttempts: config.LoginIPMaxFailedAttempts,
+		backoffBase:         config.LoginBackoffBase,
+		lockoutDuration:     config.LoginLockoutDuration,
+	}
+	if throttle.maxFailedAttempts <= 0 {
+		throttle.maxFailedAttempts = defaultLoginMaxFailedAttempts
+	}
+	if throttle.ipMaxFailedAttempts <= 0 {
+		throttle.ipMaxFailedAttempts = defaultLoginIPMaxFailedAttempts
+	}
+	if throttle.backoffBase <= 0 {
+		throttle.backoffBase = defaultLoginBackoffBase
+	}
+	if throttle.lockoutDuration <= 0 {
+		throttle.lockoutDuration = defaultLoginLockoutDuration
+	}
+	return throttle
+}
+
+// Check returns how long the client has to wait before it may try to log in again, zero if it may now
+func (t *loginThrottle) Check(ctx context.Context, username, clientIP string) (time.Duration, error) {
+	var retryAfter time.Duration
+	for _, arg := range []db.GetLoginThrottleParams{
+		{Kind: db.LoginThrottleUsername, Key: username},
+		{Kind: db.LoginThrottleIP, Key: clientIP},
+	} {
+		throttle, err := t.store.GetLoginThrottle(ctx, arg)
+		if err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				continue
+			}
+			return 0, err
+		}
+		if wait := time.Until(throttle.BlockedUntil); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+	return retryAfter, nil
+}
+
+// Fail records a failed attempt and returns how long the client has to wait before the next one.
+// Reaching maxFailedAttempts locks the username out and records a lockout an admin can undo.
+func (t *loginThrottle) Fail(ctx context.Context, username, clientIP string) (time.Duration, error) {
+	now := time.Now()
+	resetBefore := now.Add(-t.lockoutDuration)
+
+	userThrottle, err := t.store.RecordLoginFailure(ctx, db.RecordLoginFailureParams{
+		Kind:        db.LoginThrottleUsername,
+		Key:         username,
+		ResetBefore: resetBefore,
+	})
+	if err != nil {
+		return 0, err
+	}
+	retryAfter := t.backoff(userThrottle.FailedAttempts)
+	if retryAfter > 0 {
+		err = t.store.BlockLoginThrottle(ctx, db.BlockLoginThrottleParams{
+			Kind:         db.LoginThrottleUsername,
+			Key:          username,
+			BlockedUntil: now.Add(retryAfter),
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	if userThrottle.FailedAttempts >= t.maxFailedAttempts {
+		_, err = t.store.CreateLoginLockout(ctx, db.CreateLoginLockoutParams{
+			Username:       username,
+			ClientIp:       clientIP,
+			FailedAttempts: userThrottle.FailedAttempts,
+			LockedUntil:    now.Add(retryAfter),
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	ipThrottle, err := t.store.RecordLoginFailure(ctx, db.RecordLoginFailureParams{
+		Kind:        db.LoginThrottleIP,
+		Key:         clientIP,
+		ResetBefore: resetBefore,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if ipThrottle.FailedAttempts >= t.ipMaxFailedAttempts {
+		err = t.store.BlockLoginThrottle(ctx, db.BlockLoginThrottleParams{
+			Kind:         db.LoginThrottleIP,
+			Key:          clientIP,
+			BlockedUntil: now.Add(t.lockoutDuration),
+		})
+		if err != nil {
+			return 0, err
+		}
+		retryAfter = t.lockoutDuration
+	}
+
+	return retryAfter, nil
+}
+
+// Succeed forgets the failed attempts of the username. The ip keeps its count,
+// otherwise logging in to an own account would reset the limit of an attacker.
+func (t *loginThrottle) Succeed(ctx context.Context, username string) error {
+	return t.store.DeleteLoginThrottle(ctx, db.DeleteLoginThrottleParams{
+		Kind: db.LoginThrottleUsername,
+		Key:  username,
+	})
+}
+
+// backoff returns the wait after the given number of consecutive failures of a username:
+// backoffBase, 2*backoffBase, 4*backoffBase, ... and lockoutDuration once maxFailedAttempts is reached
+func (t *loginThrottle) backoff(failedAttempts int32) time.Duration {
+	if failedAttempts <= 0 {
+		return 0
+	}
+	if failedAttempts >= t.maxFailedAttempts {
+		return t.lockoutDuration
+	}
+	wait := t.backoffBase << (failedAttempts - 1)
+	if wait <= 0 || wait > t.lockoutDuration {
+		return t.lockoutDuration
+	}
+	return wait
+}
+
+// Run deletes the rows of expired failures every lockoutDuration until ctx is done
+func (t *loginThrottle) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.lockoutDuration)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, err := t.store.DeleteStaleLoginThrottles(ctx, time.Now().Add(-t.lockoutDuration))
+			if err != nil {
+				log.Println("purge login throttles failed:", err)
+			}
+		}
+	}
+}
+
+// setRetryAfter sets the Retry-After header in whole seconds
+func setRetryAfter(ctx *gin.Context, retryAfter time.Duration) {
+	ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+}
+
+// tooManyLoginAttempts responds 429 telling the client when to retry
+func tooManyLoginAttempts(ctx *gin.Context, retryAfter time.Duration) {
+	setRetryAfter(ctx, retryAfter)
+	ctx.JSON(errResponse(http.StatusTooManyRequests, errTooManyLoginAttempts))
+}
@@ -0,0 +1,202 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	mockdb "github.com/WanCodeBase/GinModule/db/mock"
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// expectLoginAllowed stubs the throttle check of a client that never failed to log in
+func expectLoginAllowed(store *mockdb.MockStore) {
+	store.EXPECT().GetLoginThrottle(gomock.Any(), gomock.Any()).Times(2).Return(db.LoginThrottle{}, db.ErrNotFound)
+}
+
+// expectLoginFailure stubs recording a failed login that leaves the username with failedAttempts,
+// below the lockout threshold
+func expectLoginFailure(store *mockdb.MockStore, failedAttempts int32) {
+	store.EXPECT().RecordLoginFailure(gomock.Any(), gomock.Any()).Times(2).
+		DoAndReturn(func(_ context.Context, arg db.RecordLoginFailureParams) (db.LoginThrottle, error) {
+			return db.LoginThrottle{Kind: arg.Kind, Key: arg.Key, FailedAttempts: failedAttempts}, nil
+		})
+	store.EXPECT().BlockLoginThrottle(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+	store.EXPECT().CreateLoginLockout(gomock.Any(), gomock.Any()).Times(0)
+}
+
+func TestLoginThrottleAPI(t *testing.T) {
+	user, password := randomUser()
+	hashedPassword, err := util.HashedPassword(password)
+	require.NoError(t, err)
+	user.HashedPassword = hashedPassword
+
+	throttle := newLoginThrottle(nil, util.Config{})
+
+	testCases := []struct {
+		name      string
+		password  string
+		stubs     func(store *mockdb.MockStore)
+		checkResp func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "UsernameLocked",
+			password: password,
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetLoginThrottle(gomock.Any(), db.GetLoginThrottleParams{Kind: db.LoginThrottleUsername, Key: user.Username}).
+					Times(1).Return(db.LoginThrottle{BlockedUntil: time.Now().Add(10 * time.Minute)}, nil)
+				store.EXPECT().GetLoginThrottle(gomock.Any(), gomock.Any()).Times(1).Return(db.LoginThrottle{}, db.ErrNotFound)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				expectLoginEvent(store, loginOutcomeThrottled)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.Equal(t, "600", recorder.Header().Get("Retry-After"))
+			},
+		},
+		{
+			name:     "IPBlocked",
+			password: password,
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetLoginThrottle(gomock.Any(), db.GetLoginThrottleParams{Kind: db.LoginThrottleUsername, Key: user.Username}).
+					Times(1).Return(db.LoginThrottle{BlockedUntil: time.Now().Add(-time.Minute)}, nil)
+				store.EXPECT().GetLoginThrottle(gomock.Any(), gomock.Any()).
+					Times(1).Return(db.LoginThrottle{BlockedUntil: time.Now().Add(30 * time.Second)}, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				expectLoginEvent(store, loginOutcomeThrottled)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.Equal(t, "30", recorder.Header().Get("Retry-After"))
+			},
+		},
+		{
+			name:     "BackOff",
+			password: "incorrect",
+			stubs: func(store *mockdb.MockStore) {
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+				expectLoginEvent(store, loginOutcomeBadPassword)
+				expectLoginFailure(store, 3)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Equal(t, "4", recorder.Header().Get("Retry-After"))
+			},
+		},
+		{
+			name:     "Lockout",
+			password: "incorrect",
+			stubs: func(store *mockdb.MockStore) {
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+				expectLoginEvent(store, loginOutcomeBadPassword)
+				store.EXPECT().RecordLoginFailure(gomock.Any(), gomock.Any()).Times(2).
+					DoAndReturn(func(_ context.Context, arg db.RecordLoginFailureParams) (db.LoginThrottle, error) {
+						return db.LoginThrottle{Kind: arg.Kind, Key: arg.Key, FailedAttempts: throttle.maxFailedAttempts}, nil
+					})
+				store.EXPECT().BlockLoginThrottle(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.BlockLoginThrottleParams) error {
+						require.Equal(t, db.LoginThrottleUsername, arg.Kind)
+						require.WithinDuration(t, time.Now().Add(throttle.lockoutDuration), arg.BlockedUntil, time.Second)
+						return nil
+					})
+				store.EXPECT().CreateLoginLockout(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateLoginLockoutParams) (db.LoginLockout, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, throttle.maxFailedAttempts, arg.FailedAttempts)
+						return db.LoginLockout{}, nil
+					})
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Equal(t, strconv.Itoa(int(throttle.lockoutDuration.Seconds())), recorder.Header().Get("Retry-After"))
+			},
+		},
+		{
+			name:     "IPThreshold",
+			password: "incorrect",
+			stubs: func(store *mockdb.MockStore) {
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(db.User{}, db.ErrNotFound)
+				expectLoginEvent(store, loginOutcomeUnknownUser)
+				store.EXPECT().RecordLoginFailure(gomock.Any(), gomock.Any()).Times(2).
+					DoAndReturn(func(_ context.Context, arg db.RecordLoginFailureParams) (db.LoginThrottle, error) {
+						if arg.Kind == db.LoginThrottleIP {
+							return db.LoginThrottle{Kind: arg.Kind, Key: arg.Key, FailedAttempts: throttle.ipMaxFailedAttempts}, nil
+						}
+						return db.LoginThrottle{Kind: arg.Kind, Key: arg.Key, FailedAttempts: 1}, nil
+					})
+				store.EXPECT().BlockLoginThrottle(gomock.Any(), gomock.Any()).Times(2).Return(nil)
+				store.EXPECT().CreateLoginLockout(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				require.Equal(t, strconv.Itoa(int(throttle.lockoutDuration.Seconds())), recorder.Header().Get("Retry-After"))
+			},
+		},
+		{
+			name:     "CheckError",
+			password: password,
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetLoginThrottle(gomock.Any(), gomock.Any()).Times(1).Return(db.LoginThrottle{}, sql.ErrConnDone)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			c.stubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			body, err := json.Marshal(gin.H{"username": user.Username, "password": c.password})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/user/login", bytes.NewReader(body))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			c.checkResp(t, recorder)
+		})
+	}
+}
+
+func TestLoginThrottleBackoff(t *testing.T) {
+	throttle := newLoginThrottle(nil, util.Config{
+		LoginMaxFailedAttempts: 5,
+		LoginBackoffBase:       time.Second,
+		LoginLockoutDuration:   time.Minute,
+	})
+
+	require.Zero(t, throttle.backoff(0))
+	require.Equal(t, time.Second, throttle.backoff(1))
+	require.Equal(t, 2*time.Second, throttle.backoff(2))
+	require.Equal(t, 8*time.Second, throttle.backoff(4))
+	require.Equal(t, time.Minute, throttle.backoff(5))
+	require.Equal(t, time.Minute, throttle.backoff(100))
+
+	// the back-off never exceeds the lockout
+	throttle.maxFailedAttempts = 100
+	require.Equal(t, time.Minute, throttle.backoff(10))
+	require.Equal(t, time.Minute, throttle.backoff(99))
+}
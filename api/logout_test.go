@@ -0,0 +1,241 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/WanCodeBase/GinModule/db/mock"
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/token"
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogoutUserAPI(t *testing.T) {
+	username := util.RandomOwner()
+	session := randomUserSession(username)
+	setSessionAuth := func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+		accessToken, _, err := tokenMaker.CreateToken(username, util.DepositorRole, time.Minute, token.WithSessionID(session.ID))
+		require.NoError(t, err)
+		request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationHeaderType, accessToken))
+	}
+
+	testCases := []struct {
+		name      string
+		body      func(t *testing.T, tokenMaker token.Maker) gin.H
+		setAuth   func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		stubs     func(store *mockdb.MockStore)
+		checkResp func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server)
+	}{
+		{
+			name: "OK",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{}
+			},
+			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				setAuthorization(t, request, tokenMaker, username, util.DepositorRole, time.Minute, authorizationHeaderType)
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().BlockSession(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateRevokedToken(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Len(t, server.revocations.tokens, 1)
+			},
+		},
+		{
+			name: "WithRefreshToken",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				refreshToken, _, err := tokenMaker.CreateToken(username, util.DepositorRole, time.Hour, token.WithPurpose(refreshTokenPurpose))
+				require.NoError(t, err)
+				return gin.H{"refresh_token": refreshToken}
+			},
+			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				setAuthorization(t, request, tokenMaker, username, util.DepositorRole, time.Minute, authorizationHeaderType)
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().BlockSession(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().CreateRevokedToken(gomock.Any(), gomock.Any()).Times(2).Return(nil)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Len(t, server.revocations.tokens, 2)
+			},
+		},
+		{
+			name: "BlocksSessionOfAccessToken",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{}
+			},
+			setAuth: setSessionAuth,
+			stubs: func(store *mockdb.MockStore) {
+				arg := db.BlockUserSessionParams{ID: session.ID, Username: username}
+				store.EXPECT().BlockUserSession(gomock.Any(), arg).Times(1).Return(session, nil)
+				store.EXPECT().CreateRevokedToken(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Len(t, server.revocations.tokens, 1)
+				require.Contains(t, server.revocations.sessions, session.ID)
+			},
+		},
+		{
+			name: "SessionGone",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{}
+			},
+			setAuth: setSessionAuth,
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().BlockUserSession(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, db.ErrNotFound)
+				store.EXPECT().CreateRevokedToken(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Len(t, server.revocations.tokens, 1)
+				require.Empty(t, server.revocations.sessions)
+			},
+		},
+		{
+			name: "BlockSessionError",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{}
+			},
+			setAuth: setSessionAuth,
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().BlockUserSession(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, sql.ErrConnDone)
+				store.EXPECT().CreateRevokedToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "RefreshTokenOfAnotherUser",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				refreshToken, _, err := tokenMaker.CreateToken("another", util.DepositorRole, time.Hour, token.WithPurpose(refreshTokenPurpose))
+				require.NoError(t, err)
+				return gin.H{"refresh_token": refreshToken}
+			},
+			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				setAuthorization(t, request, tokenMaker, username, util.DepositorRole, time.Minute, authorizationHeaderType)
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().BlockSession(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateRevokedToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{}
+			},
+			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				setAuthorization(t, request, tokenMaker, username, util.DepositorRole, time.Minute, authorizationHeaderType)
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateRevokedToken(gomock.Any(), gomock.Any()).Times(1).Return(sql.ErrConnDone)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				require.Empty(t, server.revocations.tokens)
+			},
+		},
+		{
+			name: "NoAuth",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{}
+			},
+			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateRevokedToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			c.stubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			body, err := json.Marshal(c.body(t, server.tokenMaker))
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/user/logout", bytes.NewReader(body))
+			require.NoError(t, err)
+			c.setAuth(t, request, server.tokenMaker)
+
+			server.router.ServeHTTP(recorder, request)
+			c.checkResp(t, recorder, server)
+		})
+	}
+}
+
+func TestLogoutAllUserAPI(t *testing.T) {
+	for _, tokenType := range []string{token.TypePaseto, token.TypeJWT} {
+		t.Run(tokenType, func(t *testing.T) {
+			username := util.RandomOwner()
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTokenTypeTestServer(t, store, tokenType)
+
+			store.EXPECT().BlockUserSessions(gomock.Any(), username).Times(1).Return(nil)
+			store.EXPECT().UpsertUserLogout(gomock.Any(), gomock.Any()).Times(1).
+				DoAndReturn(func(_ interface{}, arg db.UpsertUserLogoutParams) (db.UserLogout, error) {
+					require.Equal(t, username, arg.Username)
+					require.WithinDuration(t, arg.LoggedOutAt.Add(server.config.RefreshTokenDuration), arg.ExpiresAt, time.Second)
+					return db.UserLogout{
+						Username:    arg.Username,
+						LoggedOutAt: arg.LoggedOutAt,
+						ExpiresAt:   arg.ExpiresAt,
+					}, nil
+				})
+
+			// tokens issued before logout_all are all rejected
+			oldToken, _, err := server.tokenMaker.CreateToken(username, util.DepositorRole, time.Minute, issuedLastSecond)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, "/user/logout_all", nil)
+			require.NoError(t, err)
+			setAuthorization(t, request, server.tokenMaker, username, util.DepositorRole, time.Minute, authorizationHeaderType)
+
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusOK, recorder.Code)
+
+			oldPayload, err := server.tokenMaker.VerifyToken(oldToken)
+			require.NoError(t, err)
+			require.True(t, server.revocations.IsRevoked(oldPayload))
+
+			// a login right after logout_all is accepted, even within the same second
+			newToken, _, err := server.tokenMaker.CreateToken(username, util.DepositorRole, time.Minute)
+			require.NoError(t, err)
+			newPayload, err := server.tokenMaker.VerifyToken(newToken)
+			require.NoError(t, err)
+			require.False(t, server.revocations.IsRevoked(newPayload))
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/token"
+	"github.com/gin-gonic/gin"
+)
+
+// magicLinkPurpose is the purpose claim of the token in a magic link
+const magicLinkPurpose = "magic_link"
+
+var errInvalidMagicLink = errors.New("login link is invalid or has expired")
+
+type sendMagicLinkReq struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// sendMagicLink emails a single-use login link to the owner of the address.
+// The link carries a short-lived signed token, only its id is stored to make it single-use.
+// The response is the same whether or not the address is registered.
+func (server *Server) sendMagicLink(ctx *gin.Context) {
+	var req sendMagicLinkReq
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
+		return
+	}
+	resp := gin.H{"message": "if the email is registered, a login link has been sent"}
+
+	user, err := server.store.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			ctx.JSON(http.StatusOK, resp)
+			return
+		}
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	linkToken, payload, err := server.tokenMaker.CreateToken(user.Username, user.Role, server.config.MagicLinkDuration,
+		token.WithPurpose(magicLinkPurpose))
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	_, err = server.store.CreateMagicLinkTx(ctx, db.CreateMagicLinkTxParams{
+		ID:        payload.ID,
+		Username:  user.Username,
+		ExpiresAt: payload.ExpireAt,
+		Email: db.CreateOutboxEmailParams{
+			ToAddress: user.Email,
+			Subject:   "Your login link",
+			Body:      server.magicLinkEmailBody(user, linkToken, payload.ExpireAt),
+		},
+	})
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (server *Server) magicLinkEmailBody(user db.User, linkToken string, expiresAt time.Time) string {
+	link := linkToken
+	if len(server.config.MagicLinkURL) > 0 {
+		link = fmt.Sprintf("%s?token=%s", server.config.MagicLinkURL, linkToken)
+	}
+	return fmt.Sprintf("Hi %s,\n\n"+
+		"someone asked to log in to your account %s without a password. If it was you, open\n\n"+
+		"%s\n\n"+
+		"before %s. The link works once. Otherwise you can ignore this email.\n",
+		user.FullName, user.Username, link, expiresAt.UTC().Format(time.RFC1123))
+}
+
+type redeemMagicLinkReq struct {
+	Token      string `json:"token" binding:"required"`
+	DeviceName string `json:"device_name" binding:"max=100"`
+}
+
+// redeemMagicLink consumes the token of a magic link and logs its user in like loginUser,
+// users with two-factor authentication still get a challenge.
+// The link page posts the token, a GET would be consumed by mail scanners following links.
+func (server *Server) redeemMagicLink(ctx *gin.Context) {
+	var req redeemMagicLinkReq
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
+		return
+	}
+
+	payload, err := server.tokenMaker.VerifyToken(req.Token)
+	if err != nil || payload.Purpose != magicLinkPurpose || server.revocations.IsRevoked(payload) {
+		ctx.JSON(errResponse(http.StatusUnauthorized, errInvalidMagicLink))
+		return
+	}
+
+	magicLink, err := server.store.UseMagicLink(ctx, payload.ID)
+	if err != nil {
+		// used, replaced by a newer link or expired
+		if errors.Is(err, db.ErrNotFound) {
+			server.recordLogin(ctx, payload.Username, loginOutcomeBadMagicLink)
+			ctx.JSON(errResponse(http.StatusUnauthorized, errInvalidMagicLink))
+			return
+		}
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, magicLink.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			ctx.JSON(errResponse(http.StatusUnauthorized, errInvalidMagicLink))
+			return
+		}
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	server.finishFirstFactorLogin(ctx, user, req.DeviceName)
+}
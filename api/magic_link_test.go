@@ -0,0 +1,287 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	mockdb "github.com/WanCodeBase/GinModule/db/mock"
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/token"
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+var magicLinkRegexp = regexp.MustCompile(`login/magic\?token=(\S+)`)
+
+func TestSendMagicLinkAPI(t *testing.T) {
+	user, _ := randomUser()
+
+	testCases := []struct {
+		name      string
+		body      gin.H
+		stubs     func(store *mockdb.MockStore, tokenMaker token.Maker)
+		checkResp func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"email": user.Email},
+			stubs: func(store *mockdb.MockStore, tokenMaker token.Maker) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), user.Email).Times(1).Return(user, nil)
+				store.EXPECT().CreateMagicLinkTx(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateMagicLinkTxParams) (db.CreateMagicLinkTxResult, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, user.Email, arg.Email.ToAddress)
+						require.WithinDuration(t, time.Now().Add(time.Minute), arg.ExpiresAt, time.Second)
+
+						// the emailed token is signed for the stored link only
+						match := magicLinkRegexp.FindStringSubmatch(arg.Email.Body)
+						require.Len(t, match, 2)
+						payload, err := tokenMaker.VerifyToken(match[1])
+						require.NoError(t, err)
+						require.Equal(t, arg.ID, payload.ID)
+						require.Equal(t, user.Username, payload.Username)
+						require.Equal(t, magicLinkPurpose, payload.Purpose)
+						return db.CreateMagicLinkTxResult{}, nil
+					})
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "UnknownEmail",
+			body: gin.H{"email": user.Email},
+			stubs: func(store *mockdb.MockStore, tokenMaker token.Maker) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), user.Email).Times(1).Return(db.User{}, db.ErrNotFound)
+				store.EXPECT().CreateMagicLinkTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// indistinguishable from a registered email
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidEmail",
+			body: gin.H{"email": "invalid"},
+			stubs: func(store *mockdb.MockStore, tokenMaker token.Maker) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "TxError",
+			body: gin.H{"email": user.Email},
+			stubs: func(store *mockdb.MockStore, tokenMaker token.Maker) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), user.Email).Times(1).Return(user, nil)
+				store.EXPECT().CreateMagicLinkTx(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.CreateMagicLinkTxResult{}, sql.ErrConnDone)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+			c.stubs(store, server.tokenMaker)
+
+			recorder := httptest.NewRecorder()
+			body, err := json.Marshal(c.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/user/login/magic", bytes.NewReader(body))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			c.checkResp(t, recorder)
+		})
+	}
+}
+
+func TestRedeemMagicLinkAPI(t *testing.T) {
+	user, _ := randomUser()
+
+	magicLinkToken := func(t *testing.T, tokenMaker token.Maker, duration time.Duration) (string, *token.Payload) {
+		linkToken, payload, err := tokenMaker.CreateToken(user.Username, user.Role, duration, token.WithPurpose(magicLinkPurpose))
+		require.NoError(t, err)
+		return linkToken, payload
+	}
+
+	testCases := []struct {
+		name      string
+		linkToken func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload)
+		stubs     func(store *mockdb.MockStore, payload *token.Payload)
+		checkResp func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			linkToken: func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload) {
+				return magicLinkToken(t, tokenMaker, time.Minute)
+			},
+			stubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().UseMagicLink(gomock.Any(), payload.ID).Times(1).
+					Return(db.MagicLink{ID: payload.ID, Username: user.Username}, nil)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+				store.EXPECT().GetUserTOTP(gomock.Any(), user.Username).Times(1).Return(db.UserTotp{}, db.ErrNotFound)
+				store.EXPECT().DeleteLoginThrottle(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, nil)
+				expectLoginSuccess(store)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp loginUserResp
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.NotEmpty(t, resp.AccessToken)
+				require.NotEmpty(t, resp.RefreshToken)
+				require.Equal(t, user.Username, resp.User.Username)
+			},
+		},
+		{
+			name: "TOTPRequired",
+			linkToken: func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload) {
+				return magicLinkToken(t, tokenMaker, time.Minute)
+			},
+			stubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().UseMagicLink(gomock.Any(), payload.ID).Times(1).
+					Return(db.MagicLink{ID: payload.ID, Username: user.Username}, nil)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+				store.EXPECT().GetUserTOTP(gomock.Any(), user.Username).Times(1).
+					Return(db.UserTotp{Username: user.Username, IsConfirmed: true}, nil)
+				store.EXPECT().CreateLoginChallenge(gomock.Any(), gomock.Any()).Times(1).Return(db.LoginChallenge{}, nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+				expectLoginEvent(store, loginOutcomeTOTPRequired)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp loginChallengeResp
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.True(t, resp.TOTPRequired)
+				require.NotEmpty(t, resp.ChallengeToken)
+				require.NotContains(t, recorder.Body.String(), "access_token")
+			},
+		},
+		{
+			name: "UsedLink",
+			linkToken: func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload) {
+				return magicLinkToken(t, tokenMaker, time.Minute)
+			},
+			stubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().UseMagicLink(gomock.Any(), payload.ID).Times(1).Return(db.MagicLink{}, db.ErrNotFound)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+				expectLoginEvent(store, loginOutcomeBadMagicLink)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiredToken",
+			linkToken: func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload) {
+				return magicLinkToken(t, tokenMaker, -time.Minute)
+			},
+			stubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().UseMagicLink(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "AccessToken",
+			linkToken: func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload) {
+				accessToken, payload, err := tokenMaker.CreateToken(user.Username, user.Role, time.Minute)
+				require.NoError(t, err)
+				return accessToken, payload
+			},
+			stubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().UseMagicLink(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ForgedToken",
+			linkToken: func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload) {
+				otherMaker, err := token.NewPasetoMaker(util.RandomString(32), token.Claims{})
+				require.NoError(t, err)
+				linkToken, payload, err := otherMaker.CreateToken(user.Username, user.Role, time.Minute, token.WithPurpose(magicLinkPurpose))
+				require.NoError(t, err)
+				return linkToken, payload
+			},
+			stubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().UseMagicLink(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+			linkToken, payload := c.linkToken(t, server.tokenMaker)
+			c.stubs(store, payload)
+
+			recorder := httptest.NewRecorder()
+			body, err := json.Marshal(gin.H{"token": linkToken})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/user/login/magic/redeem", bytes.NewReader(body))
+			require.NoError(t, err)
+			setClientInfo(request)
+
+			server.router.ServeHTTP(recorder, request)
+			c.checkResp(t, recorder)
+		})
+	}
+}
+
+func TestMagicLinkTokenIsNoAccessToken(t *testing.T) {
+	user, _ := randomUser()
+	account := randomAccount(user.Username)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+
+	server := newTestServer(t, store)
+	linkToken, _, err := server.tokenMaker.CreateToken(user.Username, user.Role, time.Minute, token.WithPurpose(magicLinkPurpose))
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/account/%d", account.ID), nil)
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationHeaderType, linkToken))
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
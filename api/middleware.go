@@ -2,50 +2,171 @@ package api
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
 	"github.com/WanCodeBase/GinModule/token"
+	"github.com/WanCodeBase/GinModule/util"
 	"github.com/gin-gonic/gin"
 )
 
 const (
 	authorizationHeaderKey  = "authorization"
 	authorizationHeaderType = "bearer"
+	authorizationAPIKeyType = "apikey"
 	authorizationPayloadKey = "authorization_payload"
+	authorizationScopesKey  = "authorization_scopes"
 )
 
-// authMiddleware
-func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
+// authMiddleware accepts `Bearer <token>` and `ApiKey <key>` authorization.
+// API keys are limited to their scopes, see requireScope.
+// In cookie mode a request without authorization header may carry the access token in a cookie,
+// then it needs a matching csrf token unless it is read-only.
+func authMiddleware(tokenMaker token.Maker, revocations *revocationStore, store db.Store, cookies *authCookies) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
+		if len(authorizationHeader) == 0 && cookies != nil {
+			if accessToken, ok := cookies.AccessToken(ctx); ok {
+				if err := cookies.CheckCSRF(ctx); err != nil {
+					ctx.AbortWithStatusJSON(errResponse(http.StatusForbidden, err))
+					return
+				}
+				authorizeBearer(ctx, tokenMaker, revocations, store, accessToken)
+				return
+			}
+		}
 		if len(authorizationHeader) == 0 {
 			err := errors.New("authorization header is not provided")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResponse(err))
+			ctx.AbortWithStatusJSON(errResponse(http.StatusUnauthorized, err))
 			return
 		}
 
 		fields := strings.Fields(authorizationHeader)
 		if len(fields) < 2 {
 			err := errors.New("authorization header is not format")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResponse(err))
+			ctx.AbortWithStatusJSON(errResponse(http.StatusUnauthorized, err))
 			return
 		}
 		authorizationType := strings.ToLower(fields[0])
+		if authorizationType == authorizationAPIKeyType {
+			apiKey, err := verifyAPIKey(ctx, store, fields[1])
+			if err != nil {
+				if errors.Is(err, errInvalidAPIKey) || errors.Is(err, errRevokedAPIKey) {
+					ctx.AbortWithStatusJSON(errResponse(http.StatusUnauthorized, err))
+					return
+				}
+				ctx.AbortWithStatusJSON(statusErrResponse(err))
+				return
+			}
+
+			// api keys act on behalf of their owner, never with elevated roles
+			ctx.Set(authorizationPayloadKey, &token.Payload{
+				Username: apiKey.Username,
+				Role:     util.DepositorRole,
+				IssuedAt: apiKey.CreatedAt,
+			})
+			ctx.Set(authorizationScopesKey, apiKey.Scopes)
+			ctx.Next()
+			return
+		}
 		if authorizationType != authorizationHeaderType {
 			err := errors.New("authorization type is not match")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResponse(err))
+			ctx.AbortWithStatusJSON(errResponse(http.StatusUnauthorized, err))
 			return
 		}
 
-		payload, err := tokenMaker.VerifyToken(fields[1])
-		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResponse(err))
+		authorizeBearer(ctx, tokenMaker, revocations, store, fields[1])
+	}
+}
+
+// authorizeBearer verifies an access token and sets its payload into the context.
+// Every request of an impersonation token is recorded once it has been handled.
+func authorizeBearer(ctx *gin.Context, tokenMaker token.Maker, revocations *revocationStore, store db.Store, accessToken string) {
+	payload, err := tokenMaker.VerifyToken(accessToken)
+	if err != nil {
+		ctx.AbortWithStatusJSON(errResponse(http.StatusUnauthorized, err))
+		return
+	}
+	// tokens made for a purpose, like refresh tokens and magic links, are no access tokens
+	if len(payload.Purpose) > 0 {
+		ctx.AbortWithStatusJSON(errResponse(http.StatusUnauthorized, token.ErrInvalidToken))
+		return
+	}
+
+	if revocations.IsRevoked(payload) {
+		err := errors.New("token has been revoked")
+		ctx.AbortWithStatusJSON(errResponse(http.StatusUnauthorized, err))
+		return
+	}
+
+	// set payload into context
+	ctx.Set(authorizationPayloadKey, payload)
+	ctx.Next()
+
+	if payload.IsImpersonated() {
+		recordImpersonation(ctx, store, payload.Actor, payload.Username)
+	}
+}
+
+// requireRole aborts unless the authorized token carries one of roles, must run after authMiddleware
+func requireRole(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		for _, role := range roles {
+			if payload.Role == role {
+				ctx.Next()
+				return
+			}
+		}
+
+		err := errors.New("permission denied")
+		ctx.AbortWithStatusJSON(errResponse(http.StatusForbidden, err))
+	}
+}
+
+// requireScope aborts requests authorized by an api key without scope.
+// Bearer tokens are not scoped and always pass.
+func requireScope(scope string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		scopes, ok := ctx.Get(authorizationScopesKey)
+		if !ok {
+			ctx.Next()
+			return
+		}
+		for _, s := range scopes.([]string) {
+			if s == scope {
+				ctx.Next()
+				return
+			}
+		}
+
+		err := fmt.Errorf("api key is missing scope %s", scope)
+		ctx.AbortWithStatusJSON(errResponse(http.StatusForbidden, err))
+	}
+}
+
+// requireAPIKey aborts requests not authorized by an api key, for routes of api clients
+func requireAPIKey() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, ok := ctx.Get(authorizationScopesKey); !ok {
+			err := errors.New("api key is required")
+			ctx.AbortWithStatusJSON(errResponse(http.StatusUnauthorized, err))
 			return
 		}
+		ctx.Next()
+	}
+}
 
-		// set payload into context
-		ctx.Set(authorizationPayloadKey, payload)
+// requireBearer aborts requests authorized by an api key, for routes that manage credentials
+func requireBearer() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, ok := ctx.Get(authorizationScopesKey); ok {
+			err := errors.New("api keys are not allowed")
+			ctx.AbortWithStatusJSON(errResponse(http.StatusForbidden, err))
+			return
+		}
 		ctx.Next()
 	}
 }
@@ -1,16 +1,22 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	mockdb "github.com/WanCodeBase/GinModule/db/mock"
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
 	"github.com/WanCodeBase/GinModule/token"
+	"github.com/WanCodeBase/GinModule/util"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func setAuthorization(
@@ -18,11 +24,13 @@ func setAuthorization(
 	request *http.Request,
 	tokenMaker token.Maker,
 	username string,
+	role string,
 	duration time.Duration,
 	authorizationType string,
 ) {
-	authToken, err := tokenMaker.CreateToken(username, duration)
+	authToken, payload, err := tokenMaker.CreateToken(username, role, duration)
 	assert.NoError(t, err)
+	assert.NotEmpty(t, payload)
 
 	request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationType, authToken))
 }
@@ -36,7 +44,7 @@ func TestMiddleware(t *testing.T) {
 		{
 			name: "OK",
 			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				setAuthorization(t, request, tokenMaker, "user", time.Minute, authorizationHeaderType)
+				setAuthorization(t, request, tokenMaker, "user", util.DepositorRole, time.Minute, authorizationHeaderType)
 			},
 			checkResp: func(t *testing.T, recoder *httptest.ResponseRecorder) {
 				assert.Equal(t, http.StatusOK, recoder.Code)
@@ -45,7 +53,18 @@ func TestMiddleware(t *testing.T) {
 		{
 			name: "ExpiredAuth",
 			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				setAuthorization(t, request, tokenMaker, "user", -time.Minute, authorizationHeaderType)
+				setAuthorization(t, request, tokenMaker, "user", util.DepositorRole, -time.Minute, authorizationHeaderType)
+			},
+			checkResp: func(t *testing.T, recoder *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusUnauthorized, recoder.Code)
+			},
+		},
+		{
+			name: "RefreshToken",
+			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				refreshToken, _, err := tokenMaker.CreateToken("user", util.DepositorRole, time.Hour, token.WithPurpose(refreshTokenPurpose))
+				require.NoError(t, err)
+				request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationHeaderType, refreshToken))
 			},
 			checkResp: func(t *testing.T, recoder *httptest.ResponseRecorder) {
 				assert.Equal(t, http.StatusUnauthorized, recoder.Code)
@@ -61,7 +80,7 @@ func TestMiddleware(t *testing.T) {
 		{
 			name: "WrongAuthType",
 			setAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				setAuthorization(t, request, tokenMaker, "user", -time.Minute, "")
+				setAuthorization(t, request, tokenMaker, "user", util.DepositorRole, -time.Minute, "")
 			},
 			checkResp: func(t *testing.T, recoder *httptest.ResponseRecorder) {
 				assert.Equal(t, http.StatusUnauthorized, recoder.Code)
@@ -76,7 +95,7 @@ func TestMiddleware(t *testing.T) {
 			authPath := "/auth"
 			server.router.GET(
 				authPath,
-				authMiddleware(server.tokenMaker),
+				authMiddleware(server.tokenMaker, server.revocations, server.store, server.cookies),
 				func(ctx *gin.Context) {
 					ctx.JSON(http.StatusOK, nil)
 				},
@@ -92,3 +111,39 @@ func TestMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestMiddlewareRevokedToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+
+	authPath := "/auth"
+	server.router.GET(
+		authPath,
+		authMiddleware(server.tokenMaker, server.revocations, server.store, server.cookies),
+		func(ctx *gin.Context) {
+			ctx.JSON(http.StatusOK, nil)
+		},
+	)
+
+	authToken, payload, err := server.tokenMaker.CreateToken("user", util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	store.EXPECT().CreateRevokedToken(gomock.Any(), db.CreateRevokedTokenParams{
+		ID:        payload.ID,
+		Username:  payload.Username,
+		ExpiresAt: payload.ExpireAt,
+	}).Times(1).Return(nil)
+	err = server.revocations.Revoke(context.Background(), payload)
+	require.NoError(t, err)
+
+	recoder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, authPath, nil)
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationHeaderType, authToken))
+
+	server.router.ServeHTTP(recoder, request)
+	require.Equal(t, http.StatusUnauthorized, recoder.Code)
+}
@@ -0,0 +1,475 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/token"
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+)
+
+// kinds of webauthn ceremonies
+const (
+	webAuthnRegistration = "registration"
+	webAuthnLogin        = "login"
+)
+
+var (
+	errInvalidCeremony     = errors.New("invalid or expired passkey ceremony")
+	errInvalidPasskey      = errors.New("invalid passkey")
+	errClonedAuthenticator = errors.New("passkey authenticator may have been cloned")
+)
+
+// newWebAuthn creates the relying party of the passkey ceremonies, nil unless WEBAUTHN_RP_ID is set.
+// Passkeys have to be discoverable and verify the user, a pin or biometric, so they replace
+// both the password and the totp code.
+func newWebAuthn(config util.Config) (*webauthn.WebAuthn, error) {
+	if len(config.WebAuthnRPID) == 0 {
+		return nil, nil
+	}
+
+	displayName := config.WebAuthnRPDisplayName
+	if len(displayName) == 0 {
+		displayName = config.WebAuthnRPID
+	}
+	webAuthnConfig := &webauthn.Config{
+		RPID:          config.WebAuthnRPID,
+		RPDisplayName: displayName,
+		RPOrigins:     config.WebAuthnRPOrigins,
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			RequireResidentKey: protocol.ResidentKeyRequired(),
+			ResidentKey:        protocol.ResidentKeyRequirementRequired,
+			UserVerification:   protocol.VerificationRequired,
+		},
+	}
+	if config.LoginChallengeDuration > 0 {
+		timeout := webauthn.TimeoutConfig{
+			Enforce:    true,
+			Timeout:    config.LoginChallengeDuration,
+			TimeoutUVD: config.LoginChallengeDuration,
+		}
+		webAuthnConfig.Timeouts = webauthn.TimeoutsConfig{Login: timeout, Registration: timeout}
+	}
+	return webauthn.New(webAuthnConfig)
+}
+
+// webAuthnUserHandleSize is the size of a new user handle, the spec allows up to 64 bytes
+const webAuthnUserHandleSize = 32
+
+// passkeyUser is a user with its passkeys. The user handle is random and says nothing
+// about the user, a discoverable login finds the user by it without asking for a name.
+type passkeyUser struct {
+	handle      []byte
+	user        db.User
+	credentials []db.WebauthnCredential
+}
+
+func (u passkeyUser) WebAuthnID() []byte {
+	return u.handle
+}
+
+func (u passkeyUser) WebAuthnName() string {
+	return u.user.Username
+}
+
+func (u passkeyUser) WebAuthnDisplayName() string {
+	return u.user.FullName
+}
+
+func (u passkeyUser) WebAuthnIcon() string {
+	return ""
+}
+
+func (u passkeyUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.credentials))
+	for _, credential := range u.credentials {
+		transports := make([]protocol.AuthenticatorTransport, 0, len(credential.Transports))
+		for _, transport := range credential.Transports {
+			transports = append(transports, protocol.AuthenticatorTransport(transport))
+		}
+		credentials = append(credentials, webauthn.Credential{
+			ID:              credential.ID,
+			PublicKey:       credential.PublicKey,
+			AttestationType: credential.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: credential.BackupEligible,
+				BackupState:    credential.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    credential.Aaguid,
+				SignCount: uint32(credential.SignCount),
+			},
+		})
+	}
+	return credentials
+}
+
+// getPasskeyUser loads the user with its passkeys, a user registering the first passkey gets a new handle
+func (server *Server) getPasskeyUser(ctx context.Context, username string) (passkeyUser, error) {
+	handle := make([]byte, webAuthnUserHandleSize)
+	if _, err := rand.Read(handle); err != nil {
+		return passkeyUser{}, err
+	}
+	userHandle, err := server.store.CreateWebAuthnUserHandle(ctx, db.CreateWebAuthnUserHandleParams{
+		Handle:   handle,
+		Username: username,
+	})
+	if err != nil {
+		return passkeyUser{}, err
+	}
+	return server.loadPasskeyUser(ctx, userHandle)
+}
+
+// getPasskeyUserByHandle loads the user named by the handle of a discoverable login
+func (server *Server) getPasskeyUserByHandle(ctx context.Context, handle []byte) (passkeyUser, error) {
+	userHandle, err := server.store.GetWebAuthnUserHandle(ctx, handle)
+	if err != nil {
+		return passkeyUser{}, err
+	}
+	return server.loadPasskeyUser(ctx, userHandle)
+}
+
+func (server *Server) loadPasskeyUser(ctx context.Context, userHandle db.WebauthnUserHandle) (passkeyUser, error) {
+	user, err := server.store.GetUser(ctx, userHandle.Username)
+	if err != nil {
+		return passkeyUser{}, err
+	}
+	credentials, err := server.store.ListWebAuthnCredentials(ctx, userHandle.Username)
+	if err != nil {
+		return passkeyUser{}, err
+	}
+	return passkeyUser{handle: userHandle.Handle, user: user, credentials: credentials}, nil
+}
+
+type passkeyResp struct {
+	ID             string    `json:"id"`
+	Name           string    `json:"name"`
+	Transports     []string  `json:"transports"`
+	BackupEligible bool      `json:"backup_eligible"`
+	LastUsedAt     time.Time `json:"last_used_at"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func passkeyResponse(credential db.WebauthnCredential) passkeyResp {
+	return passkeyResp{
+		ID:             base64.RawURLEncoding.EncodeToString(credential.ID),
+		Name:           credential.Name,
+		Transports:     credential.Transports,
+		BackupEligible: credential.BackupEligible,
+		LastUsedAt:     credential.LastUsedAt,
+		CreatedAt:      credential.CreatedAt,
+	}
+}
+
+type beginPasskeyResp struct {
+	CeremonyID uuid.UUID `json:"ceremony_id"`
+	// Options are passed as they are to navigator.credentials.create or navigator.credentials.get
+	Options interface{} `json:"options"`
+}
+
+// createCeremony stores the state of a ceremony until the client finishes it
+func (server *Server) createCeremony(ctx context.Context, kind, username string, session *webauthn.SessionData) (uuid.UUID, error) {
+	sessionData, err := json.Marshal(session)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	ceremony, err := server.store.CreateWebAuthnCeremony(ctx, db.CreateWebAuthnCeremonyParams{
+		ID:          uuid.New(),
+		Kind:        kind,
+		Username:    username,
+		SessionData: sessionData,
+		ExpiresAt:   time.Now().Add(server.config.LoginChallengeDuration),
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return ceremony.ID, nil
+}
+
+// takeCeremony deletes the ceremony, so it is finished at most once, and returns its state.
+// errInvalidCeremony is returned for an unknown or expired ceremony.
+func (server *Server) takeCeremony(ctx context.Context, kind string, id uuid.UUID) (db.WebauthnCeremony, webauthn.SessionData, error) {
+	var session webauthn.SessionData
+	ceremony, err := server.store.TakeWebAuthnCeremony(ctx, db.TakeWebAuthnCeremonyParams{ID: id, Kind: kind})
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return db.WebauthnCeremony{}, session, errInvalidCeremony
+		}
+		return db.WebauthnCeremony{}, session, err
+	}
+	if time.Now().After(ceremony.ExpiresAt) {
+		return db.WebauthnCeremony{}, session, errInvalidCeremony
+	}
+	if err = json.Unmarshal(ceremony.SessionData, &session); err != nil {
+		return db.WebauthnCeremony{}, session, err
+	}
+	return ceremony, session, nil
+}
+
+// beginPasskeyRegistration starts adding a passkey to the authorized user
+func (server *Server) beginPasskeyRegistration(ctx *gin.Context) {
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.getPasskeyUser(ctx, payload.Username)
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	// an authenticator holding one of the passkeys of the user refuses to create another
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(user.credentials))
+	for _, credential := range user.WebAuthnCredentials() {
+		exclusions = append(exclusions, credential.Descriptor())
+	}
+	creation, session, err := server.webAuthn.BeginRegistration(user, webauthn.WithExclusions(exclusions))
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	ceremonyID, err := server.createCeremony(ctx, webAuthnRegistration, payload.Username, session)
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, beginPasskeyResp{CeremonyID: ceremonyID, Options: creation})
+}
+
+type finishPasskeyRegistrationReq struct {
+	CeremonyID uuid.UUID       `json:"ceremony_id" binding:"required"`
+	Name       string          `json:"name" binding:"required,max=100"`
+	Credential json.RawMessage `json:"credential" binding:"required"`
+}
+
+// finishPasskeyRegistration verifies the new credential of the authenticator and stores it
+func (server *Server) finishPasskeyRegistration(ctx *gin.Context) {
+	var req finishPasskeyRegistrationReq
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
+		return
+	}
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	ceremony, session, err := server.takeCeremony(ctx, webAuthnRegistration, req.CeremonyID)
+	if err != nil {
+		if err == errInvalidCeremony {
+			ctx.JSON(errResponse(http.StatusUnauthorized, err))
+			return
+		}
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	if ceremony.Username != payload.Username {
+		ctx.JSON(errResponse(http.StatusUnauthorized, errInvalidCeremony))
+		return
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(req.Credential))
+	if err != nil {
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
+		return
+	}
+	user, err := server.getPasskeyUser(ctx, payload.Username)
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	credential, err := server.webAuthn.CreateCredential(user, session, parsed)
+	if err != nil {
+		ctx.JSON(errResponse(http.StatusUnauthorized, err))
+		return
+	}
+
+	transports := make([]string, 0, len(credential.Transport))
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+	passkey, err := server.store.CreateWebAuthnCredential(ctx, db.CreateWebAuthnCredentialParams{
+		ID:              credential.ID,
+		Username:        payload.Username,
+		Name:            req.Name,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      transports,
+		Aaguid:          credential.Authenticator.AAGUID,
+		SignCount:       int64(credential.Authenticator.SignCount),
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrConflict) {
+			err = fmt.Errorf("passkey is already registered: %w", err)
+		}
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, passkeyResponse(passkey))
+}
+
+func (server *Server) listPasskeys(ctx *gin.Context) {
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	credentials, err := server.store.ListWebAuthnCredentials(ctx, payload.Username)
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	resp := make([]passkeyResp, 0, len(credentials))
+	for _, credential := range credentials {
+		resp = append(resp, passkeyResponse(credential))
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type deletePasskeyReq struct {
+	ID string `uri:"id" binding:"required,base64rawurl"`
+}
+
+func (server *Server) deletePasskey(ctx *gin.Context) {
+	var req deletePasskeyReq
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
+		return
+	}
+	id, err := base64.RawURLEncoding.DecodeString(req.ID)
+	if err != nil {
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
+		return
+	}
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	_, err = server.store.DeleteWebAuthnCredential(ctx, db.DeleteWebAuthnCredentialParams{
+		ID:       id,
+		Username: payload.Username,
+	})
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "passkey deleted"})
+}
+
+// beginPasskeyLogin starts a login with any passkey, the authenticator names the user
+func (server *Server) beginPasskeyLogin(ctx *gin.Context) {
+	assertion, session, err := server.webAuthn.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	ceremonyID, err := server.createCeremony(ctx, webAuthnLogin, "", session)
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, beginPasskeyResp{CeremonyID: ceremonyID, Options: assertion})
+}
+
+type finishPasskeyLoginReq struct {
+	CeremonyID uuid.UUID       `json:"ceremony_id" binding:"required"`
+	DeviceName string          `json:"device_name" binding:"max=100"`
+	Credential json.RawMessage `json:"credential" binding:"required"`
+}
+
+// finishPasskeyLogin verifies the assertion of the authenticator and logs the user in like loginUser.
+// The passkey verified the user, so no totp code is asked for.
+func (server *Server) finishPasskeyLogin(ctx *gin.Context) {
+	var req finishPasskeyLoginReq
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
+		return
+	}
+
+	_, session, err := server.takeCeremony(ctx, webAuthnLogin, req.CeremonyID)
+	if err != nil {
+		if err == errInvalidCeremony {
+			ctx.JSON(errResponse(http.StatusUnauthorized, err))
+			return
+		}
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(req.Credential))
+	if err != nil {
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
+		return
+	}
+
+	// the library hides lookup errors in its own, keep them apart from a bad assertion
+	var user passkeyUser
+	var lookupErr error
+	credential, err := server.webAuthn.ValidateDiscoverableLogin(func(_, userHandle []byte) (webauthn.User, error) {
+		user, lookupErr = server.getPasskeyUserByHandle(ctx, userHandle)
+		return user, lookupErr
+	}, session, parsed)
+	if lookupErr != nil && !errors.Is(lookupErr, db.ErrNotFound) {
+		ctx.JSON(statusErrResponse(lookupErr))
+		return
+	}
+	if err != nil {
+		if lookupErr == nil && len(user.user.Username) > 0 {
+			server.recordLogin(ctx, user.user.Username, loginOutcomeBadPasskey)
+		}
+		ctx.JSON(errResponse(http.StatusUnauthorized, errInvalidPasskey))
+		return
+	}
+	if credential.Authenticator.CloneWarning {
+		server.recordLogin(ctx, user.user.Username, loginOutcomeBadPasskey)
+		ctx.JSON(errResponse(http.StatusUnauthorized, errClonedAuthenticator))
+		return
+	}
+
+	_, err = server.store.UseWebAuthnCredential(ctx, db.UseWebAuthnCredentialParams{
+		ID:          credential.ID,
+		SignCount:   int64(credential.Authenticator.SignCount),
+		BackupState: credential.Flags.BackupState,
+	})
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	resp, err := server.createLoginResponse(ctx, user.user, req.DeviceName)
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// purgeCeremonies deletes abandoned ceremonies every LoginChallengeDuration until ctx is done
+func (server *Server) purgeCeremonies(ctx context.Context) {
+	interval := server.config.LoginChallengeDuration
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := server.store.DeleteExpiredWebAuthnCeremonies(ctx); err != nil {
+				log.Println("purge passkey ceremonies failed:", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,637 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/WanCodeBase/GinModule/db/mock"
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testWebAuthnRPID   = "localhost"
+	testWebAuthnOrigin = "http://localhost:3000"
+)
+
+// authenticator data flags
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttestedData = 0x40
+)
+
+func newPasskeyTestServer(t *testing.T, store db.Store) *Server {
+	config := newTestConfig()
+	config.WebAuthnRPID = testWebAuthnRPID
+	config.WebAuthnRPOrigins = []string{testWebAuthnOrigin}
+
+	server, err := NewServer(config, store)
+	require.NoError(t, err)
+	return server
+}
+
+// softAuthenticator is a software passkey, it creates and signs credentials like a security key
+// with "none" attestation would
+type softAuthenticator struct {
+	credentialID []byte
+	privateKey   *ecdsa.PrivateKey
+	signCount    uint32
+	origin       string
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	credentialID := make([]byte, 16)
+	_, err = rand.Read(credentialID)
+	require.NoError(t, err)
+
+	return &softAuthenticator{
+		credentialID: credentialID,
+		privateKey:   privateKey,
+		origin:       testWebAuthnOrigin,
+	}
+}
+
+func (a *softAuthenticator) publicKey(t *testing.T) []byte {
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: a.privateKey.X.FillBytes(make([]byte, 32)),
+		YCoord: a.privateKey.Y.FillBytes(make([]byte, 32)),
+	})
+	require.NoError(t, err)
+	return publicKey
+}
+
+// credential is the row stored for the passkey of username
+func (a *softAuthenticator) credential(t *testing.T, username string) db.WebauthnCredential {
+	return db.WebauthnCredential{
+		ID:              a.credentialID,
+		Username:        username,
+		Name:            "laptop",
+		PublicKey:       a.publicKey(t),
+		AttestationType: "none",
+		Transports:      []string{},
+		Aaguid:          make([]byte, 16),
+		CreatedAt:       time.Now(),
+	}
+}
+
+func (a *softAuthenticator) authenticatorData(flags byte, attestedData []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(testWebAuthnRPID))
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	return append(data, attestedData...)
+}
+
+func (a *softAuthenticator) clientData(t *testing.T, ceremonyType, challenge string) []byte {
+	clientData, err := json.Marshal(gin.H{
+		"type":      ceremonyType,
+		"challenge": challenge,
+		"origin":    a.origin,
+	})
+	require.NoError(t, err)
+	return clientData
+}
+
+// create answers navigator.credentials.create for challenge
+func (a *softAuthenticator) create(t *testing.T, challenge string) json.RawMessage {
+	attestedData := make([]byte, 16) // zero aaguid
+	attestedData = binary.BigEndian.AppendUint16(attestedData, uint16(len(a.credentialID)))
+	attestedData = append(attestedData, a.credentialID...)
+	attestedData = append(attestedData, a.publicKey(t)...)
+
+	attestationObject, err := webauthncbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": a.authenticatorData(flagUserPresent|flagUserVerified|flagAttestedData, attestedData),
+	})
+	require.NoError(t, err)
+
+	return a.marshalCredential(t, gin.H{
+		"clientDataJSON":    encodeBase64URL(a.clientData(t, "webauthn.create", challenge)),
+		"attestationObject": encodeBase64URL(attestationObject),
+	})
+}
+
+// get answers navigator.credentials.get for challenge as the user with userHandle
+func (a *softAuthenticator) get(t *testing.T, challenge string, userHandle []byte) json.RawMessage {
+	a.signCount++
+	authenticatorData := a.authenticatorData(flagUserPresent|flagUserVerified, nil)
+	clientData := a.clientData(t, "webauthn.get", challenge)
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authenticatorData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.privateKey, digest[:])
+	require.NoError(t, err)
+
+	return a.marshalCredential(t, gin.H{
+		"clientDataJSON":    encodeBase64URL(clientData),
+		"authenticatorData": encodeBase64URL(authenticatorData),
+		"signature":         encodeBase64URL(signature),
+		"userHandle":        encodeBase64URL(userHandle),
+	})
+}
+
+func (a *softAuthenticator) marshalCredential(t *testing.T, response gin.H) json.RawMessage {
+	credential, err := json.Marshal(gin.H{
+		"id":       encodeBase64URL(a.credentialID),
+		"rawId":    encodeBase64URL(a.credentialID),
+		"type":     "public-key",
+		"response": response,
+	})
+	require.NoError(t, err)
+	return credential
+}
+
+// randomUserHandle is the webauthn user handle of username
+func randomUserHandle(t *testing.T, username string) db.WebauthnUserHandle {
+	handle := make([]byte, webAuthnUserHandleSize)
+	_, err := rand.Read(handle)
+	require.NoError(t, err)
+	return db.WebauthnUserHandle{Handle: handle, Username: username, CreatedAt: time.Now()}
+}
+
+func encodeBase64URL(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// beginPasskeyCeremony calls a begin endpoint and returns the stored ceremony and the challenge of its options
+func beginPasskeyCeremony(t *testing.T, server *Server, store *mockdb.MockStore, url string, setAuth func(request *http.Request)) (db.WebauthnCeremony, string) {
+	var ceremony db.WebauthnCeremony
+	store.EXPECT().CreateWebAuthnCeremony(gomock.Any(), gomock.Any()).Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreateWebAuthnCeremonyParams) (db.WebauthnCeremony, error) {
+			ceremony = db.WebauthnCeremony{
+				ID:          arg.ID,
+				Kind:        arg.Kind,
+				Username:    arg.Username,
+				SessionData: arg.SessionData,
+				ExpiresAt:   arg.ExpiresAt,
+				CreatedAt:   time.Now(),
+			}
+			return ceremony, nil
+		})
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodPost, url, nil)
+	require.NoError(t, err)
+	if setAuth != nil {
+		setAuth(request)
+	}
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var resp struct {
+		CeremonyID string `json:"ceremony_id"`
+		Options    struct {
+			PublicKey struct {
+				Challenge string `json:"challenge"`
+			} `json:"publicKey"`
+		} `json:"options"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	require.Equal(t, ceremony.ID.String(), resp.CeremonyID)
+	require.NotEmpty(t, resp.Options.PublicKey.Challenge)
+	return ceremony, resp.Options.PublicKey.Challenge
+}
+
+func TestPasskeyRegistrationAPI(t *testing.T) {
+	user, _ := randomUser()
+	userHandle := randomUserHandle(t, user.Username)
+
+	testCases := []struct {
+		name      string
+		buildReq  func(t *testing.T, ceremony db.WebauthnCeremony, challenge string, authenticator *softAuthenticator) gin.H
+		stubs     func(store *mockdb.MockStore, ceremony db.WebauthnCeremony, authenticator *softAuthenticator)
+		checkResp func(t *testing.T, recorder *httptest.ResponseRecorder, authenticator *softAuthenticator)
+	}{
+		{
+			name: "OK",
+			buildReq: func(t *testing.T, ceremony db.WebauthnCeremony, challenge string, authenticator *softAuthenticator) gin.H {
+				return gin.H{"ceremony_id": ceremony.ID, "name": "laptop", "credential": authenticator.create(t, challenge)}
+			},
+			stubs: func(store *mockdb.MockStore, ceremony db.WebauthnCeremony, authenticator *softAuthenticator) {
+				store.EXPECT().TakeWebAuthnCeremony(gomock.Any(), db.TakeWebAuthnCeremonyParams{ID: ceremony.ID, Kind: webAuthnRegistration}).
+					Times(1).Return(ceremony, nil)
+				store.EXPECT().CreateWebAuthnUserHandle(gomock.Any(), gomock.Any()).Times(1).Return(userHandle, nil)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+				store.EXPECT().ListWebAuthnCredentials(gomock.Any(), user.Username).Times(1).Return([]db.WebauthnCredential{}, nil)
+				store.EXPECT().CreateWebAuthnCredential(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateWebAuthnCredentialParams) (db.WebauthnCredential, error) {
+						require.Equal(t, authenticator.credentialID, arg.ID)
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, "laptop", arg.Name)
+						require.Equal(t, authenticator.publicKey(t), arg.PublicKey)
+						require.Equal(t, "none", arg.AttestationType)
+						return db.WebauthnCredential{
+							ID:              arg.ID,
+							Username:        arg.Username,
+							Name:            arg.Name,
+							PublicKey:       arg.PublicKey,
+							AttestationType: arg.AttestationType,
+							Transports:      arg.Transports,
+							Aaguid:          arg.Aaguid,
+							CreatedAt:       time.Now(),
+						}, nil
+					})
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder, authenticator *softAuthenticator) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp passkeyResp
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.Equal(t, encodeBase64URL(authenticator.credentialID), resp.ID)
+				require.Equal(t, "laptop", resp.Name)
+				require.NotContains(t, recorder.Body.String(), "public_key")
+			},
+		},
+		{
+			name: "WrongChallenge",
+			buildReq: func(t *testing.T, ceremony db.WebauthnCeremony, challenge string, authenticator *softAuthenticator) gin.H {
+				return gin.H{"ceremony_id": ceremony.ID, "name": "laptop", "credential": authenticator.create(t, encodeBase64URL([]byte("other challenge")))}
+			},
+			stubs: func(store *mockdb.MockStore, ceremony db.WebauthnCeremony, authenticator *softAuthenticator) {
+				store.EXPECT().TakeWebAuthnCeremony(gomock.Any(), gomock.Any()).Times(1).Return(ceremony, nil)
+				store.EXPECT().CreateWebAuthnUserHandle(gomock.Any(), gomock.Any()).Times(1).Return(userHandle, nil)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+				store.EXPECT().ListWebAuthnCredentials(gomock.Any(), user.Username).Times(1).Return([]db.WebauthnCredential{}, nil)
+				store.EXPECT().CreateWebAuthnCredential(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder, authenticator *softAuthenticator) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "WrongOrigin",
+			buildReq: func(t *testing.T, ceremony db.WebauthnCeremony, challenge string, authenticator *softAuthenticator) gin.H {
+				authenticator.origin = "https://phishing.example.com"
+				return gin.H{"ceremony_id": ceremony.ID, "name": "laptop", "credential": authenticator.create(t, challenge)}
+			},
+			stubs: func(store *mockdb.MockStore, ceremony db.WebauthnCeremony, authenticator *softAuthenticator) {
+				store.EXPECT().TakeWebAuthnCeremony(gomock.Any(), gomock.Any()).Times(1).Return(ceremony, nil)
+				store.EXPECT().CreateWebAuthnUserHandle(gomock.Any(), gomock.Any()).Times(1).Return(userHandle, nil)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+				store.EXPECT().ListWebAuthnCredentials(gomock.Any(), user.Username).Times(1).Return([]db.WebauthnCredential{}, nil)
+				store.EXPECT().CreateWebAuthnCredential(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder, authenticator *softAuthenticator) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "CeremonyOfOtherUser",
+			buildReq: func(t *testing.T, ceremony db.WebauthnCeremony, challenge string, authenticator *softAuthenticator) gin.H {
+				return gin.H{"ceremony_id": ceremony.ID, "name": "laptop", "credential": authenticator.create(t, challenge)}
+			},
+			stubs: func(store *mockdb.MockStore, ceremony db.WebauthnCeremony, authenticator *softAuthenticator) {
+				ceremony.Username = util.RandomOwner()
+				store.EXPECT().TakeWebAuthnCeremony(gomock.Any(), gomock.Any()).Times(1).Return(ceremony, nil)
+				store.EXPECT().CreateWebAuthnCredential(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder, authenticator *softAuthenticator) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "UsedCeremony",
+			buildReq: func(t *testing.T, ceremony db.WebauthnCeremony, challenge string, authenticator *softAuthenticator) gin.H {
+				return gin.H{"ceremony_id": ceremony.ID, "name": "laptop", "credential": authenticator.create(t, challenge)}
+			},
+			stubs: func(store *mockdb.MockStore, ceremony db.WebauthnCeremony, authenticator *softAuthenticator) {
+				store.EXPECT().TakeWebAuthnCeremony(gomock.Any(), gomock.Any()).Times(1).Return(db.WebauthnCeremony{}, db.ErrNotFound)
+				store.EXPECT().CreateWebAuthnCredential(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder, authenticator *softAuthenticator) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "NoName",
+			buildReq: func(t *testing.T, ceremony db.WebauthnCeremony, challenge string, authenticator *softAuthenticator) gin.H {
+				return gin.H{"ceremony_id": ceremony.ID, "credential": authenticator.create(t, challenge)}
+			},
+			stubs: func(store *mockdb.MockStore, ceremony db.WebauthnCeremony, authenticator *softAuthenticator) {
+				store.EXPECT().TakeWebAuthnCeremony(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder, authenticator *softAuthenticator) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newPasskeyTestServer(t, store)
+			authenticator := newSoftAuthenticator(t)
+			setAuth := func(request *http.Request) {
+				setAuthorization(t, request, server.tokenMaker, user.Username, util.DepositorRole, time.Minute, authorizationHeaderType)
+			}
+
+			// the handle of a user registering the first passkey is random
+			store.EXPECT().CreateWebAuthnUserHandle(gomock.Any(), gomock.Any()).Times(1).
+				DoAndReturn(func(_ context.Context, arg db.CreateWebAuthnUserHandleParams) (db.WebauthnUserHandle, error) {
+					require.Equal(t, user.Username, arg.Username)
+					require.Len(t, arg.Handle, webAuthnUserHandleSize)
+					return userHandle, nil
+				})
+			store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+			store.EXPECT().ListWebAuthnCredentials(gomock.Any(), user.Username).Times(1).Return([]db.WebauthnCredential{}, nil)
+			ceremony, challenge := beginPasskeyCeremony(t, server, store, "/user/passkeys/register/begin", setAuth)
+			require.Equal(t, webAuthnRegistration, ceremony.Kind)
+			require.Equal(t, user.Username, ceremony.Username)
+
+			var session webauthn.SessionData
+			require.NoError(t, json.Unmarshal(ceremony.SessionData, &session))
+			require.Equal(t, userHandle.Handle, session.UserID)
+			require.NotContains(t, string(ceremony.SessionData), encodeBase64URL([]byte(user.Username)))
+
+			c.stubs(store, ceremony, authenticator)
+			body, err := json.Marshal(c.buildReq(t, ceremony, challenge, authenticator))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, "/user/passkeys/register/finish", bytes.NewReader(body))
+			require.NoError(t, err)
+			setAuth(request)
+
+			server.router.ServeHTTP(recorder, request)
+			c.checkResp(t, recorder, authenticator)
+		})
+	}
+}
+
+func TestPasskeyLoginAPI(t *testing.T) {
+	user, _ := randomUser()
+	userHandle := randomUserHandle(t, user.Username)
+
+	testCases := []struct {
+		name      string
+		buildReq  func(t *testing.T, ceremony db.WebauthnCeremony, challenge string, authenticator *softAuthenticator) gin.H
+		stubs     func(t *testing.T, store *mockdb.MockStore, ceremony db.WebauthnCeremony, authenticator *softAuthenticator)
+		checkResp func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildReq: func(t *testing.T, ceremony db.WebauthnCeremony, challenge string, authenticator *softAuthenticator) gin.H {
+				return gin.H{"ceremony_id": ceremony.ID, "credential": authenticator.get(t, challenge, userHandle.Handle)}
+			},
+			stubs: func(t *testing.T, store *mockdb.MockStore, ceremony db.WebauthnCeremony, authenticator *softAuthenticator) {
+				store.EXPECT().TakeWebAuthnCeremony(gomock.Any(), db.TakeWebAuthnCeremonyParams{ID: ceremony.ID, Kind: webAuthnLogin}).
+					Times(1).Return(ceremony, nil)
+				store.EXPECT().GetWebAuthnUserHandle(gomock.Any(), userHandle.Handle).Times(1).Return(userHandle, nil)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+				store.EXPECT().ListWebAuthnCredentials(gomock.Any(), user.Username).Times(1).
+					Return([]db.WebauthnCredential{authenticator.credential(t, user.Username)}, nil)
+				store.EXPECT().UseWebAuthnCredential(gomock.Any(), db.UseWebAuthnCredentialParams{
+					ID:        authenticator.credentialID,
+					SignCount: 1,
+				}).Times(1).Return(db.WebauthnCredential{}, nil)
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DeleteLoginThrottle(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, nil)
+				expectLoginSuccess(store)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp loginUserResp
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.NotEmpty(t, resp.AccessToken)
+				require.NotEmpty(t, resp.RefreshToken)
+				require.Equal(t, user.Username, resp.User.Username)
+			},
+		},
+		{
+			name: "WrongKey",
+			buildReq: func(t *testing.T, ceremony db.WebauthnCeremony, challenge string, authenticator *softAuthenticator) gin.H {
+				impostor := newSoftAuthenticator(t)
+				impostor.credentialID = authenticator.credentialID
+				return gin.H{"ceremony_id": ceremony.ID, "credential": impostor.get(t, challenge, userHandle.Handle)}
+			},
+			stubs: func(t *testing.T, store *mockdb.MockStore, ceremony db.WebauthnCeremony, authenticator *softAuthenticator) {
+				store.EXPECT().TakeWebAuthnCeremony(gomock.Any(), gomock.Any()).Times(1).Return(ceremony, nil)
+				store.EXPECT().GetWebAuthnUserHandle(gomock.Any(), userHandle.Handle).Times(1).Return(userHandle, nil)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+				store.EXPECT().ListWebAuthnCredentials(gomock.Any(), user.Username).Times(1).
+					Return([]db.WebauthnCredential{authenticator.credential(t, user.Username)}, nil)
+				store.EXPECT().UseWebAuthnCredential(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+				expectLoginEvent(store, loginOutcomeBadPasskey)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ClonedAuthenticator",
+			buildReq: func(t *testing.T, ceremony db.WebauthnCeremony, challenge string, authenticator *softAuthenticator) gin.H {
+				return gin.H{"ceremony_id": ceremony.ID, "credential": authenticator.get(t, challenge, userHandle.Handle)}
+			},
+			stubs: func(t *testing.T, store *mockdb.MockStore, ceremony db.WebauthnCeremony, authenticator *softAuthenticator) {
+				// the original authenticator has signed more often than this one
+				credential := authenticator.credential(t, user.Username)
+				credential.SignCount = 10
+
+				store.EXPECT().TakeWebAuthnCeremony(gomock.Any(), gomock.Any()).Times(1).Return(ceremony, nil)
+				store.EXPECT().GetWebAuthnUserHandle(gomock.Any(), userHandle.Handle).Times(1).Return(userHandle, nil)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+				store.EXPECT().ListWebAuthnCredentials(gomock.Any(), user.Username).Times(1).
+					Return([]db.WebauthnCredential{credential}, nil)
+				store.EXPECT().UseWebAuthnCredential(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+				expectLoginEvent(store, loginOutcomeBadPasskey)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "UnknownUser",
+			buildReq: func(t *testing.T, ceremony db.WebauthnCeremony, challenge string, authenticator *softAuthenticator) gin.H {
+				return gin.H{"ceremony_id": ceremony.ID, "credential": authenticator.get(t, challenge, userHandle.Handle)}
+			},
+			stubs: func(t *testing.T, store *mockdb.MockStore, ceremony db.WebauthnCeremony, authenticator *softAuthenticator) {
+				store.EXPECT().TakeWebAuthnCeremony(gomock.Any(), gomock.Any()).Times(1).Return(ceremony, nil)
+				store.EXPECT().GetWebAuthnUserHandle(gomock.Any(), userHandle.Handle).Times(1).Return(db.WebauthnUserHandle{}, db.ErrNotFound)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateLoginEvent(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "LegacyUsernameHandle",
+			buildReq: func(t *testing.T, ceremony db.WebauthnCeremony, challenge string, authenticator *softAuthenticator) gin.H {
+				return gin.H{"ceremony_id": ceremony.ID, "credential": authenticator.get(t, challenge, []byte(user.Username))}
+			},
+			stubs: func(t *testing.T, store *mockdb.MockStore, ceremony db.WebauthnCeremony, authenticator *softAuthenticator) {
+				// a passkey registered before the random handles keeps the username as handle
+				legacyHandle := db.WebauthnUserHandle{Handle: []byte(user.Username), Username: user.Username}
+				store.EXPECT().TakeWebAuthnCeremony(gomock.Any(), gomock.Any()).Times(1).Return(ceremony, nil)
+				store.EXPECT().GetWebAuthnUserHandle(gomock.Any(), legacyHandle.Handle).Times(1).Return(legacyHandle, nil)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+				store.EXPECT().ListWebAuthnCredentials(gomock.Any(), user.Username).Times(1).
+					Return([]db.WebauthnCredential{authenticator.credential(t, user.Username)}, nil)
+				store.EXPECT().UseWebAuthnCredential(gomock.Any(), gomock.Any()).Times(1).Return(db.WebauthnCredential{}, nil)
+				store.EXPECT().DeleteLoginThrottle(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, nil)
+				expectLoginSuccess(store)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiredCeremony",
+			buildReq: func(t *testing.T, ceremony db.WebauthnCeremony, challenge string, authenticator *softAuthenticator) gin.H {
+				return gin.H{"ceremony_id": ceremony.ID, "credential": authenticator.get(t, challenge, userHandle.Handle)}
+			},
+			stubs: func(t *testing.T, store *mockdb.MockStore, ceremony db.WebauthnCeremony, authenticator *softAuthenticator) {
+				ceremony.ExpiresAt = time.Now().Add(-time.Second)
+				store.EXPECT().TakeWebAuthnCeremony(gomock.Any(), gomock.Any()).Times(1).Return(ceremony, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidCredential",
+			buildReq: func(t *testing.T, ceremony db.WebauthnCeremony, challenge string, authenticator *softAuthenticator) gin.H {
+				return gin.H{"ceremony_id": ceremony.ID, "credential": gin.H{"id": "invalid"}}
+			},
+			stubs: func(t *testing.T, store *mockdb.MockStore, ceremony db.WebauthnCeremony, authenticator *softAuthenticator) {
+				store.EXPECT().TakeWebAuthnCeremony(gomock.Any(), gomock.Any()).Times(1).Return(ceremony, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newPasskeyTestServer(t, store)
+			authenticator := newSoftAuthenticator(t)
+
+			ceremony, challenge := beginPasskeyCeremony(t, server, store, "/user/login/passkey/begin", nil)
+			require.Equal(t, webAuthnLogin, ceremony.Kind)
+			require.Empty(t, ceremony.Username)
+
+			c.stubs(t, store, ceremony, authenticator)
+			body, err := json.Marshal(c.buildReq(t, ceremony, challenge, authenticator))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, "/user/login/passkey/finish", bytes.NewReader(body))
+			require.NoError(t, err)
+			setClientInfo(request)
+
+			server.router.ServeHTTP(recorder, request)
+			c.checkResp(t, recorder)
+		})
+	}
+}
+
+func TestDeletePasskeyAPI(t *testing.T) {
+	user, _ := randomUser()
+	credentialID := []byte(util.RandomString(16))
+
+	testCases := []struct {
+		name      string
+		id        string
+		stubs     func(store *mockdb.MockStore)
+		checkResp func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			id:   encodeBase64URL(credentialID),
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().DeleteWebAuthnCredential(gomock.Any(), db.DeleteWebAuthnCredentialParams{
+					ID:       credentialID,
+					Username: user.Username,
+				}).Times(1).Return(db.WebauthnCredential{ID: credentialID, Username: user.Username}, nil)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			id:   encodeBase64URL(credentialID),
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().DeleteWebAuthnCredential(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.WebauthnCredential{}, db.ErrNotFound)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidID",
+			id:   "not+base64",
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().DeleteWebAuthnCredential(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			c.stubs(store)
+
+			server := newPasskeyTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/user/passkeys/%s", c.id), nil)
+			require.NoError(t, err)
+			setAuthorization(t, request, server.tokenMaker, user.Username, util.DepositorRole, time.Minute, authorizationHeaderType)
+
+			server.router.ServeHTTP(recorder, request)
+			c.checkResp(t, recorder)
+		})
+	}
+}
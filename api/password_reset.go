@@ -0,0 +1,140 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const passwordResetTokenBytes = 32
+
+var errInvalidResetToken = errors.New("password reset token is invalid or has expired")
+
+type forgotPasswordReq struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// forgotPassword emails a single-use reset link to the owner of the address.
+// The response is the same whether or not the address is registered.
+func (server *Server) forgotPassword(ctx *gin.Context) {
+	var req forgotPasswordReq
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
+		return
+	}
+	resp := gin.H{"message": "if the email is registered, a password reset link has been sent"}
+
+	user, err := server.store.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			ctx.JSON(http.StatusOK, resp)
+			return
+		}
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	resetToken, err := util.RandomSecret(passwordResetTokenBytes)
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	expiresAt := time.Now().Add(server.config.PasswordResetDuration)
+
+	_, err = server.store.CreatePasswordResetTx(ctx, db.CreatePasswordResetTxParams{
+		ID:          uuid.New(),
+		Username:    user.Username,
+		HashedToken: util.HashSecret(resetToken),
+		ExpiresAt:   expiresAt,
+		Email: db.CreateOutboxEmailParams{
+			ToAddress: user.Email,
+			Subject:   "Reset your password",
+			Body:      server.passwordResetEmailBody(user, resetToken, expiresAt),
+		},
+	})
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (server *Server) passwordResetEmailBody(user db.User, resetToken string, expiresAt time.Time) string {
+	link := resetToken
+	if len(server.config.PasswordResetURL) > 0 {
+		link = fmt.Sprintf("%s?token=%s", server.config.PasswordResetURL, resetToken)
+	}
+	return fmt.Sprintf("Hi %s,\n\n"+
+		"Someone asked to reset the password of your account %s. If it was you, open\n\n"+
+		"%s\n\n"+
+		"before %s. Otherwise you can ignore this email, your password stays unchanged.\n",
+		user.FullName, user.Username, link, expiresAt.UTC().Format(time.RFC1123))
+}
+
+type resetPasswordReq struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,password"`
+}
+
+// resetPassword consumes a reset token and sets a new password.
+// Like a password change, it revokes every token issued before.
+func (server *Server) resetPassword(ctx *gin.Context) {
+	var req resetPasswordReq
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		ctx.JSON(server.validationErrResponse(err))
+		return
+	}
+
+	resetToken, err := server.store.GetPasswordResetTokenByHash(ctx, util.HashSecret(req.Token))
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			ctx.JSON(errResponse(http.StatusUnauthorized, errInvalidResetToken))
+			return
+		}
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	if resetToken.UsedAt.Valid || time.Now().After(resetToken.ExpiresAt) {
+		ctx.JSON(errResponse(http.StatusUnauthorized, errInvalidResetToken))
+		return
+	}
+	user, err := server.store.GetUser(ctx, resetToken.Username)
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	if err := server.passwordPolicy.Check(req.NewPassword, user.Username, user.Email); err != nil {
+		ctx.JSON(passwordPolicyErrResponse("new_password", err))
+		return
+	}
+
+	hashedPassword, err := server.hasher.Hash(req.NewPassword)
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	result, err := server.store.ResetPasswordTx(ctx, db.ResetPasswordTxParams{
+		TokenID:           resetToken.ID,
+		HashedPassword:    hashedPassword,
+		PasswordChangedAt: time.Now(),
+	})
+	if err != nil {
+		// used or expired by a concurrent request
+		if errors.Is(err, db.ErrNotFound) {
+			ctx.JSON(errResponse(http.StatusUnauthorized, errInvalidResetToken))
+			return
+		}
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	server.revocations.PasswordChanged(result.User.Username, result.User.PasswordChangedAt)
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "password has been reset"})
+}
@@ -0,0 +1,247 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/WanCodeBase/GinModule/db/mock"
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+var resetLinkRegexp = regexp.MustCompile(`reset_password\?token=([0-9a-f]+)`)
+
+func TestForgotPasswordAPI(t *testing.T) {
+	user, _ := randomUser()
+
+	testCases := []struct {
+		name      string
+		body      gin.H
+		stubs     func(store *mockdb.MockStore)
+		checkResp func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"email": user.Email},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), user.Email).Times(1).Return(user, nil)
+				store.EXPECT().CreatePasswordResetTx(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreatePasswordResetTxParams) (db.CreatePasswordResetTxResult, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, user.Email, arg.Email.ToAddress)
+						require.WithinDuration(t, time.Now().Add(time.Minute), arg.ExpiresAt, time.Second)
+
+						// only the hash of the emailed token is stored
+						match := resetLinkRegexp.FindStringSubmatch(arg.Email.Body)
+						require.Len(t, match, 2)
+						require.Equal(t, util.HashSecret(match[1]), arg.HashedToken)
+						return db.CreatePasswordResetTxResult{}, nil
+					})
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "UnknownEmail",
+			body: gin.H{"email": user.Email},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), user.Email).Times(1).Return(db.User{}, db.ErrNotFound)
+				store.EXPECT().CreatePasswordResetTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// indistinguishable from a registered email
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidEmail",
+			body: gin.H{"email": "invalid"},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "TxError",
+			body: gin.H{"email": user.Email},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), user.Email).Times(1).Return(user, nil)
+				store.EXPECT().CreatePasswordResetTx(gomock.Any(), gomock.Any()).Times(1).Return(db.CreatePasswordResetTxResult{}, sql.ErrConnDone)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			c.stubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			body, err := json.Marshal(c.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/user/password/forgot", bytes.NewReader(body))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			c.checkResp(t, recorder)
+		})
+	}
+}
+
+func TestResetPasswordAPI(t *testing.T) {
+	user, _ := randomUser()
+	token := util.RandomString(32)
+	newPassword := util.RandomString(8)
+	resetToken := db.PasswordResetToken{
+		ID:          uuid.New(),
+		Username:    user.Username,
+		HashedToken: util.HashSecret(token),
+		ExpiresAt:   time.Now().Add(time.Minute),
+	}
+
+	testCases := []struct {
+		name      string
+		body      gin.H
+		stubs     func(store *mockdb.MockStore)
+		checkResp func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"token": token, "new_password": newPassword},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPasswordResetTokenByHash(gomock.Any(), resetToken.HashedToken).Times(1).Return(resetToken, nil)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.ResetPasswordTxParams) (db.ResetPasswordTxResult, error) {
+						require.Equal(t, resetToken.ID, arg.TokenID)
+						require.NoError(t, util.CheckPassword(newPassword, arg.HashedPassword))
+
+						changed := user
+						changed.PasswordChangedAt = arg.PasswordChangedAt
+						return db.ResetPasswordTxResult{User: changed}, nil
+					})
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "UnknownToken",
+			body: gin.H{"token": "unknown", "new_password": newPassword},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPasswordResetTokenByHash(gomock.Any(), gomock.Any()).Times(1).Return(db.PasswordResetToken{}, db.ErrNotFound)
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "UsedToken",
+			body: gin.H{"token": token, "new_password": newPassword},
+			stubs: func(store *mockdb.MockStore) {
+				used := resetToken
+				used.UsedAt = sql.NullTime{Time: time.Now(), Valid: true}
+				store.EXPECT().GetPasswordResetTokenByHash(gomock.Any(), resetToken.HashedToken).Times(1).Return(used, nil)
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiredToken",
+			body: gin.H{"token": token, "new_password": newPassword},
+			stubs: func(store *mockdb.MockStore) {
+				expired := resetToken
+				expired.ExpiresAt = time.Now().Add(-time.Second)
+				store.EXPECT().GetPasswordResetTokenByHash(gomock.Any(), resetToken.HashedToken).Times(1).Return(expired, nil)
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ConcurrentlyUsed",
+			body: gin.H{"token": token, "new_password": newPassword},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPasswordResetTokenByHash(gomock.Any(), resetToken.HashedToken).Times(1).Return(resetToken, nil)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(1).Return(db.ResetPasswordTxResult{}, db.ErrNotFound)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "PasswordContainsEmail",
+			body: gin.H{"token": token, "new_password": util.RandomString(4) + strings.Split(user.Email, "@")[0]},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPasswordResetTokenByHash(gomock.Any(), resetToken.HashedToken).Times(1).Return(resetToken, nil)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "must not contain the username or email")
+			},
+		},
+		{
+			name: "ShortPassword",
+			body: gin.H{"token": token, "new_password": "123"},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPasswordResetTokenByHash(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			c.stubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			body, err := json.Marshal(c.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/user/password/reset", bytes.NewReader(body))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			c.checkResp(t, recorder)
+		})
+	}
+}
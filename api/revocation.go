@@ -0,0 +1,242 @@
+package api
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/token"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultRevocationPurgeInterval  = 10 * time.Minute
+	defaultRevocationReloadInterval = 5 * time.Second
+)
+
+// revocationStore keeps the token denylist in postgres and caches it in memory,
+// so authMiddleware never has to hit the database on a request.
+// Revocations made by other server instances are seen after the next reload.
+// maxTokenDuration is the longest lifetime of any issued token, older entries are useless.
+type revocationStore struct {
+	store            db.Store
+	maxTokenDuration time.Duration
+
+	mu              sync.RWMutex
+	tokens          map[uuid.UUID]time.Time // token id -> token expiry
+	logouts         map[string]db.UserLogout
+	passwordChanges map[string]time.Time    // username -> password_changed_at
+	sessions        map[uuid.UUID]time.Time // blocked session id -> session expiry
+}
+
+func newRevocationStore(store db.Store, maxTokenDuration time.Duration) *revocationStore {
+	return &revocationStore{
+		store:            store,
+		maxTokenDuration: maxTokenDuration,
+		tokens:           make(map[uuid.UUID]time.Time),
+		logouts:          make(map[string]db.UserLogout),
+		passwordChanges:  make(map[string]time.Time),
+		sessions:         make(map[uuid.UUID]time.Time),
+	}
+}
+
+// Revoke denies a single token until it expires
+func (r *revocationStore) Revoke(ctx context.Context, payload *token.Payload) error {
+	err := r.store.CreateRevokedToken(ctx, db.CreateRevokedTokenParams{
+		ID:        payload.ID,
+		Username:  payload.Username,
+		ExpiresAt: payload.ExpireAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.tokens[payload.ID] = payload.ExpireAt
+	r.mu.Unlock()
+	return nil
+}
+
+// RevokeAll denies every token of the user issued before now
+func (r *revocationStore) RevokeAll(ctx context.Context, username string) error {
+	now := time.Now()
+	logout, err := r.store.UpsertUserLogout(ctx, db.UpsertUserLogoutParams{
+		Username:    username,
+		LoggedOutAt: now,
+		ExpiresAt:   now.Add(r.maxTokenDuration),
+	})
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.logouts[username] = logout
+	r.mu.Unlock()
+	return nil
+}
+
+// PasswordChanged denies every token of the user issued before changedAt.
+// The password_changed_at column is the source of truth, this only updates the cache.
+func (r *revocationStore) PasswordChanged(username string, changedAt time.Time) {
+	r.mu.Lock()
+	r.passwordChanges[username] = changedAt
+	r.mu.Unlock()
+}
+
+// SessionRevoked denies every token of a session blocked until the session expires.
+// The is_blocked column is the source of truth, this only updates the cache.
+func (r *revocationStore) SessionRevoked(sessionID uuid.UUID, expiresAt time.Time) {
+	r.mu.Lock()
+	r.sessions[sessionID] = expiresAt
+	r.mu.Unlock()
+}
+
+// IsRevoked reports whether the token was denied by Revoke, RevokeAll, a password change or a blocked session.
+// An impersonation token is also denied by RevokeAll or a password change of its actor.
+func (r *revocationStore) IsRevoked(payload *token.Payload) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.tokens[payload.ID]; ok {
+		return true
+	}
+	if _, ok := r.sessions[payload.SessionID]; ok && payload.SessionID != uuid.Nil {
+		return true
+	}
+	// the refresh token of a session has the id of the session
+	if _, ok := r.sessions[payload.ID]; ok {
+		return true
+	}
+	if r.isUserRevoked(payload.Username, payload.IssuedAt) {
+		return true
+	}
+	return payload.IsImpersonated() && r.isUserRevoked(payload.Actor, payload.IssuedAt)
+}
+
+// isUserRevoked reports whether tokens of username issued at issuedAt were denied, r.mu must be held
+func (r *revocationStore) isUserRevoked(username string, issuedAt time.Time) bool {
+	if changedAt, ok := r.passwordChanges[username]; ok && issuedBefore(issuedAt, changedAt) {
+		return true
+	}
+	logout, ok := r.logouts[username]
+	return ok && issuedBefore(issuedAt, logout.LoggedOutAt)
+}
+
+// issuedBefore compares in whole seconds because the iat of a jwt has no fraction.
+// A token issued in the same second as cutoff is kept, otherwise the login right
+// after a password change or logout_all would be rejected.
+func issuedBefore(issuedAt, cutoff time.Time) bool {
+	return issuedAt.Before(cutoff.Truncate(time.Second))
+}
+
+// Load replaces the cache with the unexpired rows in the database
+func (r *revocationStore) Load(ctx context.Context) error {
+	revokedTokens, err := r.store.ListRevokedTokens(ctx)
+	if err != nil {
+		return err
+	}
+	userLogouts, err := r.store.ListUserLogouts(ctx)
+	if err != nil {
+		return err
+	}
+	userPasswordChanges, err := r.store.ListPasswordChanges(ctx, time.Now().Add(-r.maxTokenDuration))
+	if err != nil {
+		return err
+	}
+	blockedSessions, err := r.store.ListBlockedSessions(ctx)
+	if err != nil {
+		return err
+	}
+
+	tokens := make(map[uuid.UUID]time.Time, len(revokedTokens))
+	for _, revokedToken := range revokedTokens {
+		tokens[revokedToken.ID] = revokedToken.ExpiresAt
+	}
+	logouts := make(map[string]db.UserLogout, len(userLogouts))
+	for _, logout := range userLogouts {
+		logouts[logout.Username] = logout
+	}
+	passwordChanges := make(map[string]time.Time, len(userPasswordChanges))
+	for _, change := range userPasswordChanges {
+		passwordChanges[change.Username] = change.PasswordChangedAt
+	}
+	sessions := make(map[uuid.UUID]time.Time, len(blockedSessions))
+	for _, session := range blockedSessions {
+		sessions[session.ID] = session.ExpiresAt
+	}
+
+	r.mu.Lock()
+	r.tokens = tokens
+	r.logouts = logouts
+	r.passwordChanges = passwordChanges
+	r.sessions = sessions
+	r.mu.Unlock()
+	return nil
+}
+
+// Purge deletes expired rows from the database and the cache
+func (r *revocationStore) Purge(ctx context.Context) error {
+	if _, err := r.store.DeleteExpiredRevokedTokens(ctx); err != nil {
+		return err
+	}
+	if _, err := r.store.DeleteExpiredUserLogouts(ctx); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	r.mu.Lock()
+	for id, expiresAt := range r.tokens {
+		if !now.Before(expiresAt) {
+			delete(r.tokens, id)
+		}
+	}
+	for username, logout := range r.logouts {
+		if !now.Before(logout.ExpiresAt) {
+			delete(r.logouts, username)
+		}
+	}
+	for username, changedAt := range r.passwordChanges {
+		if !now.Before(changedAt.Add(r.maxTokenDuration)) {
+			delete(r.passwordChanges, username)
+		}
+	}
+	for id, expiresAt := range r.sessions {
+		if !now.Before(expiresAt) {
+			delete(r.sessions, id)
+		}
+	}
+	r.mu.Unlock()
+	return nil
+}
+
+// Run reloads the cache every reloadInterval and purges expired entries every purgeInterval until ctx is done.
+// Reloading picks up revocations made by other server instances, reloadInterval bounds how long they go unseen.
+func (r *revocationStore) Run(ctx context.Context, purgeInterval, reloadInterval time.Duration) {
+	if purgeInterval <= 0 {
+		purgeInterval = defaultRevocationPurgeInterval
+	}
+	if reloadInterval <= 0 {
+		reloadInterval = defaultRevocationReloadInterval
+	}
+	purgeTicker := time.NewTicker(purgeInterval)
+	defer purgeTicker.Stop()
+	reloadTicker := time.NewTicker(reloadInterval)
+	defer reloadTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-purgeTicker.C:
+			if err := r.Purge(ctx); err != nil {
+				log.Println("purge revoked tokens failed:", err)
+			}
+		case <-reloadTicker.C:
+			if err := r.Load(ctx); err != nil {
+				log.Println("reload revoked tokens failed:", err)
+			}
+		}
+	}
+}
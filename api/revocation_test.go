@@ -0,0 +1,48 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mockdb "github.com/WanCodeBase/GinModule/db/mock"
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/token"
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// a token revoked by another server instance is rejected after the next reload,
+// long before the purge interval
+func TestRevocationStoreRunReloads(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	revocations := newRevocationStore(store, time.Hour)
+
+	payload, err := token.NewPayload(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	store.EXPECT().ListRevokedTokens(gomock.Any()).MinTimes(1).Return([]db.RevokedToken{
+		{ID: payload.ID, Username: payload.Username, ExpiresAt: payload.ExpireAt},
+	}, nil)
+	store.EXPECT().ListUserLogouts(gomock.Any()).MinTimes(1).Return(nil, nil)
+	store.EXPECT().ListPasswordChanges(gomock.Any(), gomock.Any()).MinTimes(1).Return(nil, nil)
+	store.EXPECT().ListBlockedSessions(gomock.Any()).MinTimes(1).Return(nil, nil)
+	store.EXPECT().DeleteExpiredRevokedTokens(gomock.Any()).Times(0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		revocations.Run(ctx, time.Hour, 10*time.Millisecond)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		return revocations.IsRevoked(payload)
+	}, time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+}
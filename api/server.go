@@ -1,35 +1,92 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/mail"
 	"github.com/WanCodeBase/GinModule/token"
 	"github.com/WanCodeBase/GinModule/util"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"time"
 )
 
 type Server struct {
-	config     util.Config
-	tokenMaker token.Maker
-	store      db.Store
-	router     *gin.Engine
+	config         util.Config
+	tokenMaker     token.Maker
+	store          db.Store
+	keyring        *token.Keyring
+	revocations    *revocationStore
+	throttle       *loginThrottle
+	mailSender     mail.Sender
+	outbox         *mail.Outbox
+	hasher         util.PasswordHasher
+	passwordPolicy *util.PasswordPolicy
+	loginNotifier  LoginNotifier
+	cookies        *authCookies
+	webAuthn       *webauthn.WebAuthn
+	router         *gin.Engine
 }
 
 func NewServer(config util.Config, store db.Store) (*Server, error) {
-	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+	tokenMaker, keyring, err := newTokenMaker(config)
 	if err != nil {
 		return nil, fmt.Errorf("create token maker failed:%w", err)
 	}
+	mailSender, err := mail.NewSender(config.MailSender, mail.Config{
+		From:         config.MailFrom,
+		SMTPAddress:  config.SMTPAddress,
+		SMTPUsername: config.SMTPUsername,
+		SMTPPassword: config.SMTPPassword,
+		FileDir:      config.MailFileDir,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create mail sender failed:%w", err)
+	}
+	hasher, err := util.NewPasswordHasher(config)
+	if err != nil {
+		return nil, fmt.Errorf("create password hasher failed:%w", err)
+	}
+	passwordPolicy, err := util.NewPasswordPolicy(config)
+	if err != nil {
+		return nil, fmt.Errorf("create password policy failed:%w", err)
+	}
+	loginNotifier, err := NewLoginNotifier(config.LoginNotifier, store)
+	if err != nil {
+		return nil, fmt.Errorf("create login notifier failed:%w", err)
+	}
+	cookies, err := newAuthCookies(config)
+	if err != nil {
+		return nil, fmt.Errorf("create auth cookies failed:%w", err)
+	}
+	webAuthn, err := newWebAuthn(config)
+	if err != nil {
+		return nil, fmt.Errorf("create webauthn relying party failed:%w", err)
+	}
 	server := &Server{
-		tokenMaker: tokenMaker,
-		config:     config,
-		store:      store,
+		tokenMaker:     tokenMaker,
+		keyring:        keyring,
+		config:         config,
+		store:          store,
+		revocations:    newRevocationStore(store, maxTokenDuration(config)),
+		throttle:       newLoginThrottle(store, config),
+		mailSender:     mailSender,
+		outbox:         mail.NewOutbox(store, mailSender),
+		hasher:         hasher,
+		passwordPolicy: passwordPolicy,
+		loginNotifier:  loginNotifier,
+		cookies:        cookies,
+		webAuthn:       webAuthn,
 	}
 
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
 		v.RegisterValidation("currency", validCurrency)
+		v.RegisterValidation("scope", validScope)
+		v.RegisterValidation("password", newValidPassword(passwordPolicy))
+		v.RegisterTagNameFunc(jsonFieldName)
 	}
 
 	server.setRouter()
@@ -37,32 +94,162 @@ func NewServer(config util.Config, store db.Store) (*Server, error) {
 	return server, nil
 }
 
+// newTokenMaker creates the maker of config.TokenType. Tokens of config.TokenVerifyTypes
+// are accepted too, so the token type can be switched without logging everybody out.
+// The keyring is nil unless one of the types is asymmetric.
+func newTokenMaker(config util.Config) (token.Maker, *token.Keyring, error) {
+	var keys token.Keys
+	keys.SymmetricKey = config.TokenSymmetricKey
+
+	needKeyring := token.IsAsymmetricType(config.TokenType)
+	for _, tokenType := range config.TokenVerifyTypes {
+		needKeyring = needKeyring || token.IsAsymmetricType(tokenType)
+	}
+	if needKeyring && len(config.TokenPrivateKeyFile) > 0 {
+		keyring, err := token.LoadKeyring(config.TokenPrivateKeyFile, config.TokenPublicKeyFiles)
+		if err != nil {
+			return nil, nil, err
+		}
+		keys.Keyring = keyring
+	}
+
+	claims := token.Claims{
+		Issuer:    config.TokenIssuer,
+		Audience:  config.TokenAudience,
+		ClockSkew: config.TokenClockSkew,
+	}
+
+	primary, err := token.NewMaker(config.TokenType, keys, claims)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	verifiers := make([]token.Maker, 0, len(config.TokenVerifyTypes))
+	for _, tokenType := range config.TokenVerifyTypes {
+		if tokenType == config.TokenType {
+			continue
+		}
+		verifier, err := token.NewMaker(tokenType, keys, claims)
+		if err != nil {
+			return nil, nil, err
+		}
+		verifiers = append(verifiers, verifier)
+	}
+
+	return token.NewMultiMaker(primary, verifiers...), keys.Keyring, nil
+}
+
+// maxTokenDuration is the longest lifetime of any token the server issues,
+// including the clock skew it is accepted for after its expiry
+func maxTokenDuration(config util.Config) time.Duration {
+	if config.RefreshTokenDuration > config.TokenExpiredDuration {
+		return config.RefreshTokenDuration + config.TokenClockSkew
+	}
+	return config.TokenExpiredDuration + config.TokenClockSkew
+}
+
 func (server *Server) setRouter() {
 	router := gin.Default()
 
 	// user
 	router.POST("/user", server.createUser)
 	router.POST("/user/login", server.loginUser)
+	router.POST("/user/login/totp", server.loginUserTOTP)
+	router.POST("/user/login/magic", server.sendMagicLink)
+	router.POST("/user/login/magic/redeem", server.redeemMagicLink)
+	router.POST("/user/password/forgot", server.forgotPassword)
+	router.POST("/user/password/reset", server.resetPassword)
+	router.GET("/user/verify_email", server.verifyEmail)
+	router.POST("/tokens/renew_access", server.renewAccessToken)
+
+	// passkey login, only when a relying party is configured
+	if server.webAuthn != nil {
+		router.POST("/user/login/passkey/begin", server.beginPasskeyLogin)
+		router.POST("/user/login/passkey/finish", server.finishPasskeyLogin)
+	}
+
+	// public verification keys, only in asymmetric mode
+	if server.keyring != nil {
+		router.GET("/.well-known/jwks.json", server.getJWKS)
+		router.GET("/.well-known/paserk.json", server.getPASERK)
+	}
 
 	// add middleware
-	authRouters := router.Group("/").Use(authMiddleware(server.tokenMaker))
+	auth := authMiddleware(server.tokenMaker, server.revocations, server.store, server.cookies)
+	authRouters := router.Group("/").Use(auth)
 
-	authRouters.GET("/user/:username", server.getUser)
+	authRouters.GET("/user/:username", requireScope(util.UsersReadScope), server.getUser)
 
 	// account
-	authRouters.POST("/account", server.createAccount)
-	authRouters.GET("/account/:id", server.getAccount)
-	authRouters.GET("/accounts", server.listAccount)
+	authRouters.POST("/account", requireScope(util.AccountsWriteScope), server.requireVerifiedEmail(), server.createAccount)
+	authRouters.GET("/account/:id", requireScope(util.AccountsReadScope), server.getAccount)
+	authRouters.GET("/accounts", requireScope(util.AccountsReadScope), server.listAccount)
+
+	// money movement is forbidden under impersonation unless IMPERSONATION_CAN_TRANSFER is set
+	transferHandlers := []gin.HandlerFunc{requireScope(util.TransfersWriteScope)}
+	if !server.config.ImpersonationCanTransfer {
+		transferHandlers = append(transferHandlers, forbidImpersonation())
+	}
+	transferHandlers = append(transferHandlers, server.requireVerifiedEmail(), server.createTransfer)
+	authRouters.POST("/transfer", transferHandlers...)
+
+	// api clients only
+	authRouters.POST("/oauth/introspect", requireAPIKey(), requireScope(util.TokensIntrospectScope), server.introspectToken)
+
+	// credentials cannot be managed with an api key
+	bearerRouters := router.Group("/").Use(auth, requireBearer())
 
-	authRouters.POST("/transfer", server.createTransfer)
+	bearerRouters.POST("/user/logout", server.logoutUser)
+	bearerRouters.POST("/user/logout_all", forbidImpersonation(), server.logoutAllUser)
+	bearerRouters.PUT("/user/password", forbidImpersonation(), server.changeUserPassword)
+	bearerRouters.GET("/user/sessions", server.listUserSessions)
+	bearerRouters.DELETE("/user/sessions/:id", forbidImpersonation(), server.revokeUserSession)
+	bearerRouters.GET("/user/logins", server.listLogins)
+
+	bearerRouters.POST("/user/totp/enroll", forbidImpersonation(), server.enrollTOTP)
+	bearerRouters.POST("/user/totp/confirm", forbidImpersonation(), server.confirmTOTP)
+
+	bearerRouters.POST("/api_keys", forbidImpersonation(), server.createAPIKey)
+	bearerRouters.GET("/api_keys", server.listAPIKeys)
+	bearerRouters.DELETE("/api_keys/:id", forbidImpersonation(), server.revokeAPIKey)
+
+	if server.webAuthn != nil {
+		bearerRouters.POST("/user/passkeys/register/begin", forbidImpersonation(), server.beginPasskeyRegistration)
+		bearerRouters.POST("/user/passkeys/register/finish", forbidImpersonation(), server.finishPasskeyRegistration)
+		bearerRouters.GET("/user/passkeys", server.listPasskeys)
+		bearerRouters.DELETE("/user/passkeys/:id", forbidImpersonation(), server.deletePasskey)
+	}
+
+	// admin
+	adminRouters := router.Group("/admin").Use(auth, requireRole(util.AdminRole))
+
+	adminRouters.GET("/users/:username", server.adminGetUser)
+	adminRouters.GET("/users/:username/lockouts", server.adminListLockouts)
+	adminRouters.POST("/users/:username/unlock", server.adminUnlockUser)
+	adminRouters.POST("/users/:username/impersonate", server.adminImpersonateUser)
+	adminRouters.GET("/users/:username/impersonations", server.adminListImpersonations)
+	adminRouters.GET("/accounts", server.adminListAccounts)
+	adminRouters.GET("/accounts/:id", server.adminGetAccount)
+	adminRouters.PUT("/accounts/:id/overdraft_limit", server.adminSetOverdraftLimit)
+	adminRouters.GET("/transfers/:id", server.adminGetTransfer)
+	adminRouters.GET("/stats/tx", server.adminGetTxStats)
 
 	server.router = router
 }
 
 func (server *Server) Start(address string) error {
-	return server.router.Run(address)
-}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := server.revocations.Load(ctx); err != nil {
+		return fmt.Errorf("load revoked tokens failed:%w", err)
+	}
+	go server.revocations.Run(ctx, server.config.RevocationPurgeInterval, server.config.RevocationReloadInterval)
+	go server.throttle.Run(ctx)
+	go server.outbox.Run(ctx, server.config.OutboxInterval)
+	if server.webAuthn != nil {
+		go server.purgeCeremonies(ctx)
+	}
 
-func errResponse(err error) gin.H {
-	return gin.H{"error": err.Error()}
+	return server.router.Run(address)
 }
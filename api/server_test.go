@@ -2,6 +2,8 @@ package api
 
 import (
 	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/mail"
+	"github.com/WanCodeBase/GinModule/token"
 	"github.com/WanCodeBase/GinModule/util"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -9,13 +11,67 @@ import (
 )
 
 func newTestServer(t *testing.T, store db.Store) *Server {
-	config := util.Config{
-		TokenSymmetricKey:    util.RandomString(32),
-		TokenExpiredDuration: time.Minute,
-	}
+	server, err := NewServer(newTestConfig(), store)
+	assert.NoError(t, err)
+
+	return server
+}
 
+// newTokenTypeTestServer is newTestServer issuing tokens of tokenType
+func newTokenTypeTestServer(t *testing.T, store db.Store, tokenType string) *Server {
+	config := newTestConfig()
+	config.TokenType = tokenType
 	server, err := NewServer(config, store)
 	assert.NoError(t, err)
 
 	return server
 }
+
+// issuedLastSecond backdates a token, revocations compare iat in whole seconds
+func issuedLastSecond(p *token.Payload) {
+	p.IssuedAt = p.IssuedAt.Add(-time.Second)
+}
+
+func newTestConfig() util.Config {
+	return util.Config{
+		TokenType:              token.TypePaseto,
+		TokenSymmetricKey:      util.RandomString(32),
+		TokenExpiredDuration:   time.Minute,
+		RefreshTokenDuration:   time.Hour,
+		TOTPIssuer:             "SimpleBank",
+		LoginChallengeDuration: time.Minute,
+		MailSender:             mail.SenderMemory,
+		PasswordResetURL:       "http://localhost/reset_password",
+		PasswordResetDuration:  time.Minute,
+		VerifyEmailURL:         "http://localhost/user/verify_email",
+		VerifyEmailDuration:    time.Hour,
+		MagicLinkURL:           "http://localhost/login/magic",
+		MagicLinkDuration:      time.Minute,
+	}
+}
+
+func TestNewServerTokenType(t *testing.T) {
+	symmetricKey := util.RandomString(32)
+
+	// migrating from paseto to jwt: jwt is issued, paseto still accepted
+	server, err := NewServer(util.Config{
+		TokenType:         token.TypeJWT,
+		TokenVerifyTypes:  []string{token.TypePaseto},
+		TokenSymmetricKey: symmetricKey,
+		MailSender:        mail.SenderMemory,
+	}, nil)
+	assert.NoError(t, err)
+
+	pasetoMaker, err := token.NewPasetoMaker(symmetricKey, token.Claims{})
+	assert.NoError(t, err)
+	pasetoToken, _, err := pasetoMaker.CreateToken("user", util.DepositorRole, time.Minute)
+	assert.NoError(t, err)
+	_, err = server.tokenMaker.VerifyToken(pasetoToken)
+	assert.NoError(t, err)
+
+	_, err = NewServer(util.Config{TokenType: "unknown", TokenSymmetricKey: symmetricKey}, nil)
+	assert.Error(t, err)
+
+	_, err = NewServer(util.Config{TokenType: token.TypeJWTEdDSA, TokenSymmetricKey: symmetricKey}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/token"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type sessionResp struct {
+	ID         uuid.UUID `json:"id"`
+	DeviceName string    `json:"device_name"`
+	UserAgent  string    `json:"user_agent"`
+	ClientIP   string    `json:"client_ip"`
+	IsCurrent  bool      `json:"is_current"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// sessionResponse never includes the refresh token of the session
+func sessionResponse(session db.Session, payload *token.Payload) sessionResp {
+	return sessionResp{
+		ID:         session.ID,
+		DeviceName: session.DeviceName,
+		UserAgent:  session.UserAgent,
+		ClientIP:   session.ClientIp,
+		IsCurrent:  session.ID == payload.SessionID,
+		ExpiresAt:  session.ExpiresAt,
+		CreatedAt:  session.CreatedAt,
+	}
+}
+
+// listUserSessions lists where the authorized user is logged in, newest first
+func (server *Server) listUserSessions(ctx *gin.Context) {
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	sessions, err := server.store.ListUserSessions(ctx, payload.Username)
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	resp := make([]sessionResp, 0, len(sessions))
+	for _, session := range sessions {
+		resp = append(resp, sessionResponse(session, payload))
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type revokeUserSessionReq struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+// revokeUserSession logs a device out: its refresh token cannot be renewed
+// and the access tokens issued for the session are rejected from now on
+func (server *Server) revokeUserSession(ctx *gin.Context) {
+	var req revokeUserSessionReq
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
+		return
+	}
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	session, err := server.store.BlockUserSession(ctx, db.BlockUserSessionParams{
+		ID:       uuid.MustParse(req.ID),
+		Username: payload.Username,
+	})
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	server.revocations.SessionRevoked(session.ID, session.ExpiresAt)
+
+	ctx.JSON(http.StatusOK, sessionResponse(session, payload))
+}
@@ -0,0 +1,236 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/WanCodeBase/GinModule/db/mock"
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/token"
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func randomUserSession(username string) db.Session {
+	return db.Session{
+		ID:           uuid.New(),
+		Username:     username,
+		RefreshToken: util.RandomString(32),
+		DeviceName:   "laptop",
+		UserAgent:    "Mozilla/5.0",
+		ClientIp:     "192.0.2.1",
+		ExpiresAt:    time.Now().Add(time.Hour),
+		CreatedAt:    time.Now(),
+	}
+}
+
+// setSessionAuthorization sets a bearer access token issued for session
+func setSessionAuthorization(t *testing.T, request *http.Request, server *Server, session db.Session) {
+	accessToken, _, err := server.tokenMaker.CreateToken(session.Username, util.DepositorRole, time.Minute,
+		token.WithSessionID(session.ID))
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationHeaderType, accessToken))
+}
+
+func TestListUserSessionsAPI(t *testing.T) {
+	username := util.RandomOwner()
+	current := randomUserSession(username)
+	other := randomUserSession(username)
+
+	testCases := []struct {
+		name      string
+		stubs     func(store *mockdb.MockStore)
+		checkResp func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListUserSessions(gomock.Any(), username).Times(1).Return([]db.Session{other, current}, nil)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), current.RefreshToken)
+
+				var resp []sessionResp
+				err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+				require.NoError(t, err)
+				require.Len(t, resp, 2)
+				require.Equal(t, other.ID, resp[0].ID)
+				require.False(t, resp[0].IsCurrent)
+				require.Equal(t, current.ID, resp[1].ID)
+				require.True(t, resp[1].IsCurrent)
+				require.Equal(t, current.DeviceName, resp[1].DeviceName)
+				require.Equal(t, current.UserAgent, resp[1].UserAgent)
+				require.Equal(t, current.ClientIp, resp[1].ClientIP)
+			},
+		},
+		{
+			name: "InternalError",
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListUserSessions(gomock.Any(), username).Times(1).Return(nil, sql.ErrConnDone)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			c.stubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/user/sessions", nil)
+			require.NoError(t, err)
+			setSessionAuthorization(t, request, server, current)
+
+			server.router.ServeHTTP(recorder, request)
+			c.checkResp(t, recorder)
+		})
+	}
+}
+
+func TestRevokeUserSessionAPI(t *testing.T) {
+	username := util.RandomOwner()
+	current := randomUserSession(username)
+	other := randomUserSession(username)
+
+	testCases := []struct {
+		name      string
+		sessionID string
+		stubs     func(store *mockdb.MockStore)
+		checkResp func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server)
+	}{
+		{
+			name:      "OK",
+			sessionID: other.ID.String(),
+			stubs: func(store *mockdb.MockStore) {
+				blocked := other
+				blocked.IsBlocked = true
+				store.EXPECT().
+					BlockUserSession(gomock.Any(), db.BlockUserSessionParams{ID: other.ID, Username: username}).
+					Times(1).
+					Return(blocked, nil)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				// tokens of the revoked session are rejected, the current session keeps working
+				request, err := http.NewRequest(http.MethodGet, "/user/sessions", nil)
+				require.NoError(t, err)
+				setSessionAuthorization(t, request, server, other)
+				recorder = httptest.NewRecorder()
+				server.router.ServeHTTP(recorder, request)
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:      "NotFound",
+			sessionID: other.ID.String(),
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					BlockUserSession(gomock.Any(), db.BlockUserSessionParams{ID: other.ID, Username: username}).
+					Times(1).
+					Return(db.Session{}, db.ErrNotFound)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				require.Empty(t, server.revocations.sessions)
+			},
+		},
+		{
+			name:      "InvalidID",
+			sessionID: "invalid",
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().BlockUserSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "InternalError",
+			sessionID: other.ID.String(),
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().BlockUserSession(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, sql.ErrConnDone)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			c.stubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodDelete, "/user/sessions/"+c.sessionID, nil)
+			require.NoError(t, err)
+			setSessionAuthorization(t, request, server, current)
+
+			server.router.ServeHTTP(recorder, request)
+			c.checkResp(t, recorder, server)
+		})
+	}
+}
+
+func TestRevokeUserSessionRefreshToken(t *testing.T) {
+	username := util.RandomOwner()
+	current := randomUserSession(username)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+
+	refreshToken, refreshPayload, err := server.tokenMaker.CreateToken(username, util.DepositorRole, time.Hour,
+		token.WithPurpose(refreshTokenPurpose))
+	require.NoError(t, err)
+	other := randomUserSession(username)
+	other.ID = refreshPayload.ID
+	other.RefreshToken = refreshToken
+
+	blocked := other
+	blocked.IsBlocked = true
+	store.EXPECT().
+		BlockUserSession(gomock.Any(), db.BlockUserSessionParams{ID: other.ID, Username: username}).
+		Times(1).
+		Return(blocked, nil)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodDelete, "/user/sessions/"+other.ID.String(), nil)
+	require.NoError(t, err)
+	setSessionAuthorization(t, request, server, current)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.True(t, server.revocations.IsRevoked(refreshPayload))
+
+	// a stolen refresh token of the revoked session does not authorize requests
+	recorder = httptest.NewRecorder()
+	request, err = http.NewRequest(http.MethodGet, "/user/sessions", nil)
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationHeaderType, refreshToken))
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
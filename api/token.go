@@ -0,0 +1,125 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/token"
+	"github.com/gin-gonic/gin"
+)
+
+// refreshTokenPurpose is the purpose claim of refresh tokens, it keeps them from authorizing requests
+const refreshTokenPurpose = "refresh"
+
+type renewAccessTokenReq struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type renewAccessTokenResp struct {
+	AccessToken          string    `json:"access_token,omitempty"`
+	AccessTokenExpiresAt time.Time `json:"access_token_expires_at"`
+}
+
+// renewAccessToken issues a new access token for a valid refresh token.
+// In cookie mode the refresh token may come from its cookie and the new access token is only set as cookie.
+func (server *Server) renewAccessToken(ctx *gin.Context) {
+	var req renewAccessTokenReq
+	var fromCookie bool
+	if server.cookies != nil {
+		req.RefreshToken, fromCookie = server.cookies.RefreshToken(ctx)
+		if fromCookie {
+			if err := server.cookies.CheckCSRF(ctx); err != nil {
+				ctx.JSON(errResponse(http.StatusForbidden, err))
+				return
+			}
+		}
+	}
+	if !fromCookie {
+		if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+			ctx.JSON(errResponse(http.StatusBadRequest, err))
+			return
+		}
+	}
+
+	refreshPayload, err := server.tokenMaker.VerifyToken(req.RefreshToken)
+	if err != nil {
+		ctx.JSON(errResponse(http.StatusUnauthorized, err))
+		return
+	}
+	if refreshPayload.Purpose != refreshTokenPurpose {
+		ctx.JSON(errResponse(http.StatusUnauthorized, token.ErrInvalidToken))
+		return
+	}
+
+	session, err := server.store.GetSession(ctx, refreshPayload.ID)
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	if session.IsBlocked {
+		err := errors.New("blocked session")
+		ctx.JSON(errResponse(http.StatusUnauthorized, err))
+		return
+	}
+
+	if session.Username != refreshPayload.Username {
+		err := errors.New("incorrect session user")
+		ctx.JSON(errResponse(http.StatusUnauthorized, err))
+		return
+	}
+
+	if session.RefreshToken != req.RefreshToken {
+		err := errors.New("mismatched session token")
+		ctx.JSON(errResponse(http.StatusUnauthorized, err))
+		return
+	}
+
+	if session.UserAgent != ctx.Request.UserAgent() {
+		err := errors.New("mismatched session user agent")
+		ctx.JSON(errResponse(http.StatusUnauthorized, err))
+		return
+	}
+
+	if session.ClientIp != ctx.ClientIP() {
+		err := errors.New("mismatched session client ip")
+		ctx.JSON(errResponse(http.StatusUnauthorized, err))
+		return
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		err := errors.New("expired session")
+		ctx.JSON(errResponse(http.StatusUnauthorized, err))
+		return
+	}
+
+	// the role may have changed since the login, the refresh token keeps the old one
+	user, err := server.store.GetUser(ctx, session.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			ctx.JSON(errResponse(http.StatusUnauthorized, err))
+			return
+		}
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	accessToken, accessPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, server.config.TokenExpiredDuration,
+		token.WithSessionID(session.ID))
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	resp := renewAccessTokenResp{
+		AccessToken:          accessToken,
+		AccessTokenExpiresAt: accessPayload.ExpireAt,
+	}
+	if server.cookies != nil {
+		// like at login the token only lives in its http-only cookie
+		server.cookies.SetAccessToken(ctx, accessToken, accessPayload.ExpireAt)
+		resp.AccessToken = ""
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
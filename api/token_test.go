@@ -0,0 +1,244 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/WanCodeBase/GinModule/db/mock"
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/token"
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testUserAgent = "simple-bank-test"
+	testClientIP  = "127.0.0.1"
+)
+
+func TestRenewAccessTokenAPI(t *testing.T) {
+	username := util.RandomOwner()
+
+	testCases := []struct {
+		name       string
+		buildToken func(t *testing.T, tokenMaker token.Maker) (string, db.Session)
+		stubs      func(store *mockdb.MockStore, session db.Session)
+		checkResp  func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildToken: func(t *testing.T, tokenMaker token.Maker) (string, db.Session) {
+				return randomSession(t, tokenMaker, username, time.Minute)
+			},
+			stubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), session.ID).Times(1).Return(session, nil)
+				store.EXPECT().GetUser(gomock.Any(), username).Times(1).Return(db.User{Username: username, Role: util.DepositorRole}, nil)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp renewAccessTokenResp
+				err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+				require.NoError(t, err)
+				require.NotEmpty(t, resp.AccessToken)
+			},
+		},
+		{
+			name: "ExpiredToken",
+			buildToken: func(t *testing.T, tokenMaker token.Maker) (string, db.Session) {
+				return randomSession(t, tokenMaker, username, -time.Minute)
+			},
+			stubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "AccessToken",
+			buildToken: func(t *testing.T, tokenMaker token.Maker) (string, db.Session) {
+				accessToken, payload, err := tokenMaker.CreateToken(username, util.DepositorRole, time.Minute)
+				require.NoError(t, err)
+				return accessToken, db.Session{ID: payload.ID}
+			},
+			stubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			buildToken: func(t *testing.T, tokenMaker token.Maker) (string, db.Session) {
+				return randomSession(t, tokenMaker, username, time.Minute)
+			},
+			stubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), session.ID).Times(1).Return(db.Session{}, db.ErrNotFound)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "UserNotFound",
+			buildToken: func(t *testing.T, tokenMaker token.Maker) (string, db.Session) {
+				return randomSession(t, tokenMaker, username, time.Minute)
+			},
+			stubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), session.ID).Times(1).Return(session, nil)
+				store.EXPECT().GetUser(gomock.Any(), username).Times(1).Return(db.User{}, db.ErrNotFound)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "BlockedSession",
+			buildToken: func(t *testing.T, tokenMaker token.Maker) (string, db.Session) {
+				refreshToken, session := randomSession(t, tokenMaker, username, time.Minute)
+				session.IsBlocked = true
+				return refreshToken, session
+			},
+			stubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), session.ID).Times(1).Return(session, nil)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "MismatchedUserAgent",
+			buildToken: func(t *testing.T, tokenMaker token.Maker) (string, db.Session) {
+				refreshToken, session := randomSession(t, tokenMaker, username, time.Minute)
+				session.UserAgent = "another-agent"
+				return refreshToken, session
+			},
+			stubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), session.ID).Times(1).Return(session, nil)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "MismatchedClientIP",
+			buildToken: func(t *testing.T, tokenMaker token.Maker) (string, db.Session) {
+				refreshToken, session := randomSession(t, tokenMaker, username, time.Minute)
+				session.ClientIp = "10.0.0.1"
+				return refreshToken, session
+			},
+			stubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), session.ID).Times(1).Return(session, nil)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiredSession",
+			buildToken: func(t *testing.T, tokenMaker token.Maker) (string, db.Session) {
+				refreshToken, session := randomSession(t, tokenMaker, username, time.Minute)
+				session.ExpiresAt = time.Now().Add(-time.Minute)
+				return refreshToken, session
+			},
+			stubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), session.ID).Times(1).Return(session, nil)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			refreshToken, session := c.buildToken(t, server.tokenMaker)
+			c.stubs(store, session)
+
+			body, err := json.Marshal(gin.H{"refresh_token": refreshToken})
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, "/tokens/renew_access", bytes.NewReader(body))
+			require.NoError(t, err)
+			setClientInfo(request)
+
+			server.router.ServeHTTP(recorder, request)
+			c.checkResp(t, recorder)
+		})
+	}
+}
+
+func TestRenewAccessTokenRoleChangedAPI(t *testing.T) {
+	username := util.RandomOwner()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+
+	// logged in as admin, demoted before renewing
+	refreshToken, payload, err := server.tokenMaker.CreateToken(username, util.AdminRole, time.Minute, token.WithPurpose(refreshTokenPurpose))
+	require.NoError(t, err)
+	session := db.Session{
+		ID:           payload.ID,
+		Username:     username,
+		RefreshToken: refreshToken,
+		UserAgent:    testUserAgent,
+		ClientIp:     testClientIP,
+		ExpiresAt:    payload.ExpireAt,
+	}
+	store.EXPECT().GetSession(gomock.Any(), session.ID).Times(1).Return(session, nil)
+	store.EXPECT().GetUser(gomock.Any(), username).Times(1).Return(db.User{Username: username, Role: util.DepositorRole}, nil)
+
+	body, err := json.Marshal(gin.H{"refresh_token": refreshToken})
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodPost, "/tokens/renew_access", bytes.NewReader(body))
+	require.NoError(t, err)
+	setClientInfo(request)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var resp renewAccessTokenResp
+	err = json.Unmarshal(recorder.Body.Bytes(), &resp)
+	require.NoError(t, err)
+	accessPayload, err := server.tokenMaker.VerifyToken(resp.AccessToken)
+	require.NoError(t, err)
+	require.Equal(t, util.DepositorRole, accessPayload.Role)
+}
+
+func randomSession(t *testing.T, tokenMaker token.Maker, username string, duration time.Duration) (string, db.Session) {
+	refreshToken, payload, err := tokenMaker.CreateToken(username, util.DepositorRole, duration, token.WithPurpose(refreshTokenPurpose))
+	require.NoError(t, err)
+
+	return refreshToken, db.Session{
+		ID:           payload.ID,
+		Username:     username,
+		RefreshToken: refreshToken,
+		UserAgent:    testUserAgent,
+		ClientIp:     testClientIP,
+		ExpiresAt:    payload.ExpireAt,
+	}
+}
+
+func setClientInfo(request *http.Request) {
+	request.Header.Set("User-Agent", testUserAgent)
+	request.RemoteAddr = testClientIP + ":12345"
+}
@@ -0,0 +1,270 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/token"
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	recoveryCodeCount         = 10
+	recoveryCodeBytes         = 8
+	challengeTokenBytes       = 32
+	maxLoginChallengeAttempts = 5
+)
+
+type enrollTOTPResp struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"`
+}
+
+// enrollTOTP creates a pending totp secret, it is enabled by confirmTOTP
+func (server *Server) enrollTOTP(ctx *gin.Context) {
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	userTOTP, err := server.store.GetUserTOTP(ctx, payload.Username)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	if err == nil && userTOTP.IsConfirmed {
+		err := errors.New("totp is already enabled")
+		ctx.JSON(errResponse(http.StatusForbidden, err))
+		return
+	}
+
+	secret, uri, err := util.GenerateTOTP(server.config.TOTPIssuer, payload.Username)
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	_, err = server.store.UpsertUserTOTP(ctx, db.UpsertUserTOTPParams{
+		Username: payload.Username,
+		Secret:   secret,
+	})
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, enrollTOTPResp{
+		Secret:     secret,
+		OtpauthURI: uri,
+	})
+}
+
+type confirmTOTPReq struct {
+	Code string `json:"code" binding:"required,numeric,len=6"`
+}
+
+type confirmTOTPResp struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// confirmTOTP enables totp once the user proves the authenticator works,
+// the recovery codes are only shown in this response
+func (server *Server) confirmTOTP(ctx *gin.Context) {
+	var req confirmTOTPReq
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
+		return
+	}
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	userTOTP, err := server.store.GetUserTOTP(ctx, payload.Username)
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	if userTOTP.IsConfirmed {
+		err := errors.New("totp is already enabled")
+		ctx.JSON(errResponse(http.StatusForbidden, err))
+		return
+	}
+
+	if _, ok := util.ValidateTOTP(req.Code, userTOTP.Secret, time.Now()); !ok {
+		err := errors.New("invalid totp code")
+		ctx.JSON(errResponse(http.StatusUnauthorized, err))
+		return
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashedCodes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		codes[i], err = util.RandomSecret(recoveryCodeBytes)
+		if err != nil {
+			ctx.JSON(statusErrResponse(err))
+			return
+		}
+		hashedCodes[i] = util.HashSecret(codes[i])
+	}
+
+	_, err = server.store.ConfirmTOTPTx(ctx, db.ConfirmTOTPTxParams{
+		Username:            payload.Username,
+		Secret:              userTOTP.Secret,
+		HashedRecoveryCodes: hashedCodes,
+	})
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, confirmTOTPResp{RecoveryCodes: codes})
+}
+
+type loginChallengeResp struct {
+	TOTPRequired       bool      `json:"totp_required"`
+	ChallengeToken     string    `json:"challenge_token"`
+	ChallengeExpiresAt time.Time `json:"challenge_expires_at"`
+}
+
+// createLoginChallenge stores a single use challenge that loginUserTOTP exchanges for tokens
+func (server *Server) createLoginChallenge(ctx *gin.Context, username string) (loginChallengeResp, error) {
+	challengeToken, err := util.RandomSecret(challengeTokenBytes)
+	if err != nil {
+		return loginChallengeResp{}, err
+	}
+
+	challenge, err := server.store.CreateLoginChallenge(ctx, db.CreateLoginChallengeParams{
+		ID:          uuid.New(),
+		Username:    username,
+		HashedToken: util.HashSecret(challengeToken),
+		ExpiresAt:   time.Now().Add(server.config.LoginChallengeDuration),
+	})
+	if err != nil {
+		return loginChallengeResp{}, err
+	}
+
+	return loginChallengeResp{
+		TOTPRequired:       true,
+		ChallengeToken:     challengeToken,
+		ChallengeExpiresAt: challenge.ExpiresAt,
+	}, nil
+}
+
+type loginUserTOTPReq struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required_without=RecoveryCode,omitempty,numeric,len=6"`
+	RecoveryCode   string `json:"recovery_code" binding:"required_without=Code"`
+	DeviceName     string `json:"device_name" binding:"max=100"`
+}
+
+// loginUserTOTP is the second login step, it accepts a totp code or an unused recovery code
+func (server *Server) loginUserTOTP(ctx *gin.Context) {
+	var req loginUserTOTPReq
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
+		return
+	}
+
+	challenge, err := server.store.GetLoginChallengeByHash(ctx, util.HashSecret(req.ChallengeToken))
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			err := errors.New("invalid challenge token")
+			ctx.JSON(errResponse(http.StatusUnauthorized, err))
+			return
+		}
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	if time.Now().After(challenge.ExpiresAt) || challenge.Attempts >= maxLoginChallengeAttempts {
+		if err := server.store.DeleteLoginChallenge(ctx, challenge.ID); err != nil {
+			ctx.JSON(statusErrResponse(err))
+			return
+		}
+		err := errors.New("challenge has expired")
+		ctx.JSON(errResponse(http.StatusUnauthorized, err))
+		return
+	}
+
+	// second factor guesses share the throttle of passwords, a new challenge does not bring new guesses
+	retryAfter, err := server.throttle.Check(ctx, challenge.Username, ctx.ClientIP())
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	if retryAfter > 0 {
+		server.recordLogin(ctx, challenge.Username, loginOutcomeThrottled)
+		tooManyLoginAttempts(ctx, retryAfter)
+		return
+	}
+
+	if _, err = server.store.IncrementLoginChallengeAttempts(ctx, challenge.ID); err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	if len(req.Code) > 0 {
+		err = server.checkTOTPCode(ctx, challenge.Username, req.Code)
+	} else {
+		_, err = server.store.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{
+			Username:   challenge.Username,
+			HashedCode: util.HashSecret(req.RecoveryCode),
+		})
+		if errors.Is(err, db.ErrNotFound) {
+			err = errInvalidSecondFactor
+		}
+	}
+	if err != nil {
+		if err == errInvalidSecondFactor {
+			server.recordLogin(ctx, challenge.Username, loginOutcomeBadSecondFactor)
+			if !server.failLogin(ctx, challenge.Username) {
+				return
+			}
+			ctx.JSON(errResponse(http.StatusUnauthorized, err))
+			return
+		}
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	if err = server.store.DeleteLoginChallenge(ctx, challenge.ID); err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, challenge.Username)
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	resp, err := server.createLoginResponse(ctx, user, req.DeviceName)
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+var errInvalidSecondFactor = errors.New("invalid totp or recovery code")
+
+// checkTOTPCode validates code and records its time step, so the same code cannot be used twice
+func (server *Server) checkTOTPCode(ctx *gin.Context, username, code string) error {
+	userTOTP, err := server.store.GetUserTOTP(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	step, ok := util.ValidateTOTP(code, userTOTP.Secret, time.Now())
+	if !ok || !userTOTP.IsConfirmed {
+		return errInvalidSecondFactor
+	}
+
+	_, err = server.store.UseTOTPStep(ctx, db.UseTOTPStepParams{
+		Username: username,
+		Step:     step,
+	})
+	if errors.Is(err, db.ErrNotFound) {
+		return errInvalidSecondFactor
+	}
+	return err
+}
@@ -0,0 +1,410 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/WanCodeBase/GinModule/db/mock"
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func randomUserTOTP(t *testing.T, username string, confirmed bool) db.UserTotp {
+	secret, _, err := util.GenerateTOTP("SimpleBank", username)
+	require.NoError(t, err)
+	return db.UserTotp{
+		Username:    username,
+		Secret:      secret,
+		IsConfirmed: confirmed,
+	}
+}
+
+func TestEnrollTOTPAPI(t *testing.T) {
+	username := util.RandomOwner()
+
+	testCases := []struct {
+		name      string
+		stubs     func(store *mockdb.MockStore)
+		checkResp func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), username).Times(1).Return(db.UserTotp{}, db.ErrNotFound)
+				store.EXPECT().UpsertUserTOTP(gomock.Any(), gomock.Any()).Times(1).Return(db.UserTotp{}, nil)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp enrollTOTPResp
+				err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+				require.NoError(t, err)
+				require.NotEmpty(t, resp.Secret)
+				require.Contains(t, resp.OtpauthURI, "otpauth://totp/")
+			},
+		},
+		{
+			name: "AlreadyEnabled",
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), username).Times(1).Return(randomUserTOTP(t, username, true), nil)
+				store.EXPECT().UpsertUserTOTP(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			c.stubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodPost, "/user/totp/enroll", nil)
+			require.NoError(t, err)
+			setAuthorization(t, request, server.tokenMaker, username, util.DepositorRole, time.Minute, authorizationHeaderType)
+
+			server.router.ServeHTTP(recorder, request)
+			c.checkResp(t, recorder)
+		})
+	}
+}
+
+func TestConfirmTOTPAPI(t *testing.T) {
+	username := util.RandomOwner()
+	userTOTP := randomUserTOTP(t, username, false)
+	code, err := util.TOTPCode(userTOTP.Secret, time.Now())
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name      string
+		code      string
+		stubs     func(store *mockdb.MockStore)
+		checkResp func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			code: code,
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), username).Times(1).Return(userTOTP, nil)
+				store.EXPECT().ConfirmTOTPTx(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ConfirmTOTPTxParams) (db.ConfirmTOTPTxResult, error) {
+						require.Equal(t, username, arg.Username)
+						require.Equal(t, userTOTP.Secret, arg.Secret)
+						require.Len(t, arg.HashedRecoveryCodes, recoveryCodeCount)
+						return db.ConfirmTOTPTxResult{}, nil
+					})
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp confirmTOTPResp
+				err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+				require.NoError(t, err)
+				require.Len(t, resp.RecoveryCodes, recoveryCodeCount)
+			},
+		},
+		{
+			name: "InvalidCode",
+			code: "000000",
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), username).Times(1).Return(userTOTP, nil)
+				store.EXPECT().ConfirmTOTPTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "SecretReplaced",
+			code: code,
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), username).Times(1).Return(userTOTP, nil)
+				store.EXPECT().ConfirmTOTPTx(gomock.Any(), gomock.Any()).Times(1).Return(db.ConfirmTOTPTxResult{}, db.ErrConflict)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "NotEnrolled",
+			code: code,
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), username).Times(1).Return(db.UserTotp{}, db.ErrNotFound)
+				store.EXPECT().ConfirmTOTPTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "BadRequest",
+			code: "abc",
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			c.stubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			body, err := json.Marshal(gin.H{"code": c.code})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/user/totp/confirm", bytes.NewReader(body))
+			require.NoError(t, err)
+			setAuthorization(t, request, server.tokenMaker, username, util.DepositorRole, time.Minute, authorizationHeaderType)
+
+			server.router.ServeHTTP(recorder, request)
+			c.checkResp(t, recorder)
+		})
+	}
+}
+
+func TestLoginUserWithTOTPAPI(t *testing.T) {
+	user, password := randomUser()
+	hashedPassword, err := util.HashedPassword(password)
+	require.NoError(t, err)
+	user.HashedPassword = hashedPassword
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	expectLoginAllowed(store)
+	store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+	// the password alone does not forget the failed attempts, the second factor is still to come
+	store.EXPECT().DeleteLoginThrottle(gomock.Any(), gomock.Any()).Times(0)
+	store.EXPECT().GetUserTOTP(gomock.Any(), user.Username).Times(1).Return(randomUserTOTP(t, user.Username, true), nil)
+	store.EXPECT().CreateLoginChallenge(gomock.Any(), gomock.Any()).Times(1).
+		DoAndReturn(func(_ interface{}, arg db.CreateLoginChallengeParams) (db.LoginChallenge, error) {
+			return db.LoginChallenge{
+				ID:          arg.ID,
+				Username:    arg.Username,
+				HashedToken: arg.HashedToken,
+				ExpiresAt:   arg.ExpiresAt,
+			}, nil
+		})
+	// no tokens are issued before the second factor
+	store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+	expectLoginEvent(store, loginOutcomeTOTPRequired)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	body, err := json.Marshal(gin.H{"username": user.Username, "password": password})
+	require.NoError(t, err)
+	request, err := http.NewRequest(http.MethodPost, "/user/login", bytes.NewReader(body))
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var resp loginChallengeResp
+	err = json.Unmarshal(recorder.Body.Bytes(), &resp)
+	require.NoError(t, err)
+	require.True(t, resp.TOTPRequired)
+	require.NotEmpty(t, resp.ChallengeToken)
+	require.NotContains(t, recorder.Body.String(), "access_token")
+}
+
+func TestLoginUserTOTPAPI(t *testing.T) {
+	user, _ := randomUser()
+	userTOTP := randomUserTOTP(t, user.Username, true)
+	code, err := util.TOTPCode(userTOTP.Secret, time.Now())
+	require.NoError(t, err)
+
+	challengeToken := util.RandomString(32)
+	challenge := db.LoginChallenge{
+		ID:          uuid.New(),
+		Username:    user.Username,
+		HashedToken: util.HashSecret(challengeToken),
+		ExpiresAt:   time.Now().Add(time.Minute),
+	}
+
+	issueTokens := func(store *mockdb.MockStore) {
+		store.EXPECT().DeleteLoginChallenge(gomock.Any(), challenge.ID).Times(1).Return(nil)
+		store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+		store.EXPECT().DeleteLoginThrottle(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+		store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, nil)
+		expectLoginSuccess(store)
+	}
+
+	testCases := []struct {
+		name      string
+		body      gin.H
+		stubs     func(store *mockdb.MockStore)
+		checkResp func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"challenge_token": challengeToken, "code": code},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetLoginChallengeByHash(gomock.Any(), challenge.HashedToken).Times(1).Return(challenge, nil)
+				expectLoginAllowed(store)
+				store.EXPECT().IncrementLoginChallengeAttempts(gomock.Any(), challenge.ID).Times(1).Return(challenge, nil)
+				store.EXPECT().GetUserTOTP(gomock.Any(), user.Username).Times(1).Return(userTOTP, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(userTOTP, nil)
+				issueTokens(store)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp loginUserResp
+				err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+				require.NoError(t, err)
+				require.NotEmpty(t, resp.AccessToken)
+			},
+		},
+		{
+			name: "ReplayedCode",
+			body: gin.H{"challenge_token": challengeToken, "code": code},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetLoginChallengeByHash(gomock.Any(), challenge.HashedToken).Times(1).Return(challenge, nil)
+				expectLoginAllowed(store)
+				store.EXPECT().IncrementLoginChallengeAttempts(gomock.Any(), challenge.ID).Times(1).Return(challenge, nil)
+				store.EXPECT().GetUserTOTP(gomock.Any(), user.Username).Times(1).Return(userTOTP, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(db.UserTotp{}, db.ErrNotFound)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+				expectLoginEvent(store, loginOutcomeBadSecondFactor)
+				expectLoginFailure(store, 1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "RecoveryCode",
+			body: gin.H{"challenge_token": challengeToken, "recovery_code": "0123456789abcdef"},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetLoginChallengeByHash(gomock.Any(), challenge.HashedToken).Times(1).Return(challenge, nil)
+				expectLoginAllowed(store)
+				store.EXPECT().IncrementLoginChallengeAttempts(gomock.Any(), challenge.ID).Times(1).Return(challenge, nil)
+				store.EXPECT().UseRecoveryCode(gomock.Any(), db.UseRecoveryCodeParams{
+					Username:   user.Username,
+					HashedCode: util.HashSecret("0123456789abcdef"),
+				}).Times(1).Return(db.RecoveryCode{}, nil)
+				issueTokens(store)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "UsedRecoveryCode",
+			body: gin.H{"challenge_token": challengeToken, "recovery_code": "0123456789abcdef"},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetLoginChallengeByHash(gomock.Any(), challenge.HashedToken).Times(1).Return(challenge, nil)
+				expectLoginAllowed(store)
+				store.EXPECT().IncrementLoginChallengeAttempts(gomock.Any(), challenge.ID).Times(1).Return(challenge, nil)
+				store.EXPECT().UseRecoveryCode(gomock.Any(), gomock.Any()).Times(1).Return(db.RecoveryCode{}, db.ErrNotFound)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+				expectLoginEvent(store, loginOutcomeBadSecondFactor)
+				expectLoginFailure(store, 1)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "Throttled",
+			body: gin.H{"challenge_token": challengeToken, "code": code},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetLoginChallengeByHash(gomock.Any(), challenge.HashedToken).Times(1).Return(challenge, nil)
+				store.EXPECT().GetLoginThrottle(gomock.Any(), db.GetLoginThrottleParams{Kind: db.LoginThrottleUsername, Key: user.Username}).
+					Times(1).Return(db.LoginThrottle{BlockedUntil: time.Now().Add(10 * time.Minute)}, nil)
+				store.EXPECT().GetLoginThrottle(gomock.Any(), gomock.Any()).Times(1).Return(db.LoginThrottle{}, db.ErrNotFound)
+				store.EXPECT().IncrementLoginChallengeAttempts(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+				expectLoginEvent(store, loginOutcomeThrottled)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.NotEmpty(t, recorder.Header().Get("Retry-After"))
+			},
+		},
+		{
+			name: "InvalidChallenge",
+			body: gin.H{"challenge_token": "unknown", "code": code},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetLoginChallengeByHash(gomock.Any(), gomock.Any()).Times(1).Return(db.LoginChallenge{}, db.ErrNotFound)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "TooManyAttempts",
+			body: gin.H{"challenge_token": challengeToken, "code": code},
+			stubs: func(store *mockdb.MockStore) {
+				exhausted := challenge
+				exhausted.Attempts = maxLoginChallengeAttempts
+				store.EXPECT().GetLoginChallengeByHash(gomock.Any(), challenge.HashedToken).Times(1).Return(exhausted, nil)
+				store.EXPECT().DeleteLoginChallenge(gomock.Any(), challenge.ID).Times(1).Return(nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "NoSecondFactor",
+			body: gin.H{"challenge_token": challengeToken},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetLoginChallengeByHash(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			c.stubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			body, err := json.Marshal(c.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/user/login/totp", bytes.NewReader(body))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			c.checkResp(t, recorder)
+		})
+	}
+}
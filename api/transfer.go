@@ -1,7 +1,6 @@
 package api
 
 import (
-	"database/sql"
 	"errors"
 	"fmt"
 	db "github.com/WanCodeBase/GinModule/db/sqlc"
@@ -20,7 +19,7 @@ type transferReq struct {
 func (server *Server) createTransfer(ctx *gin.Context) {
 	var req transferReq
 	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
 		return
 	}
 
@@ -31,7 +30,7 @@ func (server *Server) createTransfer(ctx *gin.Context) {
 	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 	if fromAccount.Owner != payload.Username {
 		err := errors.New("account owner is not match")
-		ctx.JSON(http.StatusUnauthorized, errResponse(err))
+		ctx.JSON(errResponse(http.StatusUnauthorized, err))
 		return
 	}
 
@@ -45,11 +44,7 @@ func (server *Server) createTransfer(ctx *gin.Context) {
 		Amount:        req.Amount,
 	})
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errResponse(err))
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		ctx.JSON(statusErrResponse(err))
 		return
 	}
 
@@ -59,17 +54,13 @@ func (server *Server) createTransfer(ctx *gin.Context) {
 func (server *Server) validateCurrency(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
 	account, err := server.store.GetAccount(ctx, accountID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errResponse(err))
-			return account, false
-		}
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		ctx.JSON(statusErrResponse(err))
 		return account, false
 	}
 
 	if account.Currency != currency {
-		ctx.JSON(http.StatusBadRequest,
-			fmt.Sprintf("Currency cannot match: %s vs %s", account.Currency, currency))
+		err := fmt.Errorf("account %d is in %s, not %s: %w", accountID, account.Currency, currency, db.ErrCurrencyMismatch)
+		ctx.JSON(statusErrResponse(err))
 		return account, false
 	}
 	return account, true
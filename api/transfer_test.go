@@ -0,0 +1,176 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/WanCodeBase/GinModule/db/mock"
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferAPI(t *testing.T) {
+	user, _ := randomUser()
+	fromAccount := randomAccount(user.Username)
+	toAccount := randomAccount(util.RandomOwner())
+	toAccount.ID = fromAccount.ID + 1
+	toAccount.Currency = fromAccount.Currency
+	amount := int64(10)
+	otherCurrency := util.USD
+	if fromAccount.Currency == util.USD {
+		otherCurrency = util.EUR
+	}
+
+	testCases := []struct {
+		name      string
+		body      gin.H
+		stubs     func(store *mockdb.MockStore)
+		checkResp func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          amount,
+				"currency":        fromAccount.Currency,
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), fromAccount.ID).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), toAccount.ID).Times(1).Return(toAccount, nil)
+				store.EXPECT().TransferTx(gomock.Any(), db.TransferTxParams{
+					FromAccountID: fromAccount.ID,
+					ToAccountID:   toAccount.ID,
+					Amount:        amount,
+				}).Times(1).Return(db.TransferTxResult{}, nil)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "InsufficientFunds",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          amount,
+				"currency":        fromAccount.Currency,
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), fromAccount.ID).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), toAccount.ID).Times(1).Return(toAccount, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.TransferTxResult{}, db.ErrInsufficientFunds)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				requireErrCode(t, recorder, errCodeInsufficientFunds)
+			},
+		},
+		{
+			name: "ToAccountNotFound",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          amount,
+				"currency":        fromAccount.Currency,
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), fromAccount.ID).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), toAccount.ID).Times(1).Return(db.Account{}, db.ErrNotFound)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "CurrencyMismatch",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          amount,
+				"currency":        otherCurrency,
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), fromAccount.ID).Times(1).Return(fromAccount, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				requireErrCode(t, recorder, errCodeCurrencyMismatch)
+			},
+		},
+		{
+			name: "TxError",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          amount,
+				"currency":        fromAccount.Currency,
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), fromAccount.ID).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), toAccount.ID).Times(1).Return(toAccount, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.TransferTxResult{}, sql.ErrConnDone)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "NegativeAmount",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          -amount,
+				"currency":        fromAccount.Currency,
+			},
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			c.stubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			body, err := json.Marshal(c.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/transfer", bytes.NewReader(body))
+			require.NoError(t, err)
+			setAuthorization(t, request, server.tokenMaker, user.Username, util.DepositorRole, time.Minute, authorizationHeaderType)
+
+			server.router.ServeHTTP(recorder, request)
+			c.checkResp(t, recorder)
+		})
+	}
+}
+
+func requireErrCode(t *testing.T, recorder *httptest.ResponseRecorder, code string) {
+	var body gin.H
+	err := json.Unmarshal(recorder.Body.Bytes(), &body)
+	require.NoError(t, err)
+	require.Equal(t, code, body["code"])
+}
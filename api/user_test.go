@@ -2,6 +2,8 @@ package api
 
 import (
 	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,9 +11,11 @@ import (
 	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
 
 	mockdb "github.com/WanCodeBase/GinModule/db/mock"
 	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/token"
 	"github.com/WanCodeBase/GinModule/util"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
@@ -28,32 +32,37 @@ func randomUser() (db.User, string) {
 	return user, util.RandomString(8)
 }
 
-type eqCreateUserParamsMatcher struct {
+type eqCreateUserTxParamsMatcher struct {
 	arg      db.CreateUserParams
 	password string
 }
 
 // 使用bcrypt哈希密码时，一样的字符串每一次会生产出不同的哈希值 （random salt)
 // 实现自定义的Matches来处理密码
-func (e eqCreateUserParamsMatcher) Matches(x interface{}) bool {
-	arg, ok := x.(db.CreateUserParams)
+func (e eqCreateUserTxParamsMatcher) Matches(x interface{}) bool {
+	txArg, ok := x.(db.CreateUserTxParams)
 	if !ok {
 		return false
 	}
+	arg := txArg.CreateUserParams
 	err := util.CheckPassword(e.password, arg.HashedPassword)
 	if err != nil {
 		return false
 	}
+	// the verification email carries the code whose hash is stored
+	if txArg.VerifyEmail.ToAddress != e.arg.Email || len(txArg.HashedVerifyCode) == 0 {
+		return false
+	}
 	e.arg.HashedPassword = arg.HashedPassword
 	return reflect.DeepEqual(e.arg, arg)
 }
 
-func (e eqCreateUserParamsMatcher) String() string {
+func (e eqCreateUserTxParamsMatcher) String() string {
 	return "is anything"
 }
 
-func EqCreateUserParams(params db.CreateUserParams, password string) gomock.Matcher {
-	return eqCreateUserParamsMatcher{
+func EqCreateUserTxParams(params db.CreateUserParams, password string) gomock.Matcher {
+	return eqCreateUserTxParamsMatcher{
 		arg:      params,
 		password: password,
 	}
@@ -82,13 +91,61 @@ func TestCreateUserAPI(t *testing.T) {
 					FullName: user.FullName,
 					Email:    user.Email,
 				}
-				store.EXPECT().CreateUser(gomock.Any(), EqCreateUserParams(arg, password)).Times(1).Return(user, nil)
+				store.EXPECT().CreateUserTx(gomock.Any(), EqCreateUserTxParams(arg, password)).Times(1).Return(db.CreateUserTxResult{User: user}, nil)
 			},
 			checkResp: func(recoder *httptest.ResponseRecorder) {
 				assert.Equal(t, http.StatusOK, recoder.Code)
 				requireBodyMatchUser(t, recoder.Body, user)
 			},
 		},
+		{
+			name: "ShortPassword",
+			body: gin.H{
+				"username":  user.Username,
+				"password":  "abc12",
+				"full_name": user.FullName,
+				"email":     user.Email,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recoder.Code)
+				requireFieldError(t, recoder.Body, "password", "must be at least 8 characters long")
+			},
+		},
+		{
+			name: "PasswordContainsUsername",
+			body: gin.H{
+				"username":  user.Username,
+				"password":  "X" + user.Username + "42",
+				"full_name": user.FullName,
+				"email":     user.Email,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recoder.Code)
+				requireFieldError(t, recoder.Body, "password", "must not contain the username or email")
+			},
+		},
+		{
+			name: "InvalidEmail",
+			body: gin.H{
+				"username":  user.Username,
+				"password":  password,
+				"full_name": user.FullName,
+				"email":     "invalid-email",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recoder.Code)
+				requireFieldError(t, recoder.Body, "email", "must be a valid email address")
+			},
+		},
 	}
 
 	for _, c := range testCases {
@@ -115,6 +172,395 @@ func TestCreateUserAPI(t *testing.T) {
 	}
 }
 
+func TestLoginUserAPI(t *testing.T) {
+	user, password := randomUser()
+	hashedPassword, err := util.HashedPassword(password)
+	require.NoError(t, err)
+	user.HashedPassword = hashedPassword
+
+	testCases := []struct {
+		name       string
+		body       gin.H
+		buildStubs func(store *mockdb.MockStore)
+		checkResp  func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"username":    user.Username,
+				"password":    password,
+				"device_name": "laptop",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+				store.EXPECT().DeleteLoginThrottle(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().GetUserTOTP(gomock.Any(), user.Username).Times(1).Return(db.UserTotp{}, db.ErrNotFound)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateSessionParams) (db.Session, error) {
+						require.Equal(t, "laptop", arg.DeviceName)
+						return db.Session{
+							ID:           arg.ID,
+							Username:     arg.Username,
+							RefreshToken: arg.RefreshToken,
+							UserAgent:    arg.UserAgent,
+							ClientIp:     arg.ClientIp,
+							ExpiresAt:    arg.ExpiresAt,
+						}, nil
+					})
+				expectLoginSuccess(store)
+			},
+			checkResp: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recoder.Code)
+
+				var resp loginUserResp
+				err := json.Unmarshal(recoder.Body.Bytes(), &resp)
+				require.NoError(t, err)
+				require.NotEmpty(t, resp.AccessToken)
+				require.NotEmpty(t, resp.RefreshToken)
+				require.NotZero(t, resp.SessionID)
+			},
+		},
+		{
+			name: "UserNotFound",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(db.User{}, db.ErrNotFound)
+				expectLoginFailure(store, 1)
+				expectLoginEvent(store, loginOutcomeUnknownUser)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recoder.Code)
+			},
+		},
+		{
+			name: "IncorrectPassword",
+			body: gin.H{
+				"username": user.Username,
+				"password": "incorrect",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+				expectLoginFailure(store, 1)
+				expectLoginEvent(store, loginOutcomeBadPassword)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recoder.Code)
+			},
+		},
+		{
+			name: "CreateSessionError",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+				store.EXPECT().DeleteLoginThrottle(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().GetUserTOTP(gomock.Any(), user.Username).Times(1).Return(db.UserTotp{}, db.ErrNotFound)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, sql.ErrConnDone)
+			},
+			checkResp: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recoder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			c.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recoder := httptest.NewRecorder()
+
+			body, err := json.Marshal(c.body)
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/user/login", bytes.NewReader(body))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recoder, req)
+			c.checkResp(recoder)
+		})
+	}
+}
+
+func TestLoginUserRehashAPI(t *testing.T) {
+	user, password := randomUser()
+	hashedPassword, err := util.HashedPassword(password)
+	require.NoError(t, err)
+	user.HashedPassword = hashedPassword
+
+	hasher := util.Argon2idHasher{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	argon2idHash, err := hasher.Hash(password)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name           string
+		hashedPassword string
+		buildStubs     func(store *mockdb.MockStore)
+	}{
+		{
+			name:           "Rehash",
+			hashedPassword: hashedPassword,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().RehashUserPassword(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.RehashUserPasswordParams) (int64, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, hashedPassword, arg.OldHashedPassword)
+						require.False(t, hasher.NeedsRehash(arg.NewHashedPassword))
+						require.NoError(t, util.CheckPassword(password, arg.NewHashedPassword))
+						return 1, nil
+					})
+			},
+		},
+		{
+			name:           "RehashError",
+			hashedPassword: hashedPassword,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().RehashUserPassword(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), sql.ErrConnDone)
+			},
+		},
+		{
+			name:           "UpToDate",
+			hashedPassword: argon2idHash,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().RehashUserPassword(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			loginUser := user
+			loginUser.HashedPassword = c.hashedPassword
+
+			store := mockdb.NewMockStore(ctrl)
+			expectLoginAllowed(store)
+			store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(loginUser, nil)
+			store.EXPECT().DeleteLoginThrottle(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			store.EXPECT().GetUserTOTP(gomock.Any(), user.Username).Times(1).Return(db.UserTotp{}, db.ErrNotFound)
+			store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, nil)
+			expectLoginSuccess(store)
+			c.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.hasher = hasher
+			recoder := httptest.NewRecorder()
+
+			body, err := json.Marshal(gin.H{"username": user.Username, "password": password})
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/user/login", bytes.NewReader(body))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recoder, req)
+			// a failed rehash never fails the login
+			require.Equal(t, http.StatusOK, recoder.Code)
+		})
+	}
+}
+
+func TestChangeUserPasswordAPI(t *testing.T) {
+	user, password := randomUser()
+	hashedPassword, err := util.HashedPassword(password)
+	require.NoError(t, err)
+	user.HashedPassword = hashedPassword
+	newPassword := util.RandomString(8)
+
+	testCases := []struct {
+		name       string
+		body       gin.H
+		buildStubs func(store *mockdb.MockStore)
+		checkResp  func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"current_password": password,
+				"new_password":     newPassword,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.ChangePasswordTxParams) (db.ChangePasswordTxResult, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.NoError(t, util.CheckPassword(newPassword, arg.HashedPassword))
+						require.WithinDuration(t, time.Now(), arg.PasswordChangedAt, time.Second)
+
+						changed := user
+						changed.HashedPassword = arg.HashedPassword
+						changed.PasswordChangedAt = arg.PasswordChangedAt
+						return db.ChangePasswordTxResult{User: changed}, nil
+					})
+			},
+			checkResp: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recoder.Code)
+			},
+		},
+		{
+			name: "IncorrectPassword",
+			body: gin.H{
+				"current_password": "incorrect",
+				"new_password":     newPassword,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+				expectLoginFailure(store, 1)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recoder.Code)
+				require.NotEmpty(t, recoder.Header().Get("Retry-After"))
+			},
+		},
+		{
+			name: "Throttled",
+			body: gin.H{
+				"current_password": password,
+				"new_password":     newPassword,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetLoginThrottle(gomock.Any(), db.GetLoginThrottleParams{Kind: db.LoginThrottleUsername, Key: user.Username}).
+					Times(1).Return(db.LoginThrottle{BlockedUntil: time.Now().Add(10 * time.Minute)}, nil)
+				store.EXPECT().GetLoginThrottle(gomock.Any(), gomock.Any()).Times(1).Return(db.LoginThrottle{}, db.ErrNotFound)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recoder.Code)
+				require.NotEmpty(t, recoder.Header().Get("Retry-After"))
+			},
+		},
+		{
+			name: "ShortPassword",
+			body: gin.H{
+				"current_password": password,
+				"new_password":     "123",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recoder.Code)
+			},
+		},
+		{
+			name: "PasswordContainsEmail",
+			body: gin.H{
+				"current_password": password,
+				"new_password":     user.Email + "1",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recoder.Code)
+				requireFieldError(t, recoder.Body, "new_password", "must not contain the username or email")
+			},
+		},
+		{
+			name: "TxError",
+			body: gin.H{
+				"current_password": password,
+				"new_password":     newPassword,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(1).Return(db.ChangePasswordTxResult{}, sql.ErrConnDone)
+			},
+			checkResp: func(recoder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recoder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			c.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recoder := httptest.NewRecorder()
+
+			body, err := json.Marshal(c.body)
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPut, "/user/password", bytes.NewReader(body))
+			require.NoError(t, err)
+			setAuthorization(t, req, server.tokenMaker, user.Username, util.DepositorRole, time.Minute, authorizationHeaderType)
+
+			server.router.ServeHTTP(recoder, req)
+			c.checkResp(recoder)
+		})
+	}
+}
+
+func TestChangeUserPasswordRevokesOldTokens(t *testing.T) {
+	for _, tokenType := range []string{token.TypePaseto, token.TypeJWT} {
+		t.Run(tokenType, func(t *testing.T) {
+			username := util.RandomOwner()
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTokenTypeTestServer(t, store, tokenType)
+
+			oldToken, _, err := server.tokenMaker.CreateToken(username, util.DepositorRole, time.Minute, issuedLastSecond)
+			require.NoError(t, err)
+
+			server.revocations.PasswordChanged(username, time.Now())
+
+			// issued within the same second as the change, like the login that follows it
+			newToken, _, err := server.tokenMaker.CreateToken(username, util.DepositorRole, time.Minute)
+			require.NoError(t, err)
+
+			store.EXPECT().GetUser(gomock.Any(), username).Times(1).Return(db.User{Username: username}, nil)
+
+			for _, c := range []struct {
+				token string
+				code  int
+			}{
+				{token: oldToken, code: http.StatusUnauthorized},
+				{token: newToken, code: http.StatusOK},
+			} {
+				recoder := httptest.NewRecorder()
+				req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/user/%s", username), nil)
+				require.NoError(t, err)
+				req.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationHeaderType, c.token))
+
+				server.router.ServeHTTP(recoder, req)
+				require.Equal(t, c.code, recoder.Code)
+			}
+		})
+	}
+}
+
 func requireBodyMatchUser(t *testing.T, body *bytes.Buffer, user db.User) {
 	data, err := io.ReadAll(body)
 	require.NoError(t, err)
@@ -124,3 +570,14 @@ func requireBodyMatchUser(t *testing.T, body *bytes.Buffer, user db.User) {
 	require.NoError(t, err)
 	require.Equal(t, user, gotUser)
 }
+
+func requireFieldError(t *testing.T, body *bytes.Buffer, field, message string) {
+	var resp struct {
+		Error  string            `json:"error"`
+		Fields map[string]string `json:"fields"`
+	}
+	err := json.Unmarshal(body.Bytes(), &resp)
+	require.NoError(t, err)
+	require.Contains(t, resp.Fields[field], message)
+	require.Contains(t, resp.Error, field)
+}
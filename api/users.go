@@ -1,21 +1,21 @@
 package api
 
 import (
-	"database/sql"
 	"errors"
 	"github.com/WanCodeBase/GinModule/token"
+	"log"
 	"net/http"
 	"time"
 
 	db "github.com/WanCodeBase/GinModule/db/sqlc"
 	"github.com/WanCodeBase/GinModule/util"
 	"github.com/gin-gonic/gin"
-	"github.com/lib/pq"
+	"github.com/google/uuid"
 )
 
 type createUserReq struct {
 	Username string `json:"username" binding:"required,alphanum"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required,password=Username Email"`
 	FullName string `json:"full_name" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
 }
@@ -24,6 +24,8 @@ type userResp struct {
 	Username          string    `json:"username"`
 	FullName          string    `json:"full_name"`
 	Email             string    `json:"email"`
+	Role              string    `json:"role"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
 }
@@ -33,6 +35,8 @@ func userResponse(user db.User) userResp {
 		Username:          user.Username,
 		FullName:          user.FullName,
 		Email:             user.Email,
+		Role:              user.Role,
+		IsEmailVerified:   user.IsEmailVerified,
 		PasswordChangedAt: user.PasswordChangedAt,
 		CreatedAt:         user.CreatedAt,
 	}
@@ -42,32 +46,41 @@ func userResponse(user db.User) userResp {
 func (server *Server) createUser(ctx *gin.Context) {
 	var req createUserReq
 	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		ctx.JSON(server.validationErrResponse(err))
 		return
 	}
-	hashedPassword, err := util.HashedPassword(req.Password)
+	hashedPassword, err := server.hasher.Hash(req.Password)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		ctx.JSON(statusErrResponse(err))
 		return
 	}
-	user, err := server.store.CreateUser(ctx, db.CreateUserParams{
-		Username:       req.Username,
-		HashedPassword: hashedPassword,
-		FullName:       req.FullName,
-		Email:          req.Email,
+	verifyCode, err := util.RandomSecret(verifyEmailCodeBytes)
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	expiresAt := time.Now().Add(server.config.VerifyEmailDuration)
+
+	result, err := server.store.CreateUserTx(ctx, db.CreateUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username:       req.Username,
+			HashedPassword: hashedPassword,
+			FullName:       req.FullName,
+			Email:          req.Email,
+		},
+		HashedVerifyCode:    util.HashSecret(verifyCode),
+		VerifyCodeExpiresAt: expiresAt,
+		VerifyEmail: db.CreateOutboxEmailParams{
+			ToAddress: req.Email,
+			Subject:   "Verify your email",
+			Body:      server.verifyEmailBody(req.FullName, verifyCode, expiresAt),
+		},
 	})
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch pqErr.Code.Name() {
-			case "unique_violation":
-				ctx.JSON(http.StatusForbidden, errResponse(err))
-				return
-			}
-		}
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		ctx.JSON(statusErrResponse(err))
 		return
 	}
-	resp := userResponse(user)
+	resp := userResponse(result.User)
 	ctx.JSON(http.StatusOK, resp)
 }
 
@@ -78,22 +91,18 @@ type getUserReq struct {
 func (server *Server) getUser(ctx *gin.Context) {
 	var req getUserReq
 	if err := ctx.ShouldBindUri(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
 		return
 	}
 	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 	if payload.Username != req.Username {
 		err := errors.New("account owner is not match")
-		ctx.JSON(http.StatusUnauthorized, errResponse(err))
+		ctx.JSON(errResponse(http.StatusUnauthorized, err))
 		return
 	}
 	user, err := server.store.GetUser(ctx, req.Username)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errResponse(err))
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		ctx.JSON(statusErrResponse(err))
 		return
 	}
 	resp := userResponse(user)
@@ -101,46 +110,337 @@ func (server *Server) getUser(ctx *gin.Context) {
 }
 
 type loginUserReq struct {
-	Username string `json:"username" binding:"required,alphanum"`
-	Password string `json:"password" binding:"required,min=6"`
+	Username   string `json:"username" binding:"required,alphanum"`
+	Password   string `json:"password" binding:"required,min=6"`
+	DeviceName string `json:"device_name" binding:"max=100"`
 }
 
 type loginUserResp struct {
-	AccessToken string   `json:"access_token"`
-	User        userResp `json:"user"`
+	SessionID             uuid.UUID `json:"session_id"`
+	AccessToken           string    `json:"access_token,omitempty"`
+	AccessTokenExpiresAt  time.Time `json:"access_token_expires_at"`
+	RefreshToken          string    `json:"refresh_token,omitempty"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+	User                  userResp  `json:"user"`
 }
 
 func (server *Server) loginUser(ctx *gin.Context) {
 	var req loginUserReq
 	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
+		return
+	}
+
+	retryAfter, err := server.throttle.Check(ctx, req.Username, ctx.ClientIP())
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	if retryAfter > 0 {
+		server.recordLogin(ctx, req.Username, loginOutcomeThrottled)
+		tooManyLoginAttempts(ctx, retryAfter)
 		return
 	}
 
 	user, err := server.store.GetUser(ctx, req.Username)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errResponse(err))
+		if errors.Is(err, db.ErrNotFound) {
+			server.recordLogin(ctx, req.Username, loginOutcomeUnknownUser)
+			// unknown usernames count too, guessing them must not be cheaper
+			if !server.failLogin(ctx, req.Username) {
+				return
+			}
+			ctx.JSON(errResponse(http.StatusNotFound, err))
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		ctx.JSON(statusErrResponse(err))
 		return
 	}
 
 	err = util.CheckPassword(req.Password, user.HashedPassword)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, errResponse(err))
+		server.recordLogin(ctx, user.Username, loginOutcomeBadPassword)
+		if !server.failLogin(ctx, req.Username) {
+			return
+		}
+		ctx.JSON(errResponse(http.StatusUnauthorized, err))
+		return
+	}
+	server.rehashPassword(ctx, user, req.Password)
+
+	server.finishFirstFactorLogin(ctx, user, req.DeviceName)
+}
+
+// finishFirstFactorLogin responds to a login that proved the first factor, a password or a magic link.
+// Users with two-factor authentication get a challenge instead of tokens.
+func (server *Server) finishFirstFactorLogin(ctx *gin.Context, user db.User, deviceName string) {
+	userTOTP, err := server.store.GetUserTOTP(ctx, user.Username)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	if err == nil && userTOTP.IsConfirmed {
+		resp, err := server.createLoginChallenge(ctx, user.Username)
+		if err != nil {
+			ctx.JSON(statusErrResponse(err))
+			return
+		}
+		server.recordLogin(ctx, user.Username, loginOutcomeTOTPRequired)
+		ctx.JSON(http.StatusOK, resp)
 		return
 	}
 
-	token, err := server.tokenMaker.CreateToken(user.Username, server.config.TokenExpiredDuration)
+	resp, err := server.createLoginResponse(ctx, user, deviceName)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		ctx.JSON(statusErrResponse(err))
 		return
 	}
+	ctx.JSON(http.StatusOK, resp)
+}
 
-	ctx.JSON(http.StatusOK, loginUserResp{
-		AccessToken: token,
-		User:        userResponse(user),
+// rehashPassword upgrades the stored hash to the current hashing policy after a successful check.
+// It is best effort, the login does not fail if the hash cannot be updated.
+func (server *Server) rehashPassword(ctx *gin.Context, user db.User, password string) {
+	if !server.hasher.NeedsRehash(user.HashedPassword) {
+		return
+	}
+	hashedPassword, err := server.hasher.Hash(password)
+	if err != nil {
+		log.Println("rehash password failed:", err)
+		return
+	}
+	// the condition on the old hash keeps a concurrent password change
+	_, err = server.store.RehashUserPassword(ctx, db.RehashUserPasswordParams{
+		NewHashedPassword: hashedPassword,
+		Username:          user.Username,
+		OldHashedPassword: user.HashedPassword,
 	})
+	if err != nil {
+		log.Println("rehash password failed:", err)
+	}
+}
+
+// failLogin records a failed login and sets Retry-After when the next attempt has to wait.
+// It responds itself and returns false if the attempt could not be recorded.
+func (server *Server) failLogin(ctx *gin.Context, username string) bool {
+	retryAfter, err := server.throttle.Fail(ctx, username, ctx.ClientIP())
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return false
+	}
+	if retryAfter > 0 {
+		setRetryAfter(ctx, retryAfter)
+	}
+	return true
+}
+
+// createLoginResponse issues a refresh token backed by a new session of the device
+// and an access token tied to that session. Every successful login ends here and is audited.
+// Only here, after the second factor, the failed attempts of the user are forgotten,
+// a password alone must not reset the throttle of the second factor.
+func (server *Server) createLoginResponse(ctx *gin.Context, user db.User, deviceName string) (loginUserResp, error) {
+	if err := server.throttle.Succeed(ctx, user.Username); err != nil {
+		return loginUserResp{}, err
+	}
+
+	refreshToken, refreshPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, server.config.RefreshTokenDuration,
+		token.WithPurpose(refreshTokenPurpose))
+	if err != nil {
+		return loginUserResp{}, err
+	}
+
+	accessToken, accessPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, server.config.TokenExpiredDuration,
+		token.WithSessionID(refreshPayload.ID))
+	if err != nil {
+		return loginUserResp{}, err
+	}
+
+	session, err := server.store.CreateSession(ctx, db.CreateSessionParams{
+		ID:           refreshPayload.ID,
+		Username:     user.Username,
+		RefreshToken: refreshToken,
+		DeviceName:   deviceName,
+		UserAgent:    ctx.Request.UserAgent(),
+		ClientIp:     ctx.ClientIP(),
+		IsBlocked:    false,
+		ExpiresAt:    refreshPayload.ExpireAt,
+	})
+	if err != nil {
+		return loginUserResp{}, err
+	}
+	server.recordLoginSuccess(ctx, user)
+
+	resp := loginUserResp{
+		SessionID:             session.ID,
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessPayload.ExpireAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: refreshPayload.ExpireAt,
+		User:                  userResponse(user),
+	}
+	if server.cookies != nil {
+		if err := server.cookies.SetLogin(ctx, resp); err != nil {
+			return loginUserResp{}, err
+		}
+		// the tokens only live in http-only cookies, scripts of the page must not see them
+		resp.AccessToken, resp.RefreshToken = "", ""
+	}
+	return resp, nil
+}
+
+type changeUserPasswordReq struct {
+	CurrentPassword string `json:"current_password" binding:"required,min=6"`
+	NewPassword     string `json:"new_password" binding:"required,password"`
+}
+
+// changeUserPassword replaces the password of the authorized user.
+// Every token issued before the change is rejected from now on, so the client has to log in again.
+func (server *Server) changeUserPassword(ctx *gin.Context) {
+	var req changeUserPasswordReq
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		ctx.JSON(server.validationErrResponse(err))
+		return
+	}
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	// a stolen access token must not allow unlimited guesses of the current password
+	retryAfter, err := server.throttle.Check(ctx, payload.Username, ctx.ClientIP())
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	if retryAfter > 0 {
+		tooManyLoginAttempts(ctx, retryAfter)
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, payload.Username)
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	err = util.CheckPassword(req.CurrentPassword, user.HashedPassword)
+	if err != nil {
+		if !server.failLogin(ctx, user.Username) {
+			return
+		}
+		ctx.JSON(errResponse(http.StatusUnauthorized, err))
+		return
+	}
+	// the request body has no username or email for the validator to compare with
+	if err := server.passwordPolicy.Check(req.NewPassword, user.Username, user.Email); err != nil {
+		ctx.JSON(passwordPolicyErrResponse("new_password", err))
+		return
+	}
+
+	hashedPassword, err := server.hasher.Hash(req.NewPassword)
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	result, err := server.store.ChangePasswordTx(ctx, db.ChangePasswordTxParams{
+		Username:          user.Username,
+		HashedPassword:    hashedPassword,
+		PasswordChangedAt: time.Now(),
+	})
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	server.revocations.PasswordChanged(result.User.Username, result.User.PasswordChangedAt)
+
+	ctx.JSON(http.StatusOK, userResponse(result.User))
+}
+
+type logoutUserReq struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// logoutUser revokes the access token of the request and blocks its session. The refresh cookie is
+// scoped to the renew path and never reaches logout, so the session comes from the access token;
+// a refresh token in the body blocks its session too.
+func (server *Server) logoutUser(ctx *gin.Context) {
+	var req logoutUserReq
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+			ctx.JSON(errResponse(http.StatusBadRequest, err))
+			return
+		}
+	}
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	if len(req.RefreshToken) > 0 {
+		refreshPayload, err := server.tokenMaker.VerifyToken(req.RefreshToken)
+		if err != nil {
+			ctx.JSON(errResponse(http.StatusUnauthorized, err))
+			return
+		}
+		if refreshPayload.Purpose != refreshTokenPurpose {
+			ctx.JSON(errResponse(http.StatusUnauthorized, token.ErrInvalidToken))
+			return
+		}
+		if refreshPayload.Username != payload.Username {
+			err := errors.New("incorrect session user")
+			ctx.JSON(errResponse(http.StatusUnauthorized, err))
+			return
+		}
+		if err = server.store.BlockSession(ctx, refreshPayload.ID); err != nil {
+			ctx.JSON(statusErrResponse(err))
+			return
+		}
+		server.revocations.SessionRevoked(refreshPayload.ID, refreshPayload.ExpireAt)
+		if err = server.revocations.Revoke(ctx, refreshPayload); err != nil {
+			ctx.JSON(statusErrResponse(err))
+			return
+		}
+	}
+
+	// an impersonation token carries the session of its actor, which stays logged in
+	if payload.SessionID != uuid.Nil && !payload.IsImpersonated() {
+		session, err := server.store.BlockUserSession(ctx, db.BlockUserSessionParams{
+			ID:       payload.SessionID,
+			Username: payload.Username,
+		})
+		// a session that no longer exists cannot be renewed anyway
+		if err != nil && !errors.Is(err, db.ErrNotFound) {
+			ctx.JSON(statusErrResponse(err))
+			return
+		}
+		if err == nil {
+			server.revocations.SessionRevoked(session.ID, session.ExpiresAt)
+		}
+	}
+
+	if err := server.revocations.Revoke(ctx, payload); err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	if server.cookies != nil {
+		server.cookies.Clear(ctx)
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+// logoutAllUser blocks every session of the user and revokes all tokens issued so far
+func (server *Server) logoutAllUser(ctx *gin.Context) {
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err := server.store.BlockUserSessions(ctx, payload.Username)
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	err = server.revocations.RevokeAll(ctx, payload.Username)
+	if err != nil {
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	if server.cookies != nil {
+		server.cookies.Clear(ctx)
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "logged out of all sessions"})
 }
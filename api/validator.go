@@ -1,7 +1,14 @@
 package api
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
 	"github.com/WanCodeBase/GinModule/util"
+	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -11,3 +18,96 @@ var validCurrency validator.Func = func(fl validator.FieldLevel) bool {
 	}
 	return false
 }
+
+var validScope validator.Func = func(fl validator.FieldLevel) bool {
+	if scope, ok := fl.Field().Interface().(string); ok {
+		return util.IsSupportScope(scope)
+	}
+	return false
+}
+
+// newValidPassword checks a new password against the policy. The tag parameter names the
+// sibling fields the password must not contain, e.g. `binding:"password=Username Email"`
+func newValidPassword(policy *util.PasswordPolicy) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		password, ok := fl.Field().Interface().(string)
+		if !ok {
+			return false
+		}
+		return policy.Check(password, siblingStrings(fl, strings.Fields(fl.Param()))...) == nil
+	}
+}
+
+func siblingStrings(fl validator.FieldLevel, names []string) []string {
+	parent := reflect.Indirect(fl.Parent())
+	if parent.Kind() != reflect.Struct {
+		return nil
+	}
+	values := make([]string, 0, len(names))
+	for _, name := range names {
+		field := parent.FieldByName(name)
+		if field.IsValid() && field.Kind() == reflect.String {
+			values = append(values, field.String())
+		}
+	}
+	return values
+}
+
+// jsonFieldName makes validation errors refer to fields by their json name
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	if name == "-" {
+		return ""
+	}
+	if len(name) == 0 {
+		return field.Name
+	}
+	return name
+}
+
+// validationErrResponse is the errResponse of a bad request with a message for every invalid field, keyed by its json name
+func (server *Server) validationErrResponse(err error) (int, gin.H) {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return errResponse(http.StatusBadRequest, err)
+	}
+
+	fields := make(map[string]string, len(validationErrs))
+	messages := make([]string, 0, len(validationErrs))
+	for _, fieldErr := range validationErrs {
+		message := server.fieldErrMessage(fieldErr)
+		fields[fieldErr.Field()] = message
+		messages = append(messages, fmt.Sprintf("%s %s", fieldErr.Field(), message))
+	}
+	status, body := errResponse(http.StatusBadRequest, errors.New(strings.Join(messages, "; ")))
+	body["fields"] = fields
+	return status, body
+}
+
+// passwordPolicyErrResponse is the validationErrResponse of a password that broke the policy in a handler
+func passwordPolicyErrResponse(field string, err error) (int, gin.H) {
+	status, body := errResponse(http.StatusBadRequest, fmt.Errorf("%s %w", field, err))
+	body["fields"] = map[string]string{field: err.Error()}
+	return status, body
+}
+
+func (server *Server) fieldErrMessage(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		return fmt.Sprintf("must be at least %s characters long", fieldErr.Param())
+	case "email":
+		return "must be a valid email address"
+	case "alphanum":
+		return "must contain only letters and digits"
+	case "password":
+		password, _ := fieldErr.Value().(string)
+		if err := server.passwordPolicy.Check(password); err != nil {
+			return err.Error()
+		}
+		// only the sibling fields made it fail
+		return "must not contain the username or email"
+	}
+	return fmt.Sprintf("failed on the %s rule", fieldErr.Tag())
+}
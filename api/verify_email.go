@@ -0,0 +1,100 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/token"
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/gin-gonic/gin"
+)
+
+const verifyEmailCodeBytes = 32
+
+var (
+	errInvalidVerifyCode = errors.New("email verification code is invalid or has expired")
+	errEmailNotVerified  = errors.New("email address is not verified")
+)
+
+func (server *Server) verifyEmailBody(fullName, verifyCode string, expiresAt time.Time) string {
+	link := verifyCode
+	if len(server.config.VerifyEmailURL) > 0 {
+		link = fmt.Sprintf("%s?code=%s", server.config.VerifyEmailURL, verifyCode)
+	}
+	return fmt.Sprintf("Hi %s,\n\n"+
+		"thanks for signing up to SimpleBank. Please verify your email address by opening\n\n"+
+		"%s\n\n"+
+		"before %s.\n",
+		fullName, link, expiresAt.UTC().Format(time.RFC1123))
+}
+
+type verifyEmailReq struct {
+	Code string `form:"code" binding:"required"`
+}
+
+// verifyEmail consumes the code sent by createUser, it is opened from the email so it is a GET
+func (server *Server) verifyEmail(ctx *gin.Context) {
+	var req verifyEmailReq
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(errResponse(http.StatusBadRequest, err))
+		return
+	}
+
+	verifyEmail, err := server.store.GetVerifyEmailByHash(ctx, util.HashSecret(req.Code))
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			ctx.JSON(errResponse(http.StatusUnauthorized, errInvalidVerifyCode))
+			return
+		}
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+	if verifyEmail.IsUsed || time.Now().After(verifyEmail.ExpiresAt) {
+		ctx.JSON(errResponse(http.StatusUnauthorized, errInvalidVerifyCode))
+		return
+	}
+
+	result, err := server.store.VerifyEmailTx(ctx, db.VerifyEmailTxParams{
+		VerifyEmailID: verifyEmail.ID,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			ctx.JSON(errResponse(http.StatusUnauthorized, errInvalidVerifyCode))
+			return
+		}
+		ctx.JSON(statusErrResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, userResponse(result.User))
+}
+
+// requireVerifiedEmail aborts unless the authorized user verified its email,
+// it lets everything through when REQUIRE_VERIFIED_EMAIL is off. Must run after authMiddleware.
+func (server *Server) requireVerifiedEmail() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !server.config.RequireVerifiedEmail {
+			ctx.Next()
+			return
+		}
+
+		payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		user, err := server.store.GetUser(ctx, payload.Username)
+		if err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				ctx.AbortWithStatusJSON(errResponse(http.StatusUnauthorized, err))
+				return
+			}
+			ctx.AbortWithStatusJSON(statusErrResponse(err))
+			return
+		}
+		if !user.IsEmailVerified {
+			ctx.AbortWithStatusJSON(errResponse(http.StatusForbidden, errEmailNotVerified))
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,248 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	mockdb "github.com/WanCodeBase/GinModule/db/mock"
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyEmailAPI(t *testing.T) {
+	user, _ := randomUser()
+	code := util.RandomString(32)
+	verifyEmail := db.VerifyEmail{
+		ID:         util.RandomInt(1, 1000),
+		Username:   user.Username,
+		Email:      user.Email,
+		HashedCode: util.HashSecret(code),
+		ExpiresAt:  time.Now().Add(time.Hour),
+	}
+
+	testCases := []struct {
+		name      string
+		code      string
+		stubs     func(store *mockdb.MockStore)
+		checkResp func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			code: code,
+			stubs: func(store *mockdb.MockStore) {
+				verified := user
+				verified.IsEmailVerified = true
+				store.EXPECT().GetVerifyEmailByHash(gomock.Any(), verifyEmail.HashedCode).Times(1).Return(verifyEmail, nil)
+				store.EXPECT().VerifyEmailTx(gomock.Any(), db.VerifyEmailTxParams{VerifyEmailID: verifyEmail.ID}).
+					Times(1).Return(db.VerifyEmailTxResult{User: verified}, nil)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp userResp
+				err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+				require.NoError(t, err)
+				require.True(t, resp.IsEmailVerified)
+			},
+		},
+		{
+			name: "UnknownCode",
+			code: "unknown",
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetVerifyEmailByHash(gomock.Any(), gomock.Any()).Times(1).Return(db.VerifyEmail{}, db.ErrNotFound)
+				store.EXPECT().VerifyEmailTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "UsedCode",
+			code: code,
+			stubs: func(store *mockdb.MockStore) {
+				used := verifyEmail
+				used.IsUsed = true
+				store.EXPECT().GetVerifyEmailByHash(gomock.Any(), verifyEmail.HashedCode).Times(1).Return(used, nil)
+				store.EXPECT().VerifyEmailTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiredCode",
+			code: code,
+			stubs: func(store *mockdb.MockStore) {
+				expired := verifyEmail
+				expired.ExpiresAt = time.Now().Add(-time.Second)
+				store.EXPECT().GetVerifyEmailByHash(gomock.Any(), verifyEmail.HashedCode).Times(1).Return(expired, nil)
+				store.EXPECT().VerifyEmailTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "EmailChanged",
+			code: code,
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetVerifyEmailByHash(gomock.Any(), verifyEmail.HashedCode).Times(1).Return(verifyEmail, nil)
+				store.EXPECT().VerifyEmailTx(gomock.Any(), gomock.Any()).Times(1).Return(db.VerifyEmailTxResult{}, db.ErrNotFound)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "NoCode",
+			code: "",
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetVerifyEmailByHash(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			c.stubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/user/verify_email?code="+url.QueryEscape(c.code), nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			c.checkResp(t, recorder)
+		})
+	}
+}
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	user, _ := randomUser()
+	account := randomAccount(user.Username)
+
+	testCases := []struct {
+		name      string
+		require   bool
+		stubs     func(store *mockdb.MockStore)
+		checkResp func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:    "Verified",
+			require: true,
+			stubs: func(store *mockdb.MockStore) {
+				verified := user
+				verified.IsEmailVerified = true
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(verified, nil)
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(1).Return(account, nil)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:    "NotVerified",
+			require: true,
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:    "NotRequired",
+			require: false,
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(1).Return(account, nil)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:    "GetUserError",
+			require: true,
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(db.User{}, sql.ErrConnDone)
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResp: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			c.stubs(store)
+
+			server := newTestServer(t, store)
+			server.config.RequireVerifiedEmail = c.require
+			recorder := httptest.NewRecorder()
+
+			body, err := json.Marshal(gin.H{"currency": account.Currency})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/account", bytes.NewReader(body))
+			require.NoError(t, err)
+			setAuthorization(t, request, server.tokenMaker, user.Username, util.DepositorRole, time.Minute, authorizationHeaderType)
+
+			server.router.ServeHTTP(recorder, request)
+			c.checkResp(t, recorder)
+		})
+	}
+}
+
+func TestRequireVerifiedEmailTransfer(t *testing.T) {
+	user, _ := randomUser()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetUser(gomock.Any(), user.Username).Times(1).Return(user, nil)
+	store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+
+	server := newTestServer(t, store)
+	server.config.RequireVerifiedEmail = true
+	recorder := httptest.NewRecorder()
+
+	body, err := json.Marshal(gin.H{
+		"from_account_id": 1,
+		"to_account_id":   2,
+		"amount":          10,
+		"currency":        util.USD,
+	})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, "/transfer", bytes.NewReader(body))
+	require.NoError(t, err)
+	setAuthorization(t, request, server.tokenMaker, user.Username, util.DepositorRole, time.Minute, authorizationHeaderType)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+}
@@ -0,0 +1,110 @@
+// Command gen writes db/sqlc/querier_errors.go, the store boundary that translates the errors
+// of every generated query to domain errors. Run it after sqlc generate, see make sqlc.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"log"
+	"os"
+	"strings"
+)
+
+const (
+	querierFile = "db/sqlc/querier.go"
+	outputFile  = "db/sqlc/querier_errors.go"
+)
+
+func main() {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, querierFile, nil, 0)
+	if err != nil {
+		log.Fatal("parse querier failed:", err)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("// Code generated by go run ./db/gen. DO NOT EDIT.\n\n")
+	out.WriteString("package db\n\n")
+	writeImports(&out, file)
+	out.WriteString("// errQuerier runs the queries of Queries and translates their errors to domain errors, see translateError\n")
+	out.WriteString("type errQuerier struct {\n\tq *Queries\n}\n\n")
+	out.WriteString("var _ Querier = errQuerier{}\n")
+
+	ast.Inspect(file, func(node ast.Node) bool {
+		spec, ok := node.(*ast.TypeSpec)
+		if !ok || spec.Name.Name != "Querier" {
+			return true
+		}
+		for _, method := range spec.Type.(*ast.InterfaceType).Methods.List {
+			writeMethod(&out, fset, method.Names[0].Name, method.Type.(*ast.FuncType))
+		}
+		return false
+	})
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		log.Fatal("format output failed:", err)
+	}
+	if err := os.WriteFile(outputFile, src, 0o644); err != nil {
+		log.Fatal("write output failed:", err)
+	}
+}
+
+// writeImports writes the imports of the querier, the standard library first
+func writeImports(out *bytes.Buffer, file *ast.File) {
+	var std, others []string
+	for _, spec := range file.Imports {
+		if strings.Contains(spec.Path.Value, ".") {
+			others = append(others, spec.Path.Value)
+		} else {
+			std = append(std, spec.Path.Value)
+		}
+	}
+
+	out.WriteString("import (\n")
+	for _, path := range std {
+		fmt.Fprintf(out, "\t%s\n", path)
+	}
+	if len(std) > 0 && len(others) > 0 {
+		out.WriteString("\n")
+	}
+	for _, path := range others {
+		fmt.Fprintf(out, "\t%s\n", path)
+	}
+	out.WriteString(")\n\n")
+}
+
+func writeMethod(out *bytes.Buffer, fset *token.FileSet, name string, fn *ast.FuncType) {
+	var params, args []string
+	for _, field := range fn.Params.List {
+		for _, paramName := range field.Names {
+			params = append(params, fmt.Sprintf("%s %s", paramName.Name, expr(fset, field.Type)))
+			args = append(args, paramName.Name)
+		}
+	}
+	var results []string
+	for _, field := range fn.Results.List {
+		results = append(results, expr(fset, field.Type))
+	}
+	call := fmt.Sprintf("e.q.%s(%s)", name, strings.Join(args, ", "))
+
+	fmt.Fprintf(out, "\nfunc (e errQuerier) %s(%s) ", name, strings.Join(params, ", "))
+	if len(results) == 1 {
+		fmt.Fprintf(out, "error {\n\treturn translateError(%s)\n}\n", call)
+		return
+	}
+	fmt.Fprintf(out, "(%s) {\n\tresult, err := %s\n\treturn result, translateError(err)\n}\n", strings.Join(results, ", "), call)
+}
+
+func expr(fset *token.FileSet, node ast.Expr) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, node); err != nil {
+		log.Fatal("print type failed:", err)
+	}
+	return buf.String()
+}
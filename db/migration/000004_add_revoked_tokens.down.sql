@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "user_logouts";
+
+DROP TABLE IF EXISTS "revoked_tokens";
\ No newline at end of file
@@ -0,0 +1,22 @@
+CREATE TABLE "revoked_tokens" (
+                                  "id" uuid PRIMARY KEY,
+                                  "username" varchar NOT NULL,
+                                  "expires_at" timestamptz NOT NULL,
+                                  "revoked_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "user_logouts" (
+                                "username" varchar PRIMARY KEY,
+                                "logged_out_at" timestamptz NOT NULL,
+                                "expires_at" timestamptz NOT NULL
+);
+
+ALTER TABLE "revoked_tokens" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "user_logouts" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE INDEX ON "revoked_tokens" ("expires_at");
+
+CREATE INDEX ON "user_logouts" ("expires_at");
+
+COMMENT ON COLUMN "user_logouts"."logged_out_at" IS 'tokens issued before this time are revoked';
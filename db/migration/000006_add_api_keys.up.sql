@@ -0,0 +1,18 @@
+CREATE TABLE "api_keys" (
+                            "id" bigserial PRIMARY KEY,
+                            "username" varchar NOT NULL,
+                            "name" varchar NOT NULL,
+                            "key_prefix" varchar UNIQUE NOT NULL,
+                            "hashed_key" varchar NOT NULL,
+                            "scopes" varchar[] NOT NULL,
+                            "is_revoked" boolean NOT NULL DEFAULT false,
+                            "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "api_keys" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE INDEX ON "api_keys" ("username");
+
+COMMENT ON COLUMN "api_keys"."key_prefix" IS 'public part of the key used for lookup';
+
+COMMENT ON COLUMN "api_keys"."hashed_key" IS 'sha256 of the full key';
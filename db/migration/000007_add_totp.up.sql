@@ -0,0 +1,36 @@
+CREATE TABLE "user_totps" (
+                              "username" varchar PRIMARY KEY,
+                              "secret" varchar NOT NULL,
+                              "is_confirmed" boolean NOT NULL DEFAULT false,
+                              "last_used_step" bigint NOT NULL DEFAULT 0,
+                              "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "recovery_codes" (
+                                  "id" bigserial PRIMARY KEY,
+                                  "username" varchar NOT NULL,
+                                  "hashed_code" varchar NOT NULL,
+                                  "is_used" boolean NOT NULL DEFAULT false,
+                                  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "login_challenges" (
+                                    "id" uuid PRIMARY KEY,
+                                    "username" varchar NOT NULL,
+                                    "hashed_token" varchar UNIQUE NOT NULL,
+                                    "attempts" int NOT NULL DEFAULT 0,
+                                    "expires_at" timestamptz NOT NULL,
+                                    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "user_totps" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "recovery_codes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "login_challenges" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE INDEX ON "recovery_codes" ("username");
+
+COMMENT ON COLUMN "user_totps"."last_used_step" IS 'time step of the last accepted code, stops replays';
+
+COMMENT ON COLUMN "login_challenges"."hashed_token" IS 'sha256 of the challenge token';
@@ -0,0 +1,25 @@
+CREATE TABLE "login_throttles" (
+                                   "kind" varchar NOT NULL,
+                                   "key" varchar NOT NULL,
+                                   "failed_attempts" int NOT NULL DEFAULT 0,
+                                   "blocked_until" timestamptz NOT NULL DEFAULT '0001-01-01 00:00:00Z',
+                                   "last_failed_at" timestamptz NOT NULL DEFAULT (now()),
+                                   PRIMARY KEY ("kind", "key")
+);
+
+CREATE TABLE "login_lockouts" (
+                                  "id" bigserial PRIMARY KEY,
+                                  "username" varchar NOT NULL,
+                                  "client_ip" varchar NOT NULL,
+                                  "failed_attempts" int NOT NULL,
+                                  "locked_until" timestamptz NOT NULL,
+                                  "unlocked_by" varchar,
+                                  "unlocked_at" timestamptz,
+                                  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "login_lockouts" ("username");
+
+COMMENT ON COLUMN "login_throttles"."kind" IS 'username or ip';
+
+COMMENT ON COLUMN "login_lockouts"."username" IS 'not a foreign key, unknown usernames are locked out too';
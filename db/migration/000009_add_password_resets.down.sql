@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "email_outbox";
+
+DROP TABLE IF EXISTS "password_reset_tokens";
@@ -0,0 +1,30 @@
+CREATE TABLE "password_reset_tokens" (
+                                         "id" uuid PRIMARY KEY,
+                                         "username" varchar NOT NULL,
+                                         "hashed_token" varchar UNIQUE NOT NULL,
+                                         "expires_at" timestamptz NOT NULL,
+                                         "used_at" timestamptz,
+                                         "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "email_outbox" (
+                                "id" bigserial PRIMARY KEY,
+                                "to_address" varchar NOT NULL,
+                                "subject" varchar NOT NULL,
+                                "body" text NOT NULL,
+                                "attempts" int NOT NULL DEFAULT 0,
+                                "last_error" varchar NOT NULL DEFAULT '',
+                                "next_attempt_at" timestamptz NOT NULL DEFAULT (now()),
+                                "sent_at" timestamptz,
+                                "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "password_reset_tokens" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE INDEX ON "password_reset_tokens" ("username");
+
+CREATE INDEX ON "email_outbox" ("next_attempt_at") WHERE "sent_at" IS NULL;
+
+COMMENT ON COLUMN "password_reset_tokens"."hashed_token" IS 'sha256 of the reset token';
+
+COMMENT ON COLUMN "email_outbox"."next_attempt_at" IS 'a claimed email is retried after this time unless it was sent';
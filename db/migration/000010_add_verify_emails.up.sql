@@ -0,0 +1,19 @@
+ALTER TABLE "users" ADD COLUMN "is_email_verified" boolean NOT NULL DEFAULT false;
+
+CREATE TABLE "verify_emails" (
+                                 "id" bigserial PRIMARY KEY,
+                                 "username" varchar NOT NULL,
+                                 "email" varchar NOT NULL,
+                                 "hashed_code" varchar UNIQUE NOT NULL,
+                                 "is_used" boolean NOT NULL DEFAULT false,
+                                 "expires_at" timestamptz NOT NULL,
+                                 "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "verify_emails" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE INDEX ON "verify_emails" ("username");
+
+COMMENT ON COLUMN "verify_emails"."email" IS 'the address the code was sent to, it only verifies that address';
+
+COMMENT ON COLUMN "verify_emails"."hashed_code" IS 'sha256 of the secret code';
@@ -0,0 +1,5 @@
+ALTER TABLE "sessions" ADD COLUMN "device_name" varchar NOT NULL DEFAULT '';
+
+CREATE INDEX ON "sessions" ("username");
+
+COMMENT ON COLUMN "sessions"."device_name" IS 'name of the device given by the client at login';
@@ -0,0 +1,14 @@
+CREATE TABLE "login_events" (
+                                "id" bigserial PRIMARY KEY,
+                                "username" varchar NOT NULL,
+                                "client_ip" varchar NOT NULL,
+                                "user_agent" varchar NOT NULL,
+                                "outcome" varchar NOT NULL,
+                                "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "login_events" ("username", "created_at");
+
+COMMENT ON COLUMN "login_events"."username" IS 'not a foreign key, attempts on unknown usernames are recorded too';
+
+COMMENT ON COLUMN "login_events"."outcome" IS 'success, bad_password, unknown_user, throttled, totp_required or bad_second_factor';
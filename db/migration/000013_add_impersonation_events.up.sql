@@ -0,0 +1,16 @@
+CREATE TABLE "impersonation_events" (
+                                "id" bigserial PRIMARY KEY,
+                                "actor" varchar NOT NULL,
+                                "username" varchar NOT NULL,
+                                "method" varchar NOT NULL,
+                                "path" varchar NOT NULL,
+                                "status_code" int NOT NULL,
+                                "client_ip" varchar NOT NULL,
+                                "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "impersonation_events" ("username", "created_at");
+
+CREATE INDEX ON "impersonation_events" ("actor", "created_at");
+
+COMMENT ON COLUMN "impersonation_events"."actor" IS 'the admin acting as username';
@@ -0,0 +1,5 @@
+COMMENT ON COLUMN "login_events"."outcome" IS 'success, bad_password, unknown_user, throttled, totp_required or bad_second_factor';
+
+DROP TABLE IF EXISTS "webauthn_ceremonies";
+
+DROP TABLE IF EXISTS "webauthn_credentials";
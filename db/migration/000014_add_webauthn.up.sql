@@ -0,0 +1,37 @@
+CREATE TABLE "webauthn_credentials" (
+                                        "id" bytea PRIMARY KEY,
+                                        "username" varchar NOT NULL,
+                                        "name" varchar NOT NULL,
+                                        "public_key" bytea NOT NULL,
+                                        "attestation_type" varchar NOT NULL,
+                                        "transports" varchar[] NOT NULL DEFAULT '{}',
+                                        "aaguid" bytea NOT NULL,
+                                        "sign_count" bigint NOT NULL DEFAULT 0,
+                                        "backup_eligible" boolean NOT NULL DEFAULT false,
+                                        "backup_state" boolean NOT NULL DEFAULT false,
+                                        "last_used_at" timestamptz NOT NULL DEFAULT '0001-01-01 00:00:00Z',
+                                        "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "webauthn_ceremonies" (
+                                       "id" uuid PRIMARY KEY,
+                                       "kind" varchar NOT NULL,
+                                       "username" varchar NOT NULL DEFAULT '',
+                                       "session_data" jsonb NOT NULL,
+                                       "expires_at" timestamptz NOT NULL,
+                                       "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "webauthn_credentials" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE INDEX ON "webauthn_credentials" ("username");
+
+COMMENT ON COLUMN "webauthn_credentials"."id" IS 'credential id chosen by the authenticator';
+
+COMMENT ON COLUMN "webauthn_credentials"."sign_count" IS 'signature counter of the authenticator, a counter going back means a cloned authenticator';
+
+COMMENT ON COLUMN "webauthn_ceremonies"."kind" IS 'registration or login';
+
+COMMENT ON COLUMN "webauthn_ceremonies"."username" IS 'empty for a login, the authenticator names the user';
+
+COMMENT ON COLUMN "login_events"."outcome" IS 'success, bad_password, unknown_user, throttled, totp_required, bad_second_factor or bad_passkey';
@@ -0,0 +1,3 @@
+COMMENT ON COLUMN "login_events"."outcome" IS 'success, bad_password, unknown_user, throttled, totp_required, bad_second_factor or bad_passkey';
+
+DROP TABLE IF EXISTS "magic_links";
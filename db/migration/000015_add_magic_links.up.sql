@@ -0,0 +1,15 @@
+CREATE TABLE "magic_links" (
+                               "id" uuid PRIMARY KEY,
+                               "username" varchar NOT NULL,
+                               "expires_at" timestamptz NOT NULL,
+                               "used_at" timestamptz,
+                               "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "magic_links" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE INDEX ON "magic_links" ("username");
+
+COMMENT ON COLUMN "magic_links"."id" IS 'id of the signed token in the link';
+
+COMMENT ON COLUMN "login_events"."outcome" IS 'success, bad_password, unknown_user, throttled, totp_required, bad_second_factor, bad_passkey or bad_magic_link';
@@ -0,0 +1,5 @@
+ALTER TABLE "accounts" DROP CONSTRAINT IF EXISTS "accounts_balance_check";
+
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "legacy_debt";
+
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "overdraft_limit";
@@ -0,0 +1,17 @@
+ALTER TABLE "accounts" ADD COLUMN "overdraft_limit" bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE "accounts" ADD COLUMN "legacy_debt" bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_overdraft_limit_check" CHECK ("overdraft_limit" >= 0);
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_legacy_debt_check" CHECK ("legacy_debt" >= 0);
+
+-- accounts overdrawn before the limits existed keep their debt as legacy debt. It is not an allowance:
+-- the balance queries only ever lower it to the part of the debt beyond the overdraft limit.
+UPDATE "accounts" SET "legacy_debt" = -"balance" WHERE "balance" < 0;
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_balance_check" CHECK ("balance" >= -("overdraft_limit" + "legacy_debt"));
+
+COMMENT ON COLUMN "accounts"."overdraft_limit" IS 'how far the balance may go below zero';
+
+COMMENT ON COLUMN "accounts"."legacy_debt" IS 'debt beyond the overdraft limit from before the limits existed, it can be paid back but not borrowed again';
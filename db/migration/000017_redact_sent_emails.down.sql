@@ -0,0 +1 @@
+COMMENT ON COLUMN "email_outbox"."body" IS NULL;
@@ -0,0 +1,3 @@
+UPDATE "email_outbox" SET "body" = '' WHERE "sent_at" IS NOT NULL;
+
+COMMENT ON COLUMN "email_outbox"."body" IS 'cleared once sent, it may carry a secret like a reset token';
@@ -0,0 +1,14 @@
+CREATE TABLE "webauthn_user_handles" (
+                                         "handle" bytea PRIMARY KEY,
+                                         "username" varchar UNIQUE NOT NULL,
+                                         "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "webauthn_user_handles" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+-- passkeys registered before the handles existed hold the username as user handle,
+-- their users keep it or those passkeys could no longer log in
+INSERT INTO "webauthn_user_handles" ("handle", "username")
+SELECT DISTINCT convert_to("username", 'UTF8'), "username" FROM "webauthn_credentials";
+
+COMMENT ON COLUMN "webauthn_user_handles"."handle" IS 'opaque WebAuthn user handle, random bytes that say nothing about the user';
@@ -24,17 +24,29 @@ LIMIT $2
 OFFSET $3;
 
 -- name: UpdateAccount :one
+-- legacy_debt never grows, it is lowered to the debt beyond the overdraft limit
 UPDATE accounts
-SET balance = $2
-WHERE id = $1
+SET balance = sqlc.arg(balance),
+    legacy_debt = LEAST(legacy_debt, GREATEST(0, -sqlc.arg(balance) - overdraft_limit))
+WHERE id = sqlc.arg(id)
 RETURNING *;
 
 -- name: AddAccountBalance :one
+-- legacy_debt never grows, it is lowered to the debt beyond the overdraft limit
 UPDATE accounts
-SET balance = balance+sqlc.arg(amount)
+SET balance = balance+sqlc.arg(amount),
+    legacy_debt = LEAST(legacy_debt, GREATEST(0, -(balance+sqlc.arg(amount)) - overdraft_limit))
 WHERE id = sqlc.arg(id)
     RETURNING *;
 
+-- name: UpdateAccountOverdraftLimit :one
+-- legacy_debt never grows, it is lowered to the debt beyond the new overdraft limit
+UPDATE accounts
+SET overdraft_limit = sqlc.arg(overdraft_limit),
+    legacy_debt = LEAST(legacy_debt, GREATEST(0, -balance - sqlc.arg(overdraft_limit)))
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
 -- name: DeleteAccount :exec
 DELETE FROM accounts
 WHERE id = $1;
\ No newline at end of file
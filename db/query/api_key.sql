@@ -0,0 +1,25 @@
+-- name: CreateAPIKey :one
+INSERT INTO api_keys (
+    username,
+    name,
+    key_prefix,
+    hashed_key,
+    scopes
+) VALUES (
+    $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: GetAPIKeyByPrefix :one
+SELECT * FROM api_keys
+WHERE key_prefix = $1 LIMIT 1;
+
+-- name: ListAPIKeys :many
+SELECT * FROM api_keys
+WHERE username = $1
+ORDER BY id;
+
+-- name: RevokeAPIKey :one
+UPDATE api_keys
+SET is_revoked = true
+WHERE id = $1 AND username = $2
+RETURNING *;
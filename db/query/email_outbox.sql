@@ -0,0 +1,41 @@
+-- name: CreateOutboxEmail :one
+INSERT INTO email_outbox (
+    to_address,
+    subject,
+    body
+) VALUES (
+    $1, $2, $3
+) RETURNING *;
+
+-- name: ClaimOutboxEmails :many
+UPDATE email_outbox
+SET attempts = attempts + 1,
+    next_attempt_at = sqlc.arg(next_attempt_at)
+WHERE id IN (
+    SELECT pending.id FROM email_outbox AS pending
+    WHERE pending.sent_at IS NULL
+      AND pending.next_attempt_at <= now()
+      AND pending.attempts < sqlc.arg(max_attempts)
+    ORDER BY pending.id
+    LIMIT sqlc.arg(batch_size)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkOutboxEmailSent :exec
+-- the body is cleared, it may carry a secret like a reset token
+UPDATE email_outbox
+SET sent_at = now(),
+    last_error = '',
+    body = ''
+WHERE id = $1;
+
+-- name: MarkOutboxEmailFailed :exec
+UPDATE email_outbox
+SET last_error = $2
+WHERE id = $1;
+
+-- name: DeleteFinishedOutboxEmails :execrows
+DELETE FROM email_outbox
+WHERE created_at < sqlc.arg(created_before)
+  AND (sent_at IS NOT NULL OR attempts >= sqlc.arg(max_attempts));
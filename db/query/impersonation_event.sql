@@ -0,0 +1,18 @@
+-- name: CreateImpersonationEvent :one
+INSERT INTO impersonation_events (
+    actor,
+    username,
+    method,
+    path,
+    status_code,
+    client_ip
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: ListImpersonationEvents :many
+SELECT * FROM impersonation_events
+WHERE username = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2
+OFFSET $3;
@@ -0,0 +1,31 @@
+-- name: CreateLoginEvent :one
+INSERT INTO login_events (
+    username,
+    client_ip,
+    user_agent,
+    outcome
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING *;
+
+-- name: ListLoginEvents :many
+SELECT * FROM login_events
+WHERE username = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2
+OFFSET $3;
+
+-- name: GetLoginHistory :one
+SELECT
+    EXISTS (
+        SELECT 1 FROM login_events AS logins
+        WHERE logins.username = sqlc.arg(username)
+          AND logins.outcome = 'success'
+    ) AS has_logged_in,
+    EXISTS (
+        SELECT 1 FROM login_events AS device_logins
+        WHERE device_logins.username = sqlc.arg(username)
+          AND device_logins.outcome = 'success'
+          AND device_logins.client_ip = sqlc.arg(client_ip)
+          AND device_logins.user_agent = sqlc.arg(user_agent)
+    ) AS is_known_device;
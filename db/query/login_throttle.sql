@@ -0,0 +1,55 @@
+-- name: GetLoginThrottle :one
+SELECT * FROM login_throttles
+WHERE kind = $1 AND key = $2 LIMIT 1;
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_throttles (
+    kind,
+    key,
+    failed_attempts,
+    last_failed_at
+) VALUES (
+    sqlc.arg(kind), sqlc.arg(key), 1, now()
+) ON CONFLICT (kind, key) DO UPDATE
+SET failed_attempts = CASE
+        WHEN login_throttles.last_failed_at < sqlc.arg(reset_before) THEN 1
+        ELSE login_throttles.failed_attempts + 1
+    END,
+    last_failed_at = now()
+RETURNING *;
+
+-- name: BlockLoginThrottle :exec
+UPDATE login_throttles
+SET blocked_until = $3
+WHERE kind = $1 AND key = $2;
+
+-- name: DeleteLoginThrottle :exec
+DELETE FROM login_throttles
+WHERE kind = $1 AND key = $2;
+
+-- name: DeleteStaleLoginThrottles :execrows
+DELETE FROM login_throttles
+WHERE last_failed_at < $1 AND blocked_until < now();
+
+-- name: CreateLoginLockout :one
+INSERT INTO login_lockouts (
+    username,
+    client_ip,
+    failed_attempts,
+    locked_until
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING *;
+
+-- name: ListLoginLockouts :many
+SELECT * FROM login_lockouts
+WHERE username = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3;
+
+-- name: UnlockLoginLockouts :execrows
+UPDATE login_lockouts
+SET unlocked_by = $2,
+    unlocked_at = now()
+WHERE username = $1 AND unlocked_at IS NULL;
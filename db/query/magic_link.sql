@@ -0,0 +1,18 @@
+-- name: CreateMagicLink :one
+INSERT INTO magic_links (
+    id,
+    username,
+    expires_at
+) VALUES (
+    $1, $2, $3
+) RETURNING *;
+
+-- name: UseMagicLink :one
+UPDATE magic_links
+SET used_at = now()
+WHERE id = $1 AND used_at IS NULL AND expires_at > now()
+RETURNING *;
+
+-- name: DeleteUnusedMagicLinks :exec
+DELETE FROM magic_links
+WHERE username = $1 AND used_at IS NULL;
@@ -0,0 +1,23 @@
+-- name: CreatePasswordResetToken :one
+INSERT INTO password_reset_tokens (
+    id,
+    username,
+    hashed_token,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetPasswordResetTokenByHash :one
+SELECT * FROM password_reset_tokens
+WHERE hashed_token = $1 LIMIT 1;
+
+-- name: UsePasswordResetToken :one
+UPDATE password_reset_tokens
+SET used_at = now()
+WHERE id = $1 AND used_at IS NULL AND expires_at > now()
+RETURNING *;
+
+-- name: DeleteUnusedPasswordResetTokens :exec
+DELETE FROM password_reset_tokens
+WHERE username = $1 AND used_at IS NULL;
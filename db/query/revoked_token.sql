@@ -0,0 +1,36 @@
+-- name: CreateRevokedToken :exec
+INSERT INTO revoked_tokens (
+    id,
+    username,
+    expires_at
+) VALUES (
+    $1, $2, $3
+) ON CONFLICT (id) DO NOTHING;
+
+-- name: ListRevokedTokens :many
+SELECT * FROM revoked_tokens
+WHERE expires_at > now();
+
+-- name: DeleteExpiredRevokedTokens :execrows
+DELETE FROM revoked_tokens
+WHERE expires_at <= now();
+
+-- name: UpsertUserLogout :one
+INSERT INTO user_logouts (
+    username,
+    logged_out_at,
+    expires_at
+) VALUES (
+    $1, $2, $3
+) ON CONFLICT (username) DO UPDATE
+SET logged_out_at = EXCLUDED.logged_out_at,
+    expires_at = EXCLUDED.expires_at
+RETURNING *;
+
+-- name: ListUserLogouts :many
+SELECT * FROM user_logouts
+WHERE expires_at > now();
+
+-- name: DeleteExpiredUserLogouts :execrows
+DELETE FROM user_logouts
+WHERE expires_at <= now();
@@ -0,0 +1,71 @@
+-- name: UpsertUserTOTP :one
+INSERT INTO user_totps (
+    username,
+    secret
+) VALUES (
+    $1, $2
+) ON CONFLICT (username) DO UPDATE
+SET secret = EXCLUDED.secret,
+    is_confirmed = false,
+    last_used_step = 0,
+    created_at = now()
+RETURNING *;
+
+-- name: GetUserTOTP :one
+SELECT * FROM user_totps
+WHERE username = $1 LIMIT 1;
+
+-- name: ConfirmUserTOTP :one
+-- only the pending secret the user proved a code for is enabled, a re-enroll in between leaves no row
+UPDATE user_totps
+SET is_confirmed = true
+WHERE username = $1 AND secret = $2 AND is_confirmed = false
+RETURNING *;
+
+-- name: UseTOTPStep :one
+UPDATE user_totps
+SET last_used_step = sqlc.arg(step)
+WHERE username = sqlc.arg(username) AND last_used_step < sqlc.arg(step)
+RETURNING *;
+
+-- name: CreateRecoveryCode :one
+INSERT INTO recovery_codes (
+    username,
+    hashed_code
+) VALUES (
+    $1, $2
+) RETURNING *;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE username = $1;
+
+-- name: UseRecoveryCode :one
+UPDATE recovery_codes
+SET is_used = true
+WHERE username = $1 AND hashed_code = $2 AND is_used = false
+RETURNING *;
+
+-- name: CreateLoginChallenge :one
+INSERT INTO login_challenges (
+    id,
+    username,
+    hashed_token,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetLoginChallengeByHash :one
+SELECT * FROM login_challenges
+WHERE hashed_token = $1 LIMIT 1;
+
+-- name: IncrementLoginChallengeAttempts :one
+UPDATE login_challenges
+SET attempts = attempts + 1
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteLoginChallenge :exec
+DELETE FROM login_challenges
+WHERE id = $1;
@@ -0,0 +1,19 @@
+-- name: CreateVerifyEmail :one
+INSERT INTO verify_emails (
+    username,
+    email,
+    hashed_code,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetVerifyEmailByHash :one
+SELECT * FROM verify_emails
+WHERE hashed_code = $1 LIMIT 1;
+
+-- name: UseVerifyEmail :one
+UPDATE verify_emails
+SET is_used = true
+WHERE id = $1 AND is_used = false AND expires_at > now()
+RETURNING *;
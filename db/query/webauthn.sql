@@ -0,0 +1,67 @@
+-- name: CreateWebAuthnCredential :one
+INSERT INTO webauthn_credentials (
+    id,
+    username,
+    name,
+    public_key,
+    attestation_type,
+    transports,
+    aaguid,
+    sign_count,
+    backup_eligible,
+    backup_state
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+) RETURNING *;
+
+-- name: ListWebAuthnCredentials :many
+SELECT * FROM webauthn_credentials
+WHERE username = $1
+ORDER BY created_at;
+
+-- name: UseWebAuthnCredential :one
+UPDATE webauthn_credentials
+SET sign_count = sqlc.arg(sign_count),
+    backup_state = sqlc.arg(backup_state),
+    last_used_at = now()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: DeleteWebAuthnCredential :one
+DELETE FROM webauthn_credentials
+WHERE id = $1 AND username = $2
+RETURNING *;
+
+-- name: CreateWebAuthnCeremony :one
+INSERT INTO webauthn_ceremonies (
+    id,
+    kind,
+    username,
+    session_data,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: TakeWebAuthnCeremony :one
+DELETE FROM webauthn_ceremonies
+WHERE id = $1 AND kind = $2
+RETURNING *;
+
+-- name: DeleteExpiredWebAuthnCeremonies :execrows
+DELETE FROM webauthn_ceremonies
+WHERE expires_at < now();
+
+-- name: CreateWebAuthnUserHandle :one
+-- a user that has a handle keeps it
+INSERT INTO webauthn_user_handles (
+    handle,
+    username
+) VALUES (
+    $1, $2
+) ON CONFLICT (username) DO UPDATE SET username = EXCLUDED.username
+RETURNING *;
+
+-- name: GetWebAuthnUserHandle :one
+SELECT * FROM webauthn_user_handles
+WHERE handle = $1 LIMIT 1;
@@ -11,9 +11,10 @@ import (
 
 const addAccountBalance = `-- name: AddAccountBalance :one
 UPDATE accounts
-SET balance = balance+$1
+SET balance = balance+$1,
+    legacy_debt = LEAST(legacy_debt, GREATEST(0, -(balance+$1) - overdraft_limit))
 WHERE id = $2
-    RETURNING id, owner, balance, currency, created_at
+    RETURNING id, owner, balance, currency, created_at, overdraft_limit, legacy_debt
 `
 
 type AddAccountBalanceParams struct {
@@ -21,6 +22,7 @@ type AddAccountBalanceParams struct {
 	ID     int64 `json:"id"`
 }
 
+// legacy_debt never grows, it is lowered to the debt beyond the overdraft limit
 func (q *Queries) AddAccountBalance(ctx context.Context, arg AddAccountBalanceParams) (Account, error) {
 	row := q.db.QueryRowContext(ctx, addAccountBalance, arg.Amount, arg.ID)
 	var i Account
@@ -30,6 +32,8 @@ func (q *Queries) AddAccountBalance(ctx context.Context, arg AddAccountBalancePa
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.LegacyDebt,
 	)
 	return i, err
 }
@@ -41,7 +45,7 @@ INSERT INTO accounts (
     currency
 ) VALUES (
     $1, $2, $3
-) RETURNING id, owner, balance, currency, created_at
+) RETURNING id, owner, balance, currency, created_at, overdraft_limit, legacy_debt
 `
 
 type CreateAccountParams struct {
@@ -59,6 +63,8 @@ func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.LegacyDebt,
 	)
 	return i, err
 }
@@ -74,7 +80,7 @@ func (q *Queries) DeleteAccount(ctx context.Context, id int64) error {
 }
 
 const getAccount = `-- name: GetAccount :one
-SELECT id, owner, balance, currency, created_at FROM accounts
+SELECT id, owner, balance, currency, created_at, overdraft_limit, legacy_debt FROM accounts
 WHERE id = $1 LIMIT 1
 `
 
@@ -87,12 +93,14 @@ func (q *Queries) GetAccount(ctx context.Context, id int64) (Account, error) {
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.LegacyDebt,
 	)
 	return i, err
 }
 
 const getAccountForUpdate = `-- name: GetAccountForUpdate :one
-SELECT id, owner, balance, currency, created_at FROM accounts
+SELECT id, owner, balance, currency, created_at, overdraft_limit, legacy_debt FROM accounts
 WHERE id = $1 LIMIT 1
 FOR No KEY UPDATE
 `
@@ -106,12 +114,14 @@ func (q *Queries) GetAccountForUpdate(ctx context.Context, id int64) (Account, e
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.LegacyDebt,
 	)
 	return i, err
 }
 
 const listAccounts = `-- name: ListAccounts :many
-SELECT id, owner, balance, currency, created_at FROM accounts
+SELECT id, owner, balance, currency, created_at, overdraft_limit, legacy_debt FROM accounts
 WHERE owner = $1
 ORDER BY id
 LIMIT $2
@@ -139,6 +149,8 @@ func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]A
 			&i.Balance,
 			&i.Currency,
 			&i.CreatedAt,
+			&i.OverdraftLimit,
+			&i.LegacyDebt,
 		); err != nil {
 			return nil, err
 		}
@@ -155,18 +167,49 @@ func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]A
 
 const updateAccount = `-- name: UpdateAccount :one
 UPDATE accounts
-SET balance = $2
-WHERE id = $1
-RETURNING id, owner, balance, currency, created_at
+SET balance = $1,
+    legacy_debt = LEAST(legacy_debt, GREATEST(0, -$1 - overdraft_limit))
+WHERE id = $2
+RETURNING id, owner, balance, currency, created_at, overdraft_limit, legacy_debt
 `
 
 type UpdateAccountParams struct {
-	ID      int64 `json:"id"`
 	Balance int64 `json:"balance"`
+	ID      int64 `json:"id"`
 }
 
+// legacy_debt never grows, it is lowered to the debt beyond the overdraft limit
 func (q *Queries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error) {
-	row := q.db.QueryRowContext(ctx, updateAccount, arg.ID, arg.Balance)
+	row := q.db.QueryRowContext(ctx, updateAccount, arg.Balance, arg.ID)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.LegacyDebt,
+	)
+	return i, err
+}
+
+const updateAccountOverdraftLimit = `-- name: UpdateAccountOverdraftLimit :one
+UPDATE accounts
+SET overdraft_limit = $1,
+    legacy_debt = LEAST(legacy_debt, GREATEST(0, -balance - $1))
+WHERE id = $2
+RETURNING id, owner, balance, currency, created_at, overdraft_limit, legacy_debt
+`
+
+type UpdateAccountOverdraftLimitParams struct {
+	OverdraftLimit int64 `json:"overdraft_limit"`
+	ID             int64 `json:"id"`
+}
+
+// legacy_debt never grows, it is lowered to the debt beyond the new overdraft limit
+func (q *Queries) UpdateAccountOverdraftLimit(ctx context.Context, arg UpdateAccountOverdraftLimitParams) (Account, error) {
+	row := q.db.QueryRowContext(ctx, updateAccountOverdraftLimit, arg.OverdraftLimit, arg.ID)
 	var i Account
 	err := row.Scan(
 		&i.ID,
@@ -174,6 +217,8 @@ func (q *Queries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.LegacyDebt,
 	)
 	return i, err
 }
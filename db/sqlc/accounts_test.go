@@ -8,11 +8,16 @@ import (
 )
 
 func _createAccount(t *testing.T) Account {
+	return _createAccountIn(t, util.RandomCurrency())
+}
+
+// _createAccountIn creates an account with currency, transfers need two of the same currency
+func _createAccountIn(t *testing.T, currency string) Account {
 	user := _createUser(t)
 	arg := CreateAccountParams{
 		Owner:    user.Username,
 		Balance:  util.RandomMoney(),
-		Currency: util.RandomCurrency(),
+		Currency: currency,
 	}
 
 	account, err := testQueries.CreateAccount(context.Background(), arg)
@@ -0,0 +1,141 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: api_key.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+)
+
+const createAPIKey = `-- name: CreateAPIKey :one
+INSERT INTO api_keys (
+    username,
+    name,
+    key_prefix,
+    hashed_key,
+    scopes
+) VALUES (
+    $1, $2, $3, $4, $5
+) RETURNING id, username, name, key_prefix, hashed_key, scopes, is_revoked, created_at
+`
+
+type CreateAPIKeyParams struct {
+	Username  string   `json:"username"`
+	Name      string   `json:"name"`
+	KeyPrefix string   `json:"key_prefix"`
+	HashedKey string   `json:"hashed_key"`
+	Scopes    []string `json:"scopes"`
+}
+
+func (q *Queries) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error) {
+	row := q.db.QueryRowContext(ctx, createAPIKey,
+		arg.Username,
+		arg.Name,
+		arg.KeyPrefix,
+		arg.HashedKey,
+		pq.Array(arg.Scopes),
+	)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Name,
+		&i.KeyPrefix,
+		&i.HashedKey,
+		pq.Array(&i.Scopes),
+		&i.IsRevoked,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getAPIKeyByPrefix = `-- name: GetAPIKeyByPrefix :one
+SELECT id, username, name, key_prefix, hashed_key, scopes, is_revoked, created_at FROM api_keys
+WHERE key_prefix = $1 LIMIT 1
+`
+
+func (q *Queries) GetAPIKeyByPrefix(ctx context.Context, keyPrefix string) (ApiKey, error) {
+	row := q.db.QueryRowContext(ctx, getAPIKeyByPrefix, keyPrefix)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Name,
+		&i.KeyPrefix,
+		&i.HashedKey,
+		pq.Array(&i.Scopes),
+		&i.IsRevoked,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listAPIKeys = `-- name: ListAPIKeys :many
+SELECT id, username, name, key_prefix, hashed_key, scopes, is_revoked, created_at FROM api_keys
+WHERE username = $1
+ORDER BY id
+`
+
+func (q *Queries) ListAPIKeys(ctx context.Context, username string) ([]ApiKey, error) {
+	rows, err := q.db.QueryContext(ctx, listAPIKeys, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ApiKey{}
+	for rows.Next() {
+		var i ApiKey
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.Name,
+			&i.KeyPrefix,
+			&i.HashedKey,
+			pq.Array(&i.Scopes),
+			&i.IsRevoked,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeAPIKey = `-- name: RevokeAPIKey :one
+UPDATE api_keys
+SET is_revoked = true
+WHERE id = $1 AND username = $2
+RETURNING id, username, name, key_prefix, hashed_key, scopes, is_revoked, created_at
+`
+
+type RevokeAPIKeyParams struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+func (q *Queries) RevokeAPIKey(ctx context.Context, arg RevokeAPIKeyParams) (ApiKey, error) {
+	row := q.db.QueryRowContext(ctx, revokeAPIKey, arg.ID, arg.Username)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Name,
+		&i.KeyPrefix,
+		&i.HashedKey,
+		pq.Array(&i.Scopes),
+		&i.IsRevoked,
+		&i.CreatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func _createAPIKey(t *testing.T) ApiKey {
+	user := _createUser(t)
+	arg := CreateAPIKeyParams{
+		Username:  user.Username,
+		Name:      util.RandomString(6),
+		KeyPrefix: util.RandomString(16),
+		HashedKey: util.HashSecret(util.RandomString(32)),
+		Scopes:    []string{util.AccountsReadScope, util.TransfersWriteScope},
+	}
+
+	apiKey, err := testQueries.CreateAPIKey(context.Background(), arg)
+
+	assert.NoError(t, err)
+	assert.NotZero(t, apiKey.ID)
+	assert.Equal(t, arg.Username, apiKey.Username)
+	assert.Equal(t, arg.KeyPrefix, apiKey.KeyPrefix)
+	assert.Equal(t, arg.HashedKey, apiKey.HashedKey)
+	assert.Equal(t, arg.Scopes, apiKey.Scopes)
+	assert.False(t, apiKey.IsRevoked)
+	assert.NotZero(t, apiKey.CreatedAt)
+
+	return apiKey
+}
+
+func TestGetAPIKeyByPrefix(t *testing.T) {
+	apiKey1 := _createAPIKey(t)
+
+	apiKey2, err := testQueries.GetAPIKeyByPrefix(context.Background(), apiKey1.KeyPrefix)
+
+	assert.NoError(t, err)
+	assert.Equal(t, apiKey1, apiKey2)
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	apiKey1 := _createAPIKey(t)
+
+	// only the owner can revoke
+	_, err := testQueries.RevokeAPIKey(context.Background(), RevokeAPIKeyParams{
+		ID:       apiKey1.ID,
+		Username: util.RandomOwner(),
+	})
+	assert.Error(t, err)
+
+	apiKey2, err := testQueries.RevokeAPIKey(context.Background(), RevokeAPIKeyParams{
+		ID:       apiKey1.ID,
+		Username: apiKey1.Username,
+	})
+	assert.NoError(t, err)
+	assert.True(t, apiKey2.IsRevoked)
+
+	apiKeys, err := testQueries.ListAPIKeys(context.Background(), apiKey1.Username)
+	assert.NoError(t, err)
+	assert.Len(t, apiKeys, 1)
+}
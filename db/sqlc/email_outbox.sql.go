@@ -0,0 +1,148 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: email_outbox.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const claimOutboxEmails = `-- name: ClaimOutboxEmails :many
+UPDATE email_outbox
+SET attempts = attempts + 1,
+    next_attempt_at = $1
+WHERE id IN (
+    SELECT pending.id FROM email_outbox AS pending
+    WHERE pending.sent_at IS NULL
+      AND pending.next_attempt_at <= now()
+      AND pending.attempts < $2
+    ORDER BY pending.id
+    LIMIT $3
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, to_address, subject, body, attempts, last_error, next_attempt_at, sent_at, created_at
+`
+
+type ClaimOutboxEmailsParams struct {
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	MaxAttempts   int32     `json:"max_attempts"`
+	BatchSize     int32     `json:"batch_size"`
+}
+
+func (q *Queries) ClaimOutboxEmails(ctx context.Context, arg ClaimOutboxEmailsParams) ([]EmailOutbox, error) {
+	rows, err := q.db.QueryContext(ctx, claimOutboxEmails, arg.NextAttemptAt, arg.MaxAttempts, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []EmailOutbox{}
+	for rows.Next() {
+		var i EmailOutbox
+		if err := rows.Scan(
+			&i.ID,
+			&i.ToAddress,
+			&i.Subject,
+			&i.Body,
+			&i.Attempts,
+			&i.LastError,
+			&i.NextAttemptAt,
+			&i.SentAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createOutboxEmail = `-- name: CreateOutboxEmail :one
+INSERT INTO email_outbox (
+    to_address,
+    subject,
+    body
+) VALUES (
+    $1, $2, $3
+) RETURNING id, to_address, subject, body, attempts, last_error, next_attempt_at, sent_at, created_at
+`
+
+type CreateOutboxEmailParams struct {
+	ToAddress string `json:"to_address"`
+	Subject   string `json:"subject"`
+	Body      string `json:"body"`
+}
+
+func (q *Queries) CreateOutboxEmail(ctx context.Context, arg CreateOutboxEmailParams) (EmailOutbox, error) {
+	row := q.db.QueryRowContext(ctx, createOutboxEmail, arg.ToAddress, arg.Subject, arg.Body)
+	var i EmailOutbox
+	err := row.Scan(
+		&i.ID,
+		&i.ToAddress,
+		&i.Subject,
+		&i.Body,
+		&i.Attempts,
+		&i.LastError,
+		&i.NextAttemptAt,
+		&i.SentAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteFinishedOutboxEmails = `-- name: DeleteFinishedOutboxEmails :execrows
+DELETE FROM email_outbox
+WHERE created_at < $1
+  AND (sent_at IS NOT NULL OR attempts >= $2)
+`
+
+type DeleteFinishedOutboxEmailsParams struct {
+	CreatedBefore time.Time `json:"created_before"`
+	MaxAttempts   int32     `json:"max_attempts"`
+}
+
+func (q *Queries) DeleteFinishedOutboxEmails(ctx context.Context, arg DeleteFinishedOutboxEmailsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFinishedOutboxEmails, arg.CreatedBefore, arg.MaxAttempts)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const markOutboxEmailFailed = `-- name: MarkOutboxEmailFailed :exec
+UPDATE email_outbox
+SET last_error = $2
+WHERE id = $1
+`
+
+type MarkOutboxEmailFailedParams struct {
+	ID        int64  `json:"id"`
+	LastError string `json:"last_error"`
+}
+
+func (q *Queries) MarkOutboxEmailFailed(ctx context.Context, arg MarkOutboxEmailFailedParams) error {
+	_, err := q.db.ExecContext(ctx, markOutboxEmailFailed, arg.ID, arg.LastError)
+	return err
+}
+
+const markOutboxEmailSent = `-- name: MarkOutboxEmailSent :exec
+UPDATE email_outbox
+SET sent_at = now(),
+    last_error = '',
+    body = ''
+WHERE id = $1
+`
+
+// the body is cleared, it may carry a secret like a reset token
+func (q *Queries) MarkOutboxEmailSent(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, markOutboxEmailSent, id)
+	return err
+}
@@ -0,0 +1,67 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// Domain errors of the store. Errors of database/sql and the postgres driver are translated
+// to them once at the store boundary, callers never need to look at driver errors.
+var (
+	ErrNotFound          = errors.New("record not found")
+	ErrConflict          = errors.New("record conflicts with an existing one")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrCurrencyMismatch  = errors.New("accounts have different currencies")
+	ErrSerialization     = errors.New("transaction conflicted with a concurrent one")
+)
+
+// balanceCheckConstraint is the check constraint that keeps balances above their overdraft limit and legacy debt
+const balanceCheckConstraint = "accounts_balance_check"
+
+// Error is a driver error translated to the domain error Kind.
+// errors.Is and errors.As match both the kind and the driver error.
+type Error struct {
+	Kind  error
+	Cause error
+}
+
+func (e *Error) Error() string {
+	return e.Kind.Error()
+}
+
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Cause}
+}
+
+// translateError translates an error of database/sql or the postgres driver to a domain error,
+// other errors are returned unchanged
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var translated *Error
+	if errors.As(err, &translated) {
+		return err
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return &Error{Kind: ErrNotFound, Cause: err}
+	}
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	switch pqErr.Code.Name() {
+	case "unique_violation", "foreign_key_violation":
+		return &Error{Kind: ErrConflict, Cause: err}
+	case "check_violation":
+		if pqErr.Constraint == balanceCheckConstraint {
+			return &Error{Kind: ErrInsufficientFunds, Cause: err}
+		}
+	case "serialization_failure", "deadlock_detected":
+		return &Error{Kind: ErrSerialization, Cause: err}
+	}
+	return err
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranslateError(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		kind error
+	}{
+		{name: "NoRows", err: sql.ErrNoRows, kind: ErrNotFound},
+		{name: "UniqueViolation", err: &pq.Error{Code: "23505"}, kind: ErrConflict},
+		{name: "ForeignKeyViolation", err: &pq.Error{Code: "23503"}, kind: ErrConflict},
+		{name: "BalanceCheck", err: &pq.Error{Code: "23514", Constraint: balanceCheckConstraint}, kind: ErrInsufficientFunds},
+		{name: "SerializationFailure", err: &pq.Error{Code: "40001"}, kind: ErrSerialization},
+		{name: "DeadlockDetected", err: &pq.Error{Code: "40P01"}, kind: ErrSerialization},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			translated := translateError(c.err)
+			assert.ErrorIs(t, translated, c.kind)
+			// the driver error stays available
+			assert.ErrorIs(t, translated, c.err)
+			assert.Equal(t, c.kind.Error(), translated.Error())
+			// translating twice changes nothing
+			assert.Equal(t, translated, translateError(translated))
+		})
+	}
+
+	assert.NoError(t, translateError(nil))
+	other := errors.New("connection refused")
+	assert.Equal(t, other, translateError(other))
+	otherCheck := &pq.Error{Code: "23514", Constraint: "accounts_overdraft_limit_check"}
+	assert.Equal(t, error(otherCheck), translateError(otherCheck))
+}
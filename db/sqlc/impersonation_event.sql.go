@@ -0,0 +1,101 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: impersonation_event.sql
+
+package db
+
+import (
+	"context"
+)
+
+const createImpersonationEvent = `-- name: CreateImpersonationEvent :one
+INSERT INTO impersonation_events (
+    actor,
+    username,
+    method,
+    path,
+    status_code,
+    client_ip
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+) RETURNING id, actor, username, method, path, status_code, client_ip, created_at
+`
+
+type CreateImpersonationEventParams struct {
+	Actor      string `json:"actor"`
+	Username   string `json:"username"`
+	Method     string `json:"method"`
+	Path       string `json:"path"`
+	StatusCode int32  `json:"status_code"`
+	ClientIp   string `json:"client_ip"`
+}
+
+func (q *Queries) CreateImpersonationEvent(ctx context.Context, arg CreateImpersonationEventParams) (ImpersonationEvent, error) {
+	row := q.db.QueryRowContext(ctx, createImpersonationEvent,
+		arg.Actor,
+		arg.Username,
+		arg.Method,
+		arg.Path,
+		arg.StatusCode,
+		arg.ClientIp,
+	)
+	var i ImpersonationEvent
+	err := row.Scan(
+		&i.ID,
+		&i.Actor,
+		&i.Username,
+		&i.Method,
+		&i.Path,
+		&i.StatusCode,
+		&i.ClientIp,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listImpersonationEvents = `-- name: ListImpersonationEvents :many
+SELECT id, actor, username, method, path, status_code, client_ip, created_at FROM impersonation_events
+WHERE username = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2
+OFFSET $3
+`
+
+type ListImpersonationEventsParams struct {
+	Username string `json:"username"`
+	Limit    int32  `json:"limit"`
+	Offset   int32  `json:"offset"`
+}
+
+func (q *Queries) ListImpersonationEvents(ctx context.Context, arg ListImpersonationEventsParams) ([]ImpersonationEvent, error) {
+	rows, err := q.db.QueryContext(ctx, listImpersonationEvents, arg.Username, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ImpersonationEvent{}
+	for rows.Next() {
+		var i ImpersonationEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.Actor,
+			&i.Username,
+			&i.Method,
+			&i.Path,
+			&i.StatusCode,
+			&i.ClientIp,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func _createImpersonationEvent(t *testing.T, actor, username, path string) ImpersonationEvent {
+	arg := CreateImpersonationEventParams{
+		Actor:      actor,
+		Username:   username,
+		Method:     http.MethodGet,
+		Path:       path,
+		StatusCode: http.StatusOK,
+		ClientIp:   "127.0.0.1",
+	}
+
+	event, err := testQueries.CreateImpersonationEvent(context.Background(), arg)
+	assert.NoError(t, err)
+	assert.NotZero(t, event.ID)
+	assert.Equal(t, arg.Actor, event.Actor)
+	assert.Equal(t, arg.Username, event.Username)
+	assert.Equal(t, arg.Method, event.Method)
+	assert.Equal(t, arg.Path, event.Path)
+	assert.Equal(t, arg.StatusCode, event.StatusCode)
+	assert.Equal(t, arg.ClientIp, event.ClientIp)
+	assert.NotZero(t, event.CreatedAt)
+
+	return event
+}
+
+func TestListImpersonationEvents(t *testing.T) {
+	actor := util.RandomOwner()
+	username := util.RandomOwner()
+	first := _createImpersonationEvent(t, actor, username, "/accounts")
+	second := _createImpersonationEvent(t, actor, username, "/account/1")
+	_createImpersonationEvent(t, actor, util.RandomOwner(), "/accounts")
+
+	events, err := testQueries.ListImpersonationEvents(context.Background(), ListImpersonationEventsParams{
+		Username: username,
+		Limit:    5,
+		Offset:   0,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, events, 2)
+	assert.Equal(t, second.ID, events[0].ID)
+	assert.Equal(t, first.ID, events[1].ID)
+}
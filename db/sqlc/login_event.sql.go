@@ -0,0 +1,125 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: login_event.sql
+
+package db
+
+import (
+	"context"
+)
+
+const createLoginEvent = `-- name: CreateLoginEvent :one
+INSERT INTO login_events (
+    username,
+    client_ip,
+    user_agent,
+    outcome
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING id, username, client_ip, user_agent, outcome, created_at
+`
+
+type CreateLoginEventParams struct {
+	Username  string `json:"username"`
+	ClientIp  string `json:"client_ip"`
+	UserAgent string `json:"user_agent"`
+	Outcome   string `json:"outcome"`
+}
+
+func (q *Queries) CreateLoginEvent(ctx context.Context, arg CreateLoginEventParams) (LoginEvent, error) {
+	row := q.db.QueryRowContext(ctx, createLoginEvent,
+		arg.Username,
+		arg.ClientIp,
+		arg.UserAgent,
+		arg.Outcome,
+	)
+	var i LoginEvent
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.ClientIp,
+		&i.UserAgent,
+		&i.Outcome,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getLoginHistory = `-- name: GetLoginHistory :one
+SELECT
+    EXISTS (
+        SELECT 1 FROM login_events AS logins
+        WHERE logins.username = $1
+          AND logins.outcome = 'success'
+    ) AS has_logged_in,
+    EXISTS (
+        SELECT 1 FROM login_events AS device_logins
+        WHERE device_logins.username = $1
+          AND device_logins.outcome = 'success'
+          AND device_logins.client_ip = $2
+          AND device_logins.user_agent = $3
+    ) AS is_known_device
+`
+
+type GetLoginHistoryParams struct {
+	Username  string `json:"username"`
+	ClientIp  string `json:"client_ip"`
+	UserAgent string `json:"user_agent"`
+}
+
+type GetLoginHistoryRow struct {
+	HasLoggedIn   bool `json:"has_logged_in"`
+	IsKnownDevice bool `json:"is_known_device"`
+}
+
+func (q *Queries) GetLoginHistory(ctx context.Context, arg GetLoginHistoryParams) (GetLoginHistoryRow, error) {
+	row := q.db.QueryRowContext(ctx, getLoginHistory, arg.Username, arg.ClientIp, arg.UserAgent)
+	var i GetLoginHistoryRow
+	err := row.Scan(&i.HasLoggedIn, &i.IsKnownDevice)
+	return i, err
+}
+
+const listLoginEvents = `-- name: ListLoginEvents :many
+SELECT id, username, client_ip, user_agent, outcome, created_at FROM login_events
+WHERE username = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2
+OFFSET $3
+`
+
+type ListLoginEventsParams struct {
+	Username string `json:"username"`
+	Limit    int32  `json:"limit"`
+	Offset   int32  `json:"offset"`
+}
+
+func (q *Queries) ListLoginEvents(ctx context.Context, arg ListLoginEventsParams) ([]LoginEvent, error) {
+	rows, err := q.db.QueryContext(ctx, listLoginEvents, arg.Username, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []LoginEvent{}
+	for rows.Next() {
+		var i LoginEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.ClientIp,
+			&i.UserAgent,
+			&i.Outcome,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func _createLoginEvent(t *testing.T, username, clientIP, outcome string) LoginEvent {
+	arg := CreateLoginEventParams{
+		Username:  username,
+		ClientIp:  clientIP,
+		UserAgent: "Mozilla/5.0",
+		Outcome:   outcome,
+	}
+
+	event, err := testQueries.CreateLoginEvent(context.Background(), arg)
+	assert.NoError(t, err)
+	assert.NotZero(t, event.ID)
+	assert.Equal(t, arg.Username, event.Username)
+	assert.Equal(t, arg.ClientIp, event.ClientIp)
+	assert.Equal(t, arg.UserAgent, event.UserAgent)
+	assert.Equal(t, arg.Outcome, event.Outcome)
+	assert.NotZero(t, event.CreatedAt)
+
+	return event
+}
+
+func TestListLoginEvents(t *testing.T) {
+	username := util.RandomOwner()
+	failed := _createLoginEvent(t, username, "127.0.0.1", "bad_password")
+	succeeded := _createLoginEvent(t, username, "127.0.0.1", "success")
+
+	events, err := testQueries.ListLoginEvents(context.Background(), ListLoginEventsParams{
+		Username: username,
+		Limit:    5,
+		Offset:   0,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, events, 2)
+	assert.Equal(t, succeeded.ID, events[0].ID)
+	assert.Equal(t, failed.ID, events[1].ID)
+}
+
+func TestGetLoginHistory(t *testing.T) {
+	username := util.RandomOwner()
+	arg := GetLoginHistoryParams{
+		Username:  username,
+		ClientIp:  "127.0.0.1",
+		UserAgent: "Mozilla/5.0",
+	}
+
+	history, err := testQueries.GetLoginHistory(context.Background(), arg)
+	assert.NoError(t, err)
+	assert.False(t, history.HasLoggedIn)
+	assert.False(t, history.IsKnownDevice)
+
+	// failed attempts do not make a device known
+	_createLoginEvent(t, username, "127.0.0.1", "bad_password")
+	_createLoginEvent(t, username, "10.0.0.1", "success")
+
+	history, err = testQueries.GetLoginHistory(context.Background(), arg)
+	assert.NoError(t, err)
+	assert.True(t, history.HasLoggedIn)
+	assert.False(t, history.IsKnownDevice)
+
+	_createLoginEvent(t, username, "127.0.0.1", "success")
+
+	history, err = testQueries.GetLoginHistory(context.Background(), arg)
+	assert.NoError(t, err)
+	assert.True(t, history.HasLoggedIn)
+	assert.True(t, history.IsKnownDevice)
+}
@@ -0,0 +1,221 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: login_throttle.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const blockLoginThrottle = `-- name: BlockLoginThrottle :exec
+UPDATE login_throttles
+SET blocked_until = $3
+WHERE kind = $1 AND key = $2
+`
+
+type BlockLoginThrottleParams struct {
+	Kind         string    `json:"kind"`
+	Key          string    `json:"key"`
+	BlockedUntil time.Time `json:"blocked_until"`
+}
+
+func (q *Queries) BlockLoginThrottle(ctx context.Context, arg BlockLoginThrottleParams) error {
+	_, err := q.db.ExecContext(ctx, blockLoginThrottle, arg.Kind, arg.Key, arg.BlockedUntil)
+	return err
+}
+
+const createLoginLockout = `-- name: CreateLoginLockout :one
+INSERT INTO login_lockouts (
+    username,
+    client_ip,
+    failed_attempts,
+    locked_until
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING id, username, client_ip, failed_attempts, locked_until, unlocked_by, unlocked_at, created_at
+`
+
+type CreateLoginLockoutParams struct {
+	Username       string    `json:"username"`
+	ClientIp       string    `json:"client_ip"`
+	FailedAttempts int32     `json:"failed_attempts"`
+	LockedUntil    time.Time `json:"locked_until"`
+}
+
+func (q *Queries) CreateLoginLockout(ctx context.Context, arg CreateLoginLockoutParams) (LoginLockout, error) {
+	row := q.db.QueryRowContext(ctx, createLoginLockout,
+		arg.Username,
+		arg.ClientIp,
+		arg.FailedAttempts,
+		arg.LockedUntil,
+	)
+	var i LoginLockout
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.ClientIp,
+		&i.FailedAttempts,
+		&i.LockedUntil,
+		&i.UnlockedBy,
+		&i.UnlockedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteLoginThrottle = `-- name: DeleteLoginThrottle :exec
+DELETE FROM login_throttles
+WHERE kind = $1 AND key = $2
+`
+
+type DeleteLoginThrottleParams struct {
+	Kind string `json:"kind"`
+	Key  string `json:"key"`
+}
+
+func (q *Queries) DeleteLoginThrottle(ctx context.Context, arg DeleteLoginThrottleParams) error {
+	_, err := q.db.ExecContext(ctx, deleteLoginThrottle, arg.Kind, arg.Key)
+	return err
+}
+
+const deleteStaleLoginThrottles = `-- name: DeleteStaleLoginThrottles :execrows
+DELETE FROM login_throttles
+WHERE last_failed_at < $1 AND blocked_until < now()
+`
+
+func (q *Queries) DeleteStaleLoginThrottles(ctx context.Context, lastFailedAt time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteStaleLoginThrottles, lastFailedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getLoginThrottle = `-- name: GetLoginThrottle :one
+SELECT kind, key, failed_attempts, blocked_until, last_failed_at FROM login_throttles
+WHERE kind = $1 AND key = $2 LIMIT 1
+`
+
+type GetLoginThrottleParams struct {
+	Kind string `json:"kind"`
+	Key  string `json:"key"`
+}
+
+func (q *Queries) GetLoginThrottle(ctx context.Context, arg GetLoginThrottleParams) (LoginThrottle, error) {
+	row := q.db.QueryRowContext(ctx, getLoginThrottle, arg.Kind, arg.Key)
+	var i LoginThrottle
+	err := row.Scan(
+		&i.Kind,
+		&i.Key,
+		&i.FailedAttempts,
+		&i.BlockedUntil,
+		&i.LastFailedAt,
+	)
+	return i, err
+}
+
+const listLoginLockouts = `-- name: ListLoginLockouts :many
+SELECT id, username, client_ip, failed_attempts, locked_until, unlocked_by, unlocked_at, created_at FROM login_lockouts
+WHERE username = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3
+`
+
+type ListLoginLockoutsParams struct {
+	Username string `json:"username"`
+	Limit    int32  `json:"limit"`
+	Offset   int32  `json:"offset"`
+}
+
+func (q *Queries) ListLoginLockouts(ctx context.Context, arg ListLoginLockoutsParams) ([]LoginLockout, error) {
+	rows, err := q.db.QueryContext(ctx, listLoginLockouts, arg.Username, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []LoginLockout{}
+	for rows.Next() {
+		var i LoginLockout
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.ClientIp,
+			&i.FailedAttempts,
+			&i.LockedUntil,
+			&i.UnlockedBy,
+			&i.UnlockedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const recordLoginFailure = `-- name: RecordLoginFailure :one
+INSERT INTO login_throttles (
+    kind,
+    key,
+    failed_attempts,
+    last_failed_at
+) VALUES (
+    $1, $2, 1, now()
+) ON CONFLICT (kind, key) DO UPDATE
+SET failed_attempts = CASE
+        WHEN login_throttles.last_failed_at < $3 THEN 1
+        ELSE login_throttles.failed_attempts + 1
+    END,
+    last_failed_at = now()
+RETURNING kind, key, failed_attempts, blocked_until, last_failed_at
+`
+
+type RecordLoginFailureParams struct {
+	Kind        string    `json:"kind"`
+	Key         string    `json:"key"`
+	ResetBefore time.Time `json:"reset_before"`
+}
+
+func (q *Queries) RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (LoginThrottle, error) {
+	row := q.db.QueryRowContext(ctx, recordLoginFailure, arg.Kind, arg.Key, arg.ResetBefore)
+	var i LoginThrottle
+	err := row.Scan(
+		&i.Kind,
+		&i.Key,
+		&i.FailedAttempts,
+		&i.BlockedUntil,
+		&i.LastFailedAt,
+	)
+	return i, err
+}
+
+const unlockLoginLockouts = `-- name: UnlockLoginLockouts :execrows
+UPDATE login_lockouts
+SET unlocked_by = $2,
+    unlocked_at = now()
+WHERE username = $1 AND unlocked_at IS NULL
+`
+
+type UnlockLoginLockoutsParams struct {
+	Username   string         `json:"username"`
+	UnlockedBy sql.NullString `json:"unlocked_by"`
+}
+
+func (q *Queries) UnlockLoginLockouts(ctx context.Context, arg UnlockLoginLockoutsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, unlockLoginLockouts, arg.Username, arg.UnlockedBy)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordLoginFailure(t *testing.T) {
+	arg := RecordLoginFailureParams{
+		Kind:        LoginThrottleUsername,
+		Key:         util.RandomOwner(),
+		ResetBefore: time.Now().Add(-time.Minute),
+	}
+
+	throttle, err := testQueries.RecordLoginFailure(context.Background(), arg)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), throttle.FailedAttempts)
+
+	throttle, err = testQueries.RecordLoginFailure(context.Background(), arg)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), throttle.FailedAttempts)
+
+	// failures before reset_before are forgotten
+	arg.ResetBefore = time.Now().Add(time.Minute)
+	throttle, err = testQueries.RecordLoginFailure(context.Background(), arg)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), throttle.FailedAttempts)
+
+	blockedUntil := time.Now().Add(time.Minute)
+	err = testQueries.BlockLoginThrottle(context.Background(), BlockLoginThrottleParams{
+		Kind:         arg.Kind,
+		Key:          arg.Key,
+		BlockedUntil: blockedUntil,
+	})
+	assert.NoError(t, err)
+
+	throttle, err = testQueries.GetLoginThrottle(context.Background(), GetLoginThrottleParams{Kind: arg.Kind, Key: arg.Key})
+	assert.NoError(t, err)
+	assert.WithinDuration(t, blockedUntil, throttle.BlockedUntil, time.Second)
+}
+
+func TestUnlockUserTx(t *testing.T) {
+	store := NewStore(testDB, testTxRetry)
+	username := util.RandomOwner()
+
+	_, err := testQueries.RecordLoginFailure(context.Background(), RecordLoginFailureParams{
+		Kind:        LoginThrottleUsername,
+		Key:         username,
+		ResetBefore: time.Now().Add(-time.Minute),
+	})
+	assert.NoError(t, err)
+
+	lockout, err := testQueries.CreateLoginLockout(context.Background(), CreateLoginLockoutParams{
+		Username:       username,
+		ClientIp:       "127.0.0.1",
+		FailedAttempts: 5,
+		LockedUntil:    time.Now().Add(time.Minute),
+	})
+	assert.NoError(t, err)
+	assert.False(t, lockout.UnlockedAt.Valid)
+
+	result, err := store.UnlockUserTx(context.Background(), UnlockUserTxParams{
+		Username:   username,
+		UnlockedBy: "admin",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), result.UnlockedLockouts)
+
+	_, err = testQueries.GetLoginThrottle(context.Background(), GetLoginThrottleParams{Kind: LoginThrottleUsername, Key: username})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	lockouts, err := testQueries.ListLoginLockouts(context.Background(), ListLoginLockoutsParams{
+		Username: username,
+		Limit:    5,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, lockouts, 1)
+	assert.Equal(t, "admin", lockouts[0].UnlockedBy.String)
+	assert.True(t, lockouts[0].UnlockedAt.Valid)
+}
@@ -0,0 +1,72 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: magic_link.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createMagicLink = `-- name: CreateMagicLink :one
+INSERT INTO magic_links (
+    id,
+    username,
+    expires_at
+) VALUES (
+    $1, $2, $3
+) RETURNING id, username, expires_at, used_at, created_at
+`
+
+type CreateMagicLinkParams struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreateMagicLink(ctx context.Context, arg CreateMagicLinkParams) (MagicLink, error) {
+	row := q.db.QueryRowContext(ctx, createMagicLink, arg.ID, arg.Username, arg.ExpiresAt)
+	var i MagicLink
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteUnusedMagicLinks = `-- name: DeleteUnusedMagicLinks :exec
+DELETE FROM magic_links
+WHERE username = $1 AND used_at IS NULL
+`
+
+func (q *Queries) DeleteUnusedMagicLinks(ctx context.Context, username string) error {
+	_, err := q.db.ExecContext(ctx, deleteUnusedMagicLinks, username)
+	return err
+}
+
+const useMagicLink = `-- name: UseMagicLink :one
+UPDATE magic_links
+SET used_at = now()
+WHERE id = $1 AND used_at IS NULL AND expires_at > now()
+RETURNING id, username, expires_at, used_at, created_at
+`
+
+func (q *Queries) UseMagicLink(ctx context.Context, id uuid.UUID) (MagicLink, error) {
+	row := q.db.QueryRowContext(ctx, useMagicLink, id)
+	var i MagicLink
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMagicLinkTx(t *testing.T) {
+	store := NewStore(testDB, testTxRetry)
+	user := _createUser(t)
+
+	createMagicLink := func() CreateMagicLinkTxParams {
+		arg := CreateMagicLinkTxParams{
+			ID:        uuid.New(),
+			Username:  user.Username,
+			ExpiresAt: time.Now().Add(time.Minute),
+			Email: CreateOutboxEmailParams{
+				ToAddress: user.Email,
+				Subject:   "Your login link",
+				Body:      util.RandomString(20),
+			},
+		}
+		created, err := store.CreateMagicLinkTx(context.Background(), arg)
+		assert.NoError(t, err)
+		assert.Equal(t, arg.ID, created.MagicLink.ID)
+		assert.Equal(t, user.Username, created.MagicLink.Username)
+		assert.False(t, created.MagicLink.UsedAt.Valid)
+		assert.Equal(t, user.Email, created.Email.ToAddress)
+		return arg
+	}
+	first := createMagicLink()
+	second := createMagicLink()
+
+	// a new link replaces the unused ones
+	_, err := testQueries.UseMagicLink(context.Background(), first.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	used, err := testQueries.UseMagicLink(context.Background(), second.ID)
+	assert.NoError(t, err)
+	assert.True(t, used.UsedAt.Valid)
+
+	// a link can be used once
+	_, err = testQueries.UseMagicLink(context.Background(), second.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestUseExpiredMagicLink(t *testing.T) {
+	user := _createUser(t)
+	link, err := testQueries.CreateMagicLink(context.Background(), CreateMagicLinkParams{
+		ID:        uuid.New(),
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(-time.Second),
+	})
+	assert.NoError(t, err)
+
+	_, err = testQueries.UseMagicLink(context.Background(), link.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
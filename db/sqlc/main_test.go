@@ -14,6 +14,7 @@ import (
 var (
 	testQueries *Queries
 	testDB      *sql.DB
+	testTxRetry TxRetryConfig
 )
 
 func TestMain(m *testing.M) {
@@ -29,6 +30,10 @@ func TestMain(m *testing.M) {
 	}
 
 	testQueries = New(testDB)
+	testTxRetry = TxRetryConfig{
+		MaxRetries: conf.DBTxMaxRetries,
+		Backoff:    conf.DBTxRetryBackoff,
+	}
 
 	os.Exit(m.Run())
 }
@@ -5,7 +5,11 @@
 package db
 
 import (
+	"database/sql"
+	"encoding/json"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type Account struct {
@@ -14,6 +18,37 @@ type Account struct {
 	Balance   int64     `json:"balance"`
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
+	// how far the balance may go below zero
+	OverdraftLimit int64 `json:"overdraft_limit"`
+	// debt beyond the overdraft limit from before the limits existed, it can be paid back but not borrowed again
+	LegacyDebt int64 `json:"legacy_debt"`
+}
+
+type ApiKey struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	// public part of the key used for lookup
+	KeyPrefix string `json:"key_prefix"`
+	// sha256 of the full key
+	HashedKey string    `json:"hashed_key"`
+	Scopes    []string  `json:"scopes"`
+	IsRevoked bool      `json:"is_revoked"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type EmailOutbox struct {
+	ID        int64  `json:"id"`
+	ToAddress string `json:"to_address"`
+	Subject   string `json:"subject"`
+	// cleared once sent, it may carry a secret like a reset token
+	Body      string `json:"body"`
+	Attempts  int32  `json:"attempts"`
+	LastError string `json:"last_error"`
+	// a claimed email is retried after this time unless it was sent
+	NextAttemptAt time.Time    `json:"next_attempt_at"`
+	SentAt        sql.NullTime `json:"sent_at"`
+	CreatedAt     time.Time    `json:"created_at"`
 }
 
 type Entry struct {
@@ -24,6 +59,107 @@ type Entry struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+type ImpersonationEvent struct {
+	ID int64 `json:"id"`
+	// the admin acting as username
+	Actor      string    `json:"actor"`
+	Username   string    `json:"username"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	StatusCode int32     `json:"status_code"`
+	ClientIp   string    `json:"client_ip"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type LoginChallenge struct {
+	ID       uuid.UUID `json:"id"`
+	Username string    `json:"username"`
+	// sha256 of the challenge token
+	HashedToken string    `json:"hashed_token"`
+	Attempts    int32     `json:"attempts"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type LoginEvent struct {
+	ID int64 `json:"id"`
+	// not a foreign key, attempts on unknown usernames are recorded too
+	Username  string `json:"username"`
+	ClientIp  string `json:"client_ip"`
+	UserAgent string `json:"user_agent"`
+	// success, bad_password, unknown_user, throttled, totp_required, bad_second_factor, bad_passkey or bad_magic_link
+	Outcome   string    `json:"outcome"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type LoginLockout struct {
+	ID int64 `json:"id"`
+	// not a foreign key, unknown usernames are locked out too
+	Username       string         `json:"username"`
+	ClientIp       string         `json:"client_ip"`
+	FailedAttempts int32          `json:"failed_attempts"`
+	LockedUntil    time.Time      `json:"locked_until"`
+	UnlockedBy     sql.NullString `json:"unlocked_by"`
+	UnlockedAt     sql.NullTime   `json:"unlocked_at"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+type LoginThrottle struct {
+	// username or ip
+	Kind           string    `json:"kind"`
+	Key            string    `json:"key"`
+	FailedAttempts int32     `json:"failed_attempts"`
+	BlockedUntil   time.Time `json:"blocked_until"`
+	LastFailedAt   time.Time `json:"last_failed_at"`
+}
+
+type MagicLink struct {
+	// id of the signed token in the link
+	ID        uuid.UUID    `json:"id"`
+	Username  string       `json:"username"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type PasswordResetToken struct {
+	ID       uuid.UUID `json:"id"`
+	Username string    `json:"username"`
+	// sha256 of the reset token
+	HashedToken string       `json:"hashed_token"`
+	ExpiresAt   time.Time    `json:"expires_at"`
+	UsedAt      sql.NullTime `json:"used_at"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+type RecoveryCode struct {
+	ID         int64     `json:"id"`
+	Username   string    `json:"username"`
+	HashedCode string    `json:"hashed_code"`
+	IsUsed     bool      `json:"is_used"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type RevokedToken struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expires_at"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+type Session struct {
+	ID           uuid.UUID `json:"id"`
+	Username     string    `json:"username"`
+	RefreshToken string    `json:"refresh_token"`
+	UserAgent    string    `json:"user_agent"`
+	ClientIp     string    `json:"client_ip"`
+	IsBlocked    bool      `json:"is_blocked"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+	// name of the device given by the client at login
+	DeviceName string `json:"device_name"`
+}
+
 type Transfer struct {
 	ID            int64 `json:"id"`
 	FromAccountID int64 `json:"from_account_id"`
@@ -40,4 +176,69 @@ type User struct {
 	Email             string    `json:"email"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
+	Role              string    `json:"role"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
+}
+
+type UserLogout struct {
+	Username string `json:"username"`
+	// tokens issued before this time are revoked
+	LoggedOutAt time.Time `json:"logged_out_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type UserTotp struct {
+	Username    string `json:"username"`
+	Secret      string `json:"secret"`
+	IsConfirmed bool   `json:"is_confirmed"`
+	// time step of the last accepted code, stops replays
+	LastUsedStep int64     `json:"last_used_step"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type VerifyEmail struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// the address the code was sent to, it only verifies that address
+	Email string `json:"email"`
+	// sha256 of the secret code
+	HashedCode string    `json:"hashed_code"`
+	IsUsed     bool      `json:"is_used"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type WebauthnCeremony struct {
+	ID uuid.UUID `json:"id"`
+	// registration or login
+	Kind string `json:"kind"`
+	// empty for a login, the authenticator names the user
+	Username    string          `json:"username"`
+	SessionData json.RawMessage `json:"session_data"`
+	ExpiresAt   time.Time       `json:"expires_at"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+type WebauthnCredential struct {
+	// credential id chosen by the authenticator
+	ID              []byte   `json:"id"`
+	Username        string   `json:"username"`
+	Name            string   `json:"name"`
+	PublicKey       []byte   `json:"public_key"`
+	AttestationType string   `json:"attestation_type"`
+	Transports      []string `json:"transports"`
+	Aaguid          []byte   `json:"aaguid"`
+	// signature counter of the authenticator, a counter going back means a cloned authenticator
+	SignCount      int64     `json:"sign_count"`
+	BackupEligible bool      `json:"backup_eligible"`
+	BackupState    bool      `json:"backup_state"`
+	LastUsedAt     time.Time `json:"last_used_at"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type WebauthnUserHandle struct {
+	// opaque WebAuthn user handle, random bytes that say nothing about the user
+	Handle    []byte    `json:"handle"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
 }
@@ -0,0 +1,100 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: password_reset.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createPasswordResetToken = `-- name: CreatePasswordResetToken :one
+INSERT INTO password_reset_tokens (
+    id,
+    username,
+    hashed_token,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING id, username, hashed_token, expires_at, used_at, created_at
+`
+
+type CreatePasswordResetTokenParams struct {
+	ID          uuid.UUID `json:"id"`
+	Username    string    `json:"username"`
+	HashedToken string    `json:"hashed_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) (PasswordResetToken, error) {
+	row := q.db.QueryRowContext(ctx, createPasswordResetToken,
+		arg.ID,
+		arg.Username,
+		arg.HashedToken,
+		arg.ExpiresAt,
+	)
+	var i PasswordResetToken
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.HashedToken,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteUnusedPasswordResetTokens = `-- name: DeleteUnusedPasswordResetTokens :exec
+DELETE FROM password_reset_tokens
+WHERE username = $1 AND used_at IS NULL
+`
+
+func (q *Queries) DeleteUnusedPasswordResetTokens(ctx context.Context, username string) error {
+	_, err := q.db.ExecContext(ctx, deleteUnusedPasswordResetTokens, username)
+	return err
+}
+
+const getPasswordResetTokenByHash = `-- name: GetPasswordResetTokenByHash :one
+SELECT id, username, hashed_token, expires_at, used_at, created_at FROM password_reset_tokens
+WHERE hashed_token = $1 LIMIT 1
+`
+
+func (q *Queries) GetPasswordResetTokenByHash(ctx context.Context, hashedToken string) (PasswordResetToken, error) {
+	row := q.db.QueryRowContext(ctx, getPasswordResetTokenByHash, hashedToken)
+	var i PasswordResetToken
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.HashedToken,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const usePasswordResetToken = `-- name: UsePasswordResetToken :one
+UPDATE password_reset_tokens
+SET used_at = now()
+WHERE id = $1 AND used_at IS NULL AND expires_at > now()
+RETURNING id, username, hashed_token, expires_at, used_at, created_at
+`
+
+func (q *Queries) UsePasswordResetToken(ctx context.Context, id uuid.UUID) (PasswordResetToken, error) {
+	row := q.db.QueryRowContext(ctx, usePasswordResetToken, id)
+	var i PasswordResetToken
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.HashedToken,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordResetTx(t *testing.T) {
+	store := NewStore(testDB, testTxRetry)
+	user := _createUser(t)
+
+	arg := CreatePasswordResetTxParams{
+		ID:          uuid.New(),
+		Username:    user.Username,
+		HashedToken: util.HashSecret(util.RandomString(32)),
+		ExpiresAt:   time.Now().Add(time.Minute),
+		Email: CreateOutboxEmailParams{
+			ToAddress: user.Email,
+			Subject:   "Reset your password",
+			Body:      util.RandomString(20),
+		},
+	}
+	created, err := store.CreatePasswordResetTx(context.Background(), arg)
+	assert.NoError(t, err)
+	assert.Equal(t, arg.ID, created.ResetToken.ID)
+	assert.False(t, created.ResetToken.UsedAt.Valid)
+	assert.Equal(t, user.Email, created.Email.ToAddress)
+	assert.False(t, created.Email.SentAt.Valid)
+
+	hashedPassword, err := util.HashedPassword(util.RandomString(8))
+	assert.NoError(t, err)
+	resetArg := ResetPasswordTxParams{
+		TokenID:           arg.ID,
+		HashedPassword:    hashedPassword,
+		PasswordChangedAt: time.Now(),
+	}
+	result, err := store.ResetPasswordTx(context.Background(), resetArg)
+	assert.NoError(t, err)
+	assert.Equal(t, hashedPassword, result.User.HashedPassword)
+
+	// reset tokens are single use
+	_, err = store.ResetPasswordTx(context.Background(), resetArg)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestClaimOutboxEmails(t *testing.T) {
+	email, err := testQueries.CreateOutboxEmail(context.Background(), CreateOutboxEmailParams{
+		ToAddress: util.RandomOwner() + "@example.com",
+		Subject:   "hi",
+		Body:      "hello",
+	})
+	assert.NoError(t, err)
+
+	claimed, err := testQueries.ClaimOutboxEmails(context.Background(), ClaimOutboxEmailsParams{
+		NextAttemptAt: time.Now().Add(time.Minute),
+		MaxAttempts:   5,
+		BatchSize:     1000,
+	})
+	assert.NoError(t, err)
+
+	var found bool
+	for _, c := range claimed {
+		if c.ID == email.ID {
+			found = true
+			assert.Equal(t, int32(1), c.Attempts)
+		}
+	}
+	assert.True(t, found)
+
+	// claimed emails are not handed out again before next_attempt_at
+	claimed, err = testQueries.ClaimOutboxEmails(context.Background(), ClaimOutboxEmailsParams{
+		NextAttemptAt: time.Now().Add(time.Minute),
+		MaxAttempts:   5,
+		BatchSize:     1000,
+	})
+	assert.NoError(t, err)
+	for _, c := range claimed {
+		assert.NotEqual(t, email.ID, c.ID)
+	}
+
+	err = testQueries.MarkOutboxEmailSent(context.Background(), email.ID)
+	assert.NoError(t, err)
+
+	// the body of a sent email is cleared, it may carry a secret
+	var body string
+	err = testDB.QueryRowContext(context.Background(), "SELECT body FROM email_outbox WHERE id = $1", email.ID).Scan(&body)
+	assert.NoError(t, err)
+	assert.Empty(t, body)
+
+	deleted, err := testQueries.DeleteFinishedOutboxEmails(context.Background(), DeleteFinishedOutboxEmailsParams{
+		CreatedBefore: time.Now().Add(time.Minute),
+		MaxAttempts:   5,
+	})
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, deleted, int64(1))
+}
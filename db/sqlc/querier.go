@@ -6,24 +6,104 @@ package db
 
 import (
 	"context"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 type Querier interface {
+	// legacy_debt never grows, it is lowered to the debt beyond the overdraft limit
 	AddAccountBalance(ctx context.Context, arg AddAccountBalanceParams) (Account, error)
+	BlockLoginThrottle(ctx context.Context, arg BlockLoginThrottleParams) error
+	BlockSession(ctx context.Context, id uuid.UUID) error
+	BlockUserSession(ctx context.Context, arg BlockUserSessionParams) (Session, error)
+	BlockUserSessions(ctx context.Context, username string) error
+	ClaimOutboxEmails(ctx context.Context, arg ClaimOutboxEmailsParams) ([]EmailOutbox, error)
+	// only the pending secret the user proved a code for is enabled, a re-enroll in between leaves no row
+	ConfirmUserTOTP(ctx context.Context, arg ConfirmUserTOTPParams) (UserTotp, error)
+	CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	CreateImpersonationEvent(ctx context.Context, arg CreateImpersonationEventParams) (ImpersonationEvent, error)
+	CreateLoginChallenge(ctx context.Context, arg CreateLoginChallengeParams) (LoginChallenge, error)
+	CreateLoginEvent(ctx context.Context, arg CreateLoginEventParams) (LoginEvent, error)
+	CreateLoginLockout(ctx context.Context, arg CreateLoginLockoutParams) (LoginLockout, error)
+	CreateMagicLink(ctx context.Context, arg CreateMagicLinkParams) (MagicLink, error)
+	CreateOutboxEmail(ctx context.Context, arg CreateOutboxEmailParams) (EmailOutbox, error)
+	CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) (PasswordResetToken, error)
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) (RecoveryCode, error)
+	CreateRevokedToken(ctx context.Context, arg CreateRevokedTokenParams) error
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailParams) (VerifyEmail, error)
+	CreateWebAuthnCeremony(ctx context.Context, arg CreateWebAuthnCeremonyParams) (WebauthnCeremony, error)
+	CreateWebAuthnCredential(ctx context.Context, arg CreateWebAuthnCredentialParams) (WebauthnCredential, error)
+	// a user that has a handle keeps it
+	CreateWebAuthnUserHandle(ctx context.Context, arg CreateWebAuthnUserHandleParams) (WebauthnUserHandle, error)
 	DeleteAccount(ctx context.Context, id int64) error
+	DeleteExpiredRevokedTokens(ctx context.Context) (int64, error)
+	DeleteExpiredUserLogouts(ctx context.Context) (int64, error)
+	DeleteExpiredWebAuthnCeremonies(ctx context.Context) (int64, error)
+	DeleteFinishedOutboxEmails(ctx context.Context, arg DeleteFinishedOutboxEmailsParams) (int64, error)
+	DeleteLoginChallenge(ctx context.Context, id uuid.UUID) error
+	DeleteLoginThrottle(ctx context.Context, arg DeleteLoginThrottleParams) error
+	DeleteRecoveryCodes(ctx context.Context, username string) error
+	DeleteStaleLoginThrottles(ctx context.Context, lastFailedAt time.Time) (int64, error)
+	DeleteUnusedMagicLinks(ctx context.Context, username string) error
+	DeleteUnusedPasswordResetTokens(ctx context.Context, username string) error
+	DeleteWebAuthnCredential(ctx context.Context, arg DeleteWebAuthnCredentialParams) (WebauthnCredential, error)
+	GetAPIKeyByPrefix(ctx context.Context, keyPrefix string) (ApiKey, error)
 	GetAccount(ctx context.Context, id int64) (Account, error)
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetLoginChallengeByHash(ctx context.Context, hashedToken string) (LoginChallenge, error)
+	GetLoginHistory(ctx context.Context, arg GetLoginHistoryParams) (GetLoginHistoryRow, error)
+	GetLoginThrottle(ctx context.Context, arg GetLoginThrottleParams) (LoginThrottle, error)
+	GetPasswordResetTokenByHash(ctx context.Context, hashedToken string) (PasswordResetToken, error)
+	GetSession(ctx context.Context, id uuid.UUID) (Session, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
 	GetUser(ctx context.Context, username string) (User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserTOTP(ctx context.Context, username string) (UserTotp, error)
+	GetVerifyEmailByHash(ctx context.Context, hashedCode string) (VerifyEmail, error)
+	GetWebAuthnUserHandle(ctx context.Context, handle []byte) (WebauthnUserHandle, error)
+	IncrementLoginChallengeAttempts(ctx context.Context, id uuid.UUID) (LoginChallenge, error)
+	ListAPIKeys(ctx context.Context, username string) ([]ApiKey, error)
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
+	ListBlockedSessions(ctx context.Context) ([]ListBlockedSessionsRow, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
+	ListImpersonationEvents(ctx context.Context, arg ListImpersonationEventsParams) ([]ImpersonationEvent, error)
+	ListLoginEvents(ctx context.Context, arg ListLoginEventsParams) ([]LoginEvent, error)
+	ListLoginLockouts(ctx context.Context, arg ListLoginLockoutsParams) ([]LoginLockout, error)
+	ListPasswordChanges(ctx context.Context, passwordChangedAt time.Time) ([]ListPasswordChangesRow, error)
+	ListRevokedTokens(ctx context.Context) ([]RevokedToken, error)
 	ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error)
+	ListUserLogouts(ctx context.Context) ([]UserLogout, error)
+	ListUserSessions(ctx context.Context, username string) ([]Session, error)
+	ListWebAuthnCredentials(ctx context.Context, username string) ([]WebauthnCredential, error)
+	MarkOutboxEmailFailed(ctx context.Context, arg MarkOutboxEmailFailedParams) error
+	// the body is cleared, it may carry a secret like a reset token
+	MarkOutboxEmailSent(ctx context.Context, id int64) error
+	MarkUserEmailVerified(ctx context.Context, arg MarkUserEmailVerifiedParams) (User, error)
+	RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (LoginThrottle, error)
+	RehashUserPassword(ctx context.Context, arg RehashUserPasswordParams) (int64, error)
+	RevokeAPIKey(ctx context.Context, arg RevokeAPIKeyParams) (ApiKey, error)
+	TakeWebAuthnCeremony(ctx context.Context, arg TakeWebAuthnCeremonyParams) (WebauthnCeremony, error)
+	UnlockLoginLockouts(ctx context.Context, arg UnlockLoginLockoutsParams) (int64, error)
+	// legacy_debt never grows, it is lowered to the debt beyond the overdraft limit
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
+	// legacy_debt never grows, it is lowered to the debt beyond the new overdraft limit
+	UpdateAccountOverdraftLimit(ctx context.Context, arg UpdateAccountOverdraftLimitParams) (Account, error)
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error)
+	UpsertUserLogout(ctx context.Context, arg UpsertUserLogoutParams) (UserLogout, error)
+	UpsertUserTOTP(ctx context.Context, arg UpsertUserTOTPParams) (UserTotp, error)
+	UseMagicLink(ctx context.Context, id uuid.UUID) (MagicLink, error)
+	UsePasswordResetToken(ctx context.Context, id uuid.UUID) (PasswordResetToken, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (RecoveryCode, error)
+	UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (UserTotp, error)
+	UseVerifyEmail(ctx context.Context, id int64) (VerifyEmail, error)
+	UseWebAuthnCredential(ctx context.Context, arg UseWebAuthnCredentialParams) (WebauthnCredential, error)
 }
 
 var _ Querier = (*Queries)(nil)
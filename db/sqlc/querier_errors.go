@@ -0,0 +1,435 @@
+// Code generated by go run ./db/gen. DO NOT EDIT.
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// errQuerier runs the queries of Queries and translates their errors to domain errors, see translateError
+type errQuerier struct {
+	q *Queries
+}
+
+var _ Querier = errQuerier{}
+
+func (e errQuerier) AddAccountBalance(ctx context.Context, arg AddAccountBalanceParams) (Account, error) {
+	result, err := e.q.AddAccountBalance(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) BlockLoginThrottle(ctx context.Context, arg BlockLoginThrottleParams) error {
+	return translateError(e.q.BlockLoginThrottle(ctx, arg))
+}
+
+func (e errQuerier) BlockSession(ctx context.Context, id uuid.UUID) error {
+	return translateError(e.q.BlockSession(ctx, id))
+}
+
+func (e errQuerier) BlockUserSession(ctx context.Context, arg BlockUserSessionParams) (Session, error) {
+	result, err := e.q.BlockUserSession(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) BlockUserSessions(ctx context.Context, username string) error {
+	return translateError(e.q.BlockUserSessions(ctx, username))
+}
+
+func (e errQuerier) ClaimOutboxEmails(ctx context.Context, arg ClaimOutboxEmailsParams) ([]EmailOutbox, error) {
+	result, err := e.q.ClaimOutboxEmails(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) ConfirmUserTOTP(ctx context.Context, arg ConfirmUserTOTPParams) (UserTotp, error) {
+	result, err := e.q.ConfirmUserTOTP(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error) {
+	result, err := e.q.CreateAPIKey(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error) {
+	result, err := e.q.CreateAccount(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error) {
+	result, err := e.q.CreateEntry(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) CreateImpersonationEvent(ctx context.Context, arg CreateImpersonationEventParams) (ImpersonationEvent, error) {
+	result, err := e.q.CreateImpersonationEvent(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) CreateLoginChallenge(ctx context.Context, arg CreateLoginChallengeParams) (LoginChallenge, error) {
+	result, err := e.q.CreateLoginChallenge(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) CreateLoginEvent(ctx context.Context, arg CreateLoginEventParams) (LoginEvent, error) {
+	result, err := e.q.CreateLoginEvent(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) CreateLoginLockout(ctx context.Context, arg CreateLoginLockoutParams) (LoginLockout, error) {
+	result, err := e.q.CreateLoginLockout(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) CreateMagicLink(ctx context.Context, arg CreateMagicLinkParams) (MagicLink, error) {
+	result, err := e.q.CreateMagicLink(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) CreateOutboxEmail(ctx context.Context, arg CreateOutboxEmailParams) (EmailOutbox, error) {
+	result, err := e.q.CreateOutboxEmail(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) (PasswordResetToken, error) {
+	result, err := e.q.CreatePasswordResetToken(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) (RecoveryCode, error) {
+	result, err := e.q.CreateRecoveryCode(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) CreateRevokedToken(ctx context.Context, arg CreateRevokedTokenParams) error {
+	return translateError(e.q.CreateRevokedToken(ctx, arg))
+}
+
+func (e errQuerier) CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error) {
+	result, err := e.q.CreateSession(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
+	result, err := e.q.CreateTransfer(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	result, err := e.q.CreateUser(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailParams) (VerifyEmail, error) {
+	result, err := e.q.CreateVerifyEmail(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) CreateWebAuthnCeremony(ctx context.Context, arg CreateWebAuthnCeremonyParams) (WebauthnCeremony, error) {
+	result, err := e.q.CreateWebAuthnCeremony(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) CreateWebAuthnCredential(ctx context.Context, arg CreateWebAuthnCredentialParams) (WebauthnCredential, error) {
+	result, err := e.q.CreateWebAuthnCredential(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) CreateWebAuthnUserHandle(ctx context.Context, arg CreateWebAuthnUserHandleParams) (WebauthnUserHandle, error) {
+	result, err := e.q.CreateWebAuthnUserHandle(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) DeleteAccount(ctx context.Context, id int64) error {
+	return translateError(e.q.DeleteAccount(ctx, id))
+}
+
+func (e errQuerier) DeleteExpiredRevokedTokens(ctx context.Context) (int64, error) {
+	result, err := e.q.DeleteExpiredRevokedTokens(ctx)
+	return result, translateError(err)
+}
+
+func (e errQuerier) DeleteExpiredUserLogouts(ctx context.Context) (int64, error) {
+	result, err := e.q.DeleteExpiredUserLogouts(ctx)
+	return result, translateError(err)
+}
+
+func (e errQuerier) DeleteExpiredWebAuthnCeremonies(ctx context.Context) (int64, error) {
+	result, err := e.q.DeleteExpiredWebAuthnCeremonies(ctx)
+	return result, translateError(err)
+}
+
+func (e errQuerier) DeleteFinishedOutboxEmails(ctx context.Context, arg DeleteFinishedOutboxEmailsParams) (int64, error) {
+	result, err := e.q.DeleteFinishedOutboxEmails(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) DeleteLoginChallenge(ctx context.Context, id uuid.UUID) error {
+	return translateError(e.q.DeleteLoginChallenge(ctx, id))
+}
+
+func (e errQuerier) DeleteLoginThrottle(ctx context.Context, arg DeleteLoginThrottleParams) error {
+	return translateError(e.q.DeleteLoginThrottle(ctx, arg))
+}
+
+func (e errQuerier) DeleteRecoveryCodes(ctx context.Context, username string) error {
+	return translateError(e.q.DeleteRecoveryCodes(ctx, username))
+}
+
+func (e errQuerier) DeleteStaleLoginThrottles(ctx context.Context, lastFailedAt time.Time) (int64, error) {
+	result, err := e.q.DeleteStaleLoginThrottles(ctx, lastFailedAt)
+	return result, translateError(err)
+}
+
+func (e errQuerier) DeleteUnusedMagicLinks(ctx context.Context, username string) error {
+	return translateError(e.q.DeleteUnusedMagicLinks(ctx, username))
+}
+
+func (e errQuerier) DeleteUnusedPasswordResetTokens(ctx context.Context, username string) error {
+	return translateError(e.q.DeleteUnusedPasswordResetTokens(ctx, username))
+}
+
+func (e errQuerier) DeleteWebAuthnCredential(ctx context.Context, arg DeleteWebAuthnCredentialParams) (WebauthnCredential, error) {
+	result, err := e.q.DeleteWebAuthnCredential(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) GetAPIKeyByPrefix(ctx context.Context, keyPrefix string) (ApiKey, error) {
+	result, err := e.q.GetAPIKeyByPrefix(ctx, keyPrefix)
+	return result, translateError(err)
+}
+
+func (e errQuerier) GetAccount(ctx context.Context, id int64) (Account, error) {
+	result, err := e.q.GetAccount(ctx, id)
+	return result, translateError(err)
+}
+
+func (e errQuerier) GetAccountForUpdate(ctx context.Context, id int64) (Account, error) {
+	result, err := e.q.GetAccountForUpdate(ctx, id)
+	return result, translateError(err)
+}
+
+func (e errQuerier) GetEntry(ctx context.Context, id int64) (Entry, error) {
+	result, err := e.q.GetEntry(ctx, id)
+	return result, translateError(err)
+}
+
+func (e errQuerier) GetLoginChallengeByHash(ctx context.Context, hashedToken string) (LoginChallenge, error) {
+	result, err := e.q.GetLoginChallengeByHash(ctx, hashedToken)
+	return result, translateError(err)
+}
+
+func (e errQuerier) GetLoginHistory(ctx context.Context, arg GetLoginHistoryParams) (GetLoginHistoryRow, error) {
+	result, err := e.q.GetLoginHistory(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) GetLoginThrottle(ctx context.Context, arg GetLoginThrottleParams) (LoginThrottle, error) {
+	result, err := e.q.GetLoginThrottle(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) GetPasswordResetTokenByHash(ctx context.Context, hashedToken string) (PasswordResetToken, error) {
+	result, err := e.q.GetPasswordResetTokenByHash(ctx, hashedToken)
+	return result, translateError(err)
+}
+
+func (e errQuerier) GetSession(ctx context.Context, id uuid.UUID) (Session, error) {
+	result, err := e.q.GetSession(ctx, id)
+	return result, translateError(err)
+}
+
+func (e errQuerier) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
+	result, err := e.q.GetTransfer(ctx, id)
+	return result, translateError(err)
+}
+
+func (e errQuerier) GetUser(ctx context.Context, username string) (User, error) {
+	result, err := e.q.GetUser(ctx, username)
+	return result, translateError(err)
+}
+
+func (e errQuerier) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	result, err := e.q.GetUserByEmail(ctx, email)
+	return result, translateError(err)
+}
+
+func (e errQuerier) GetUserTOTP(ctx context.Context, username string) (UserTotp, error) {
+	result, err := e.q.GetUserTOTP(ctx, username)
+	return result, translateError(err)
+}
+
+func (e errQuerier) GetVerifyEmailByHash(ctx context.Context, hashedCode string) (VerifyEmail, error) {
+	result, err := e.q.GetVerifyEmailByHash(ctx, hashedCode)
+	return result, translateError(err)
+}
+
+func (e errQuerier) GetWebAuthnUserHandle(ctx context.Context, handle []byte) (WebauthnUserHandle, error) {
+	result, err := e.q.GetWebAuthnUserHandle(ctx, handle)
+	return result, translateError(err)
+}
+
+func (e errQuerier) IncrementLoginChallengeAttempts(ctx context.Context, id uuid.UUID) (LoginChallenge, error) {
+	result, err := e.q.IncrementLoginChallengeAttempts(ctx, id)
+	return result, translateError(err)
+}
+
+func (e errQuerier) ListAPIKeys(ctx context.Context, username string) ([]ApiKey, error) {
+	result, err := e.q.ListAPIKeys(ctx, username)
+	return result, translateError(err)
+}
+
+func (e errQuerier) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error) {
+	result, err := e.q.ListAccounts(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) ListBlockedSessions(ctx context.Context) ([]ListBlockedSessionsRow, error) {
+	result, err := e.q.ListBlockedSessions(ctx)
+	return result, translateError(err)
+}
+
+func (e errQuerier) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error) {
+	result, err := e.q.ListEntries(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) ListImpersonationEvents(ctx context.Context, arg ListImpersonationEventsParams) ([]ImpersonationEvent, error) {
+	result, err := e.q.ListImpersonationEvents(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) ListLoginEvents(ctx context.Context, arg ListLoginEventsParams) ([]LoginEvent, error) {
+	result, err := e.q.ListLoginEvents(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) ListLoginLockouts(ctx context.Context, arg ListLoginLockoutsParams) ([]LoginLockout, error) {
+	result, err := e.q.ListLoginLockouts(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) ListPasswordChanges(ctx context.Context, passwordChangedAt time.Time) ([]ListPasswordChangesRow, error) {
+	result, err := e.q.ListPasswordChanges(ctx, passwordChangedAt)
+	return result, translateError(err)
+}
+
+func (e errQuerier) ListRevokedTokens(ctx context.Context) ([]RevokedToken, error) {
+	result, err := e.q.ListRevokedTokens(ctx)
+	return result, translateError(err)
+}
+
+func (e errQuerier) ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error) {
+	result, err := e.q.ListTransfers(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) ListUserLogouts(ctx context.Context) ([]UserLogout, error) {
+	result, err := e.q.ListUserLogouts(ctx)
+	return result, translateError(err)
+}
+
+func (e errQuerier) ListUserSessions(ctx context.Context, username string) ([]Session, error) {
+	result, err := e.q.ListUserSessions(ctx, username)
+	return result, translateError(err)
+}
+
+func (e errQuerier) ListWebAuthnCredentials(ctx context.Context, username string) ([]WebauthnCredential, error) {
+	result, err := e.q.ListWebAuthnCredentials(ctx, username)
+	return result, translateError(err)
+}
+
+func (e errQuerier) MarkOutboxEmailFailed(ctx context.Context, arg MarkOutboxEmailFailedParams) error {
+	return translateError(e.q.MarkOutboxEmailFailed(ctx, arg))
+}
+
+func (e errQuerier) MarkOutboxEmailSent(ctx context.Context, id int64) error {
+	return translateError(e.q.MarkOutboxEmailSent(ctx, id))
+}
+
+func (e errQuerier) MarkUserEmailVerified(ctx context.Context, arg MarkUserEmailVerifiedParams) (User, error) {
+	result, err := e.q.MarkUserEmailVerified(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (LoginThrottle, error) {
+	result, err := e.q.RecordLoginFailure(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) RehashUserPassword(ctx context.Context, arg RehashUserPasswordParams) (int64, error) {
+	result, err := e.q.RehashUserPassword(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) RevokeAPIKey(ctx context.Context, arg RevokeAPIKeyParams) (ApiKey, error) {
+	result, err := e.q.RevokeAPIKey(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) TakeWebAuthnCeremony(ctx context.Context, arg TakeWebAuthnCeremonyParams) (WebauthnCeremony, error) {
+	result, err := e.q.TakeWebAuthnCeremony(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) UnlockLoginLockouts(ctx context.Context, arg UnlockLoginLockoutsParams) (int64, error) {
+	result, err := e.q.UnlockLoginLockouts(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error) {
+	result, err := e.q.UpdateAccount(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) UpdateAccountOverdraftLimit(ctx context.Context, arg UpdateAccountOverdraftLimitParams) (Account, error) {
+	result, err := e.q.UpdateAccountOverdraftLimit(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error) {
+	result, err := e.q.UpdateUserPassword(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) UpsertUserLogout(ctx context.Context, arg UpsertUserLogoutParams) (UserLogout, error) {
+	result, err := e.q.UpsertUserLogout(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) UpsertUserTOTP(ctx context.Context, arg UpsertUserTOTPParams) (UserTotp, error) {
+	result, err := e.q.UpsertUserTOTP(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) UseMagicLink(ctx context.Context, id uuid.UUID) (MagicLink, error) {
+	result, err := e.q.UseMagicLink(ctx, id)
+	return result, translateError(err)
+}
+
+func (e errQuerier) UsePasswordResetToken(ctx context.Context, id uuid.UUID) (PasswordResetToken, error) {
+	result, err := e.q.UsePasswordResetToken(ctx, id)
+	return result, translateError(err)
+}
+
+func (e errQuerier) UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (RecoveryCode, error) {
+	result, err := e.q.UseRecoveryCode(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (UserTotp, error) {
+	result, err := e.q.UseTOTPStep(ctx, arg)
+	return result, translateError(err)
+}
+
+func (e errQuerier) UseVerifyEmail(ctx context.Context, id int64) (VerifyEmail, error) {
+	result, err := e.q.UseVerifyEmail(ctx, id)
+	return result, translateError(err)
+}
+
+func (e errQuerier) UseWebAuthnCredential(ctx context.Context, arg UseWebAuthnCredentialParams) (WebauthnCredential, error) {
+	result, err := e.q.UseWebAuthnCredential(ctx, arg)
+	return result, translateError(err)
+}
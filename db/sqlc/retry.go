@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+)
+
+// TxRetryConfig is how often and how patiently execTx reruns a transaction
+// that failed with ErrSerialization, a serialization failure or a deadlock
+type TxRetryConfig struct {
+	// MaxRetries is the number of reruns after the first attempt, 0 disables retrying
+	MaxRetries int
+	// Backoff is the base delay, the n-th retry waits a random duration up to Backoff * 2^(n-1)
+	Backoff time.Duration
+}
+
+// TxStats counts the retries of the transactions of a store since it was created
+type TxStats struct {
+	// Retries is the number of reruns of failed transactions
+	Retries uint64 `json:"retries"`
+	// Recovered is the number of transactions that succeeded after at least one retry
+	Recovered uint64 `json:"recovered"`
+	// Exhausted is the number of transactions that still failed after MaxRetries retries
+	Exhausted uint64 `json:"exhausted"`
+}
+
+type txCounters struct {
+	retries   atomic.Uint64
+	recovered atomic.Uint64
+	exhausted atomic.Uint64
+}
+
+func (c *txCounters) stats() TxStats {
+	return TxStats{
+		Retries:   c.retries.Load(),
+		Recovered: c.recovered.Load(),
+		Exhausted: c.exhausted.Load(),
+	}
+}
+
+// retryTx calls run until it succeeds, fails with an error other than ErrSerialization,
+// the retries of config are used up or ctx is done
+func retryTx(ctx context.Context, config TxRetryConfig, counters *txCounters, run func() error) error {
+	for retry := 0; ; retry++ {
+		err := run()
+		if err == nil {
+			if retry > 0 {
+				counters.recovered.Add(1)
+			}
+			return nil
+		}
+		if !errors.Is(err, ErrSerialization) {
+			return err
+		}
+		if retry >= config.MaxRetries {
+			if config.MaxRetries > 0 {
+				counters.exhausted.Add(1)
+			}
+			return err
+		}
+
+		timer := time.NewTimer(retryBackoff(config.Backoff, retry))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		counters.retries.Add(1)
+	}
+}
+
+// retryBackoff is the delay before retry n+1, a random duration up to backoff * 2^n.
+// The jitter keeps transactions that conflicted once from conflicting again in lockstep.
+func retryBackoff(backoff time.Duration, n int) time.Duration {
+	ceiling := backoff << min(n, 16)
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling + 1)
+}
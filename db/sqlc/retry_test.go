@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryTx(t *testing.T) {
+	serializationErr := translateError(&pq.Error{Code: "40001"})
+	otherErr := errors.New("boom")
+	config := TxRetryConfig{MaxRetries: 3, Backoff: time.Millisecond}
+
+	testCases := []struct {
+		name     string
+		config   TxRetryConfig
+		errs     []error
+		calls    int
+		stats    TxStats
+		checkErr func(t *testing.T, err error)
+	}{
+		{
+			name:   "OK",
+			config: config,
+			errs:   []error{nil},
+			calls:  1,
+			checkErr: func(t *testing.T, err error) {
+				assert.NoError(t, err)
+			},
+		},
+		{
+			name:   "Recovered",
+			config: config,
+			errs:   []error{serializationErr, serializationErr, nil},
+			calls:  3,
+			stats:  TxStats{Retries: 2, Recovered: 1},
+			checkErr: func(t *testing.T, err error) {
+				assert.NoError(t, err)
+			},
+		},
+		{
+			name:   "Exhausted",
+			config: config,
+			errs:   []error{serializationErr, serializationErr, serializationErr, serializationErr},
+			calls:  4,
+			stats:  TxStats{Retries: 3, Exhausted: 1},
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, ErrSerialization)
+			},
+		},
+		{
+			name:   "OtherError",
+			config: config,
+			errs:   []error{otherErr},
+			calls:  1,
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, otherErr)
+			},
+		},
+		{
+			name:   "Disabled",
+			config: TxRetryConfig{},
+			errs:   []error{serializationErr},
+			calls:  1,
+			checkErr: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, ErrSerialization)
+			},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			var counters txCounters
+			calls := 0
+			err := retryTx(context.Background(), c.config, &counters, func() error {
+				err := c.errs[calls]
+				calls++
+				return err
+			})
+			c.checkErr(t, err)
+			assert.Equal(t, c.calls, calls)
+			assert.Equal(t, c.stats, counters.stats())
+		})
+	}
+}
+
+func TestRetryTxCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var counters txCounters
+	calls := 0
+	err := retryTx(ctx, TxRetryConfig{MaxRetries: 3, Backoff: time.Hour}, &counters, func() error {
+		calls++
+		return &Error{Kind: ErrSerialization}
+	})
+	assert.ErrorIs(t, err, ErrSerialization)
+	assert.Equal(t, 1, calls)
+	assert.Zero(t, counters.stats().Retries)
+}
+
+func TestRetryBackoff(t *testing.T) {
+	for n := 0; n < 5; n++ {
+		backoff := retryBackoff(10*time.Millisecond, n)
+		assert.GreaterOrEqual(t, backoff, time.Duration(0))
+		assert.LessOrEqual(t, backoff, 10*time.Millisecond<<n)
+	}
+	assert.Zero(t, retryBackoff(0, 3))
+}
@@ -0,0 +1,147 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: revoked_token.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createRevokedToken = `-- name: CreateRevokedToken :exec
+INSERT INTO revoked_tokens (
+    id,
+    username,
+    expires_at
+) VALUES (
+    $1, $2, $3
+) ON CONFLICT (id) DO NOTHING
+`
+
+type CreateRevokedTokenParams struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreateRevokedToken(ctx context.Context, arg CreateRevokedTokenParams) error {
+	_, err := q.db.ExecContext(ctx, createRevokedToken, arg.ID, arg.Username, arg.ExpiresAt)
+	return err
+}
+
+const deleteExpiredRevokedTokens = `-- name: DeleteExpiredRevokedTokens :execrows
+DELETE FROM revoked_tokens
+WHERE expires_at <= now()
+`
+
+func (q *Queries) DeleteExpiredRevokedTokens(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteExpiredRevokedTokens)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteExpiredUserLogouts = `-- name: DeleteExpiredUserLogouts :execrows
+DELETE FROM user_logouts
+WHERE expires_at <= now()
+`
+
+func (q *Queries) DeleteExpiredUserLogouts(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteExpiredUserLogouts)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const listRevokedTokens = `-- name: ListRevokedTokens :many
+SELECT id, username, expires_at, revoked_at FROM revoked_tokens
+WHERE expires_at > now()
+`
+
+func (q *Queries) ListRevokedTokens(ctx context.Context) ([]RevokedToken, error) {
+	rows, err := q.db.QueryContext(ctx, listRevokedTokens)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []RevokedToken{}
+	for rows.Next() {
+		var i RevokedToken
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.ExpiresAt,
+			&i.RevokedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserLogouts = `-- name: ListUserLogouts :many
+SELECT username, logged_out_at, expires_at FROM user_logouts
+WHERE expires_at > now()
+`
+
+func (q *Queries) ListUserLogouts(ctx context.Context) ([]UserLogout, error) {
+	rows, err := q.db.QueryContext(ctx, listUserLogouts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []UserLogout{}
+	for rows.Next() {
+		var i UserLogout
+		if err := rows.Scan(&i.Username, &i.LoggedOutAt, &i.ExpiresAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const upsertUserLogout = `-- name: UpsertUserLogout :one
+INSERT INTO user_logouts (
+    username,
+    logged_out_at,
+    expires_at
+) VALUES (
+    $1, $2, $3
+) ON CONFLICT (username) DO UPDATE
+SET logged_out_at = EXCLUDED.logged_out_at,
+    expires_at = EXCLUDED.expires_at
+RETURNING username, logged_out_at, expires_at
+`
+
+type UpsertUserLogoutParams struct {
+	Username    string    `json:"username"`
+	LoggedOutAt time.Time `json:"logged_out_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+func (q *Queries) UpsertUserLogout(ctx context.Context, arg UpsertUserLogoutParams) (UserLogout, error) {
+	row := q.db.QueryRowContext(ctx, upsertUserLogout, arg.Username, arg.LoggedOutAt, arg.ExpiresAt)
+	var i UserLogout
+	err := row.Scan(&i.Username, &i.LoggedOutAt, &i.ExpiresAt)
+	return i, err
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevokedTokens(t *testing.T) {
+	user := _createUser(t)
+
+	active := CreateRevokedTokenParams{
+		ID:        uuid.New(),
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	expired := CreateRevokedTokenParams{
+		ID:        uuid.New(),
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(-time.Hour),
+	}
+
+	assert.NoError(t, testQueries.CreateRevokedToken(context.Background(), active))
+	assert.NoError(t, testQueries.CreateRevokedToken(context.Background(), expired))
+	// revoking twice is a no-op
+	assert.NoError(t, testQueries.CreateRevokedToken(context.Background(), active))
+
+	tokens, err := testQueries.ListRevokedTokens(context.Background())
+	assert.NoError(t, err)
+	ids := make(map[uuid.UUID]bool)
+	for _, token := range tokens {
+		ids[token.ID] = true
+	}
+	assert.True(t, ids[active.ID])
+	assert.False(t, ids[expired.ID])
+
+	rows, err := testQueries.DeleteExpiredRevokedTokens(context.Background())
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, rows, int64(1))
+}
+
+func TestUpsertUserLogout(t *testing.T) {
+	user := _createUser(t)
+
+	arg := UpsertUserLogoutParams{
+		Username:    user.Username,
+		LoggedOutAt: time.Now(),
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+	logout1, err := testQueries.UpsertUserLogout(context.Background(), arg)
+	assert.NoError(t, err)
+	assert.Equal(t, arg.Username, logout1.Username)
+	assert.WithinDuration(t, arg.LoggedOutAt, logout1.LoggedOutAt, time.Second)
+
+	arg.LoggedOutAt = arg.LoggedOutAt.Add(time.Minute)
+	logout2, err := testQueries.UpsertUserLogout(context.Background(), arg)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, arg.LoggedOutAt, logout2.LoggedOutAt, time.Second)
+
+	logouts, err := testQueries.ListUserLogouts(context.Background())
+	assert.NoError(t, err)
+	assert.NotEmpty(t, logouts)
+}
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func _createSession(t *testing.T) Session {
+	user := _createUser(t)
+	arg := CreateSessionParams{
+		ID:           uuid.New(),
+		Username:     user.Username,
+		RefreshToken: util.RandomString(32),
+		DeviceName:   util.RandomString(8),
+		UserAgent:    util.RandomString(10),
+		ClientIp:     "127.0.0.1",
+		IsBlocked:    false,
+		ExpiresAt:    time.Now().Add(time.Hour),
+	}
+
+	session, err := testQueries.CreateSession(context.Background(), arg)
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, session)
+
+	assert.Equal(t, arg.ID, session.ID)
+	assert.Equal(t, arg.Username, session.Username)
+	assert.Equal(t, arg.RefreshToken, session.RefreshToken)
+	assert.Equal(t, arg.DeviceName, session.DeviceName)
+	assert.Equal(t, arg.UserAgent, session.UserAgent)
+	assert.Equal(t, arg.ClientIp, session.ClientIp)
+	assert.False(t, session.IsBlocked)
+	assert.WithinDuration(t, arg.ExpiresAt, session.ExpiresAt, time.Second)
+	assert.NotZero(t, session.CreatedAt)
+
+	return session
+}
+
+func TestCreateSession(t *testing.T) {
+	_createSession(t)
+}
+
+func TestGetSession(t *testing.T) {
+	session1 := _createSession(t)
+
+	session2, err := testQueries.GetSession(context.Background(), session1.ID)
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, session2)
+	assert.Equal(t, session1.ID, session2.ID)
+	assert.Equal(t, session1.Username, session2.Username)
+	assert.Equal(t, session1.RefreshToken, session2.RefreshToken)
+	assert.WithinDuration(t, session1.ExpiresAt, session2.ExpiresAt, time.Second)
+}
+
+func TestListUserSessions(t *testing.T) {
+	session1 := _createSession(t)
+	arg := CreateSessionParams{
+		ID:           uuid.New(),
+		Username:     session1.Username,
+		RefreshToken: util.RandomString(32),
+		DeviceName:   util.RandomString(8),
+		UserAgent:    util.RandomString(10),
+		ClientIp:     "127.0.0.1",
+		ExpiresAt:    time.Now().Add(time.Hour),
+	}
+	session2, err := testQueries.CreateSession(context.Background(), arg)
+	assert.NoError(t, err)
+
+	err = testQueries.BlockSession(context.Background(), session1.ID)
+	assert.NoError(t, err)
+
+	sessions, err := testQueries.ListUserSessions(context.Background(), session1.Username)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+	assert.Equal(t, session2.ID, sessions[0].ID)
+
+	blockedSessions, err := testQueries.ListBlockedSessions(context.Background())
+	assert.NoError(t, err)
+	var found bool
+	for _, blocked := range blockedSessions {
+		found = found || blocked.ID == session1.ID
+		assert.NotEqual(t, session2.ID, blocked.ID)
+	}
+	assert.True(t, found)
+}
+
+func TestBlockUserSession(t *testing.T) {
+	session1 := _createSession(t)
+
+	// sessions of other users cannot be blocked
+	_, err := testQueries.BlockUserSession(context.Background(), BlockUserSessionParams{
+		ID:       session1.ID,
+		Username: util.RandomOwner(),
+	})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	session2, err := testQueries.BlockUserSession(context.Background(), BlockUserSessionParams{
+		ID:       session1.ID,
+		Username: session1.Username,
+	})
+	assert.NoError(t, err)
+	assert.True(t, session2.IsBlocked)
+}
@@ -4,42 +4,70 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"log"
 )
 
 type Store interface {
 	Querier
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	ConfirmTOTPTx(ctx context.Context, arg ConfirmTOTPTxParams) (ConfirmTOTPTxResult, error)
+	UnlockUserTx(ctx context.Context, arg UnlockUserTxParams) (UnlockUserTxResult, error)
+	ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (ChangePasswordTxResult, error)
+	CreatePasswordResetTx(ctx context.Context, arg CreatePasswordResetTxParams) (CreatePasswordResetTxResult, error)
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (ResetPasswordTxResult, error)
+	CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error)
+	VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error)
+	CreateMagicLinkTx(ctx context.Context, arg CreateMagicLinkTxParams) (CreateMagicLinkTxResult, error)
+	TxStats() TxStats
 }
 
-// SQLStore provides all functions to execute db queries & transactions
+// SQLStore provides all functions to execute db queries & transactions.
+// Its errors are domain errors, driver errors are translated by errQuerier and execTx.
 type SQLStore struct {
-	*Queries
-	db *sql.DB
+	errQuerier
+	db       *sql.DB
+	retry    TxRetryConfig
+	counters txCounters
 }
 
-func NewStore(db *sql.DB) Store {
+// NewStore creates a store whose transactions are retried according to retry
+func NewStore(db *sql.DB, retry TxRetryConfig) Store {
 	return &SQLStore{
-		db:      db,
-		Queries: New(db),
+		db:         db,
+		errQuerier: errQuerier{q: New(db)},
+		retry:      retry,
 	}
 }
 
-func (store *SQLStore) execTx(ctx context.Context, fn func(queries *Queries) error) error {
-	tx, err := store.db.BeginTx(ctx, nil) // default level: RC
+// TxStats returns the retry counts of the transactions of the store
+func (store *SQLStore) TxStats() TxStats {
+	return store.counters.stats()
+}
+
+// execTx runs fn in a transaction of the isolation level and translates the error of fn or the commit to a domain error.
+// sql.LevelDefault is the READ COMMITTED level of postgres.
+// A transaction failing with a serialization failure or a deadlock is rerun from the start as configured by TxRetryConfig,
+// so fn must not have effects outside of queries.
+func (store *SQLStore) execTx(ctx context.Context, isolation sql.IsolationLevel, fn func(queries *Queries) error) error {
+	return retryTx(ctx, store.retry, &store.counters, func() error {
+		return store.runTx(ctx, isolation, fn)
+	})
+}
+
+func (store *SQLStore) runTx(ctx context.Context, isolation sql.IsolationLevel, fn func(queries *Queries) error) error {
+	tx, err := store.db.BeginTx(ctx, &sql.TxOptions{Isolation: isolation})
 	if err != nil {
-		return err
+		return translateError(err)
 	}
 	q := New(tx)
 	err = fn(q)
 	if err != nil {
 		// rollback
 		if rbErr := tx.Rollback(); rbErr != nil {
-			return fmt.Errorf("tx error: %s, rb error: %s", err, rbErr)
+			return fmt.Errorf("tx error: %w, rb error: %s", translateError(err), rbErr)
 		}
-		return err
+		return translateError(err)
 	}
-	return tx.Commit()
+	return translateError(tx.Commit())
 }
 
 type TransferTxParams struct {
@@ -58,15 +86,30 @@ type TransferTxResult struct {
 
 /*
 TransferTx performs a money transfer one account to another
-1. creates a new transfers
-2. add account entries
-3. and update accounts' balance
-within a single database transaction
+1. locks both accounts and checks their currencies and the balance of the source
+2. creates a new transfers
+3. add account entries
+4. and update accounts' balance
+within a single database transaction.
+It fails with ErrCurrencyMismatch if the accounts have different currencies
+and with ErrInsufficientFunds if the source account would drop below its overdraft limit.
 */
 func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
 	var result TransferTxResult
 
-	err := store.execTx(ctx, func(queries *Queries) error {
+	// the accounts are locked, READ COMMITTED is enough
+	err := store.execTx(ctx, sql.LevelDefault, func(queries *Queries) error {
+		fromAccount, toAccount, err := lockTransferAccounts(ctx, queries, arg.FromAccountID, arg.ToAccountID)
+		if err != nil {
+			return err
+		}
+		if fromAccount.Currency != toAccount.Currency {
+			return ErrCurrencyMismatch
+		}
+		if fromAccount.Balance-arg.Amount < -fromAccount.OverdraftLimit {
+			return ErrInsufficientFunds
+		}
+
 		transfer, err := queries.CreateTransfer(ctx, CreateTransferParams{
 			FromAccountID: arg.FromAccountID,
 			ToAccountID:   arg.ToAccountID,
@@ -114,19 +157,40 @@ func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (Tr
 					Amount: -1 * arg.Amount,
 				})
 		}
+		if err != nil {
+			// a violated balance check constraint, which backs the check above, is translated to ErrInsufficientFunds
+			return err
+		}
 
 		result.Transfer = transfer
 		result.FromEntry = fromEntry
 		result.ToEntry = toEntry
 		return nil
 	})
-	if err != nil {
-		log.Fatal("transfer err:", err)
-	}
 
 	return result, err
 }
 
+// lockTransferAccounts locks both accounts of a transfer for the rest of the transaction.
+// Like the balance updates, it locks the lower id first so concurrent transfers cannot deadlock.
+func lockTransferAccounts(ctx context.Context, q *Queries, fromAccountID, toAccountID int64) (fromAccount, toAccount Account, err error) {
+	if fromAccountID < toAccountID {
+		fromAccount, err = q.GetAccountForUpdate(ctx, fromAccountID)
+		if err != nil {
+			return
+		}
+		toAccount, err = q.GetAccountForUpdate(ctx, toAccountID)
+		return
+	}
+
+	toAccount, err = q.GetAccountForUpdate(ctx, toAccountID)
+	if err != nil {
+		return
+	}
+	fromAccount, err = q.GetAccountForUpdate(ctx, fromAccountID)
+	return
+}
+
 func (store *SQLStore) addMoney(ctx context.Context, q *Queries, param1, param2 AddAccountBalanceParams) (account1, account2 Account, err error) {
 	account1, err = q.AddAccountBalance(ctx, param1)
 	if err != nil {
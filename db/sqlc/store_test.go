@@ -2,15 +2,26 @@ package db
 
 import (
 	"context"
+	"github.com/WanCodeBase/GinModule/util"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
 
+// _fundAccount sets the balance of account, so transfers out of it do not run out of funds
+func _fundAccount(t *testing.T, account Account, balance int64) Account {
+	account, err := testQueries.UpdateAccount(context.Background(), UpdateAccountParams{
+		ID:      account.ID,
+		Balance: balance,
+	})
+	assert.NoError(t, err)
+	return account
+}
+
 func TestStore_TransferTx(t *testing.T) {
-	store := NewStore(testDB)
+	store := NewStore(testDB, testTxRetry)
 
-	account1 := _createAccount(t)
-	account2 := _createAccount(t)
+	account1 := _fundAccount(t, _createAccount(t), 1000)
+	account2 := _createAccountIn(t, account1.Currency)
 
 	n := 5
 	amount := int64(10)
@@ -93,10 +104,10 @@ func TestStore_TransferTx(t *testing.T) {
 }
 
 func TestStore_TransferTxDeadlockTx(t *testing.T) {
-	store := NewStore(testDB)
+	store := NewStore(testDB, testTxRetry)
 
-	account1 := _createAccount(t)
-	account2 := _createAccount(t)
+	account1 := _fundAccount(t, _createAccount(t), 1000)
+	account2 := _fundAccount(t, _createAccountIn(t, account1.Currency), 1000)
 
 	n := 10
 	amount := int64(10)
@@ -135,3 +146,182 @@ func TestStore_TransferTxDeadlockTx(t *testing.T) {
 	assert.Equal(t, account1.Balance, updateAccount1.Balance)
 	assert.Equal(t, account2.Balance, updateAccount2.Balance)
 }
+
+func TestStore_TransferTxInsufficientFunds(t *testing.T) {
+	store := NewStore(testDB, testTxRetry)
+
+	account1 := _fundAccount(t, _createAccount(t), 100)
+	account2 := _createAccountIn(t, account1.Currency)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        101,
+	})
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+
+	// nothing of the transfer is kept
+	updateAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, account1.Balance, updateAccount1.Balance)
+
+	updateAccount2, err := testQueries.GetAccount(context.Background(), account2.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, account2.Balance, updateAccount2.Balance)
+
+	// the whole balance can be transferred
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        100,
+	})
+	assert.NoError(t, err)
+	assert.Zero(t, result.FromAccount.Balance)
+}
+
+func TestStore_TransferTxOverdraftLimit(t *testing.T) {
+	store := NewStore(testDB, testTxRetry)
+
+	account1 := _fundAccount(t, _createAccount(t), 100)
+	account2 := _createAccountIn(t, account1.Currency)
+	account1, err := testQueries.UpdateAccountOverdraftLimit(context.Background(), UpdateAccountOverdraftLimitParams{
+		ID:             account1.ID,
+		OverdraftLimit: 50,
+	})
+	assert.NoError(t, err)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        150,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-50), result.FromAccount.Balance)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        1,
+	})
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+
+	// the limit cannot be lowered below the debt of the account
+	_, err = store.UpdateAccountOverdraftLimit(context.Background(), UpdateAccountOverdraftLimitParams{
+		ID:             account1.ID,
+		OverdraftLimit: 0,
+	})
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+}
+
+func TestStore_LegacyOverdrawnAccount(t *testing.T) {
+	store := NewStore(testDB, testTxRetry)
+
+	// an account overdrawn before the overdraft limits existed, as migrated
+	account1 := _createAccount(t)
+	account2 := _fundAccount(t, _createAccountIn(t, account1.Currency), 100)
+	_, err := testDB.Exec("UPDATE accounts SET balance = -100, legacy_debt = 100 WHERE id = $1", account1.ID)
+	assert.NoError(t, err)
+
+	// the debt can be paid back in part
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account2.ID,
+		ToAccountID:   account1.ID,
+		Amount:        40,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-60), result.ToAccount.Balance)
+	assert.Equal(t, int64(60), result.ToAccount.LegacyDebt)
+
+	// but not borrowed again
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+	_, err = store.AddAccountBalance(context.Background(), AddAccountBalanceParams{
+		ID:     account1.ID,
+		Amount: -10,
+	})
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+
+	// a granted limit takes over the debt it covers, the legacy debt only keeps the rest
+	account, err := store.UpdateAccountOverdraftLimit(context.Background(), UpdateAccountOverdraftLimitParams{
+		ID:             account1.ID,
+		OverdraftLimit: 50,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), account.LegacyDebt)
+	_, err = store.AddAccountBalance(context.Background(), AddAccountBalanceParams{
+		ID:     account1.ID,
+		Amount: -1,
+	})
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+}
+
+func TestStore_TransferTxCurrencyMismatch(t *testing.T) {
+	store := NewStore(testDB, testTxRetry)
+
+	account1 := _fundAccount(t, _createAccount(t), 100)
+	account2 := _createAccountIn(t, util.USD)
+	if account1.Currency == util.USD {
+		account2 = _createAccountIn(t, util.EUR)
+	}
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+}
+
+func TestStore_TransferTxNotFound(t *testing.T) {
+	store := NewStore(testDB, testTxRetry)
+	account1 := _fundAccount(t, _createAccount(t), 100)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account1.ID + 1000000,
+		Amount:        10,
+	})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestStore_TransferTxConcurrentOverdraw(t *testing.T) {
+	store := NewStore(testDB, testTxRetry)
+
+	account1 := _fundAccount(t, _createAccount(t), 50)
+	account2 := _createAccountIn(t, account1.Currency)
+
+	n := 10
+	amount := int64(10)
+
+	// the source is locked, so only as many transfers succeed as the balance covers
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.TransferTx(context.Background(), TransferTxParams{
+				FromAccountID: account1.ID,
+				ToAccountID:   account2.ID,
+				Amount:        amount,
+			})
+			errs <- err
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.ErrorIs(t, err, ErrInsufficientFunds)
+	}
+	assert.Equal(t, 5, succeeded)
+
+	updateAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	assert.NoError(t, err)
+	assert.Zero(t, updateAccount1.Balance)
+}
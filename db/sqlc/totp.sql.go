@@ -0,0 +1,263 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: totp.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const confirmUserTOTP = `-- name: ConfirmUserTOTP :one
+UPDATE user_totps
+SET is_confirmed = true
+WHERE username = $1 AND secret = $2 AND is_confirmed = false
+RETURNING username, secret, is_confirmed, last_used_step, created_at
+`
+
+type ConfirmUserTOTPParams struct {
+	Username string `json:"username"`
+	Secret   string `json:"secret"`
+}
+
+// only the pending secret the user proved a code for is enabled, a re-enroll in between leaves no row
+func (q *Queries) ConfirmUserTOTP(ctx context.Context, arg ConfirmUserTOTPParams) (UserTotp, error) {
+	row := q.db.QueryRowContext(ctx, confirmUserTOTP, arg.Username, arg.Secret)
+	var i UserTotp
+	err := row.Scan(
+		&i.Username,
+		&i.Secret,
+		&i.IsConfirmed,
+		&i.LastUsedStep,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createLoginChallenge = `-- name: CreateLoginChallenge :one
+INSERT INTO login_challenges (
+    id,
+    username,
+    hashed_token,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING id, username, hashed_token, attempts, expires_at, created_at
+`
+
+type CreateLoginChallengeParams struct {
+	ID          uuid.UUID `json:"id"`
+	Username    string    `json:"username"`
+	HashedToken string    `json:"hashed_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreateLoginChallenge(ctx context.Context, arg CreateLoginChallengeParams) (LoginChallenge, error) {
+	row := q.db.QueryRowContext(ctx, createLoginChallenge,
+		arg.ID,
+		arg.Username,
+		arg.HashedToken,
+		arg.ExpiresAt,
+	)
+	var i LoginChallenge
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.HashedToken,
+		&i.Attempts,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createRecoveryCode = `-- name: CreateRecoveryCode :one
+INSERT INTO recovery_codes (
+    username,
+    hashed_code
+) VALUES (
+    $1, $2
+) RETURNING id, username, hashed_code, is_used, created_at
+`
+
+type CreateRecoveryCodeParams struct {
+	Username   string `json:"username"`
+	HashedCode string `json:"hashed_code"`
+}
+
+func (q *Queries) CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) (RecoveryCode, error) {
+	row := q.db.QueryRowContext(ctx, createRecoveryCode, arg.Username, arg.HashedCode)
+	var i RecoveryCode
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.HashedCode,
+		&i.IsUsed,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteLoginChallenge = `-- name: DeleteLoginChallenge :exec
+DELETE FROM login_challenges
+WHERE id = $1
+`
+
+func (q *Queries) DeleteLoginChallenge(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteLoginChallenge, id)
+	return err
+}
+
+const deleteRecoveryCodes = `-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE username = $1
+`
+
+func (q *Queries) DeleteRecoveryCodes(ctx context.Context, username string) error {
+	_, err := q.db.ExecContext(ctx, deleteRecoveryCodes, username)
+	return err
+}
+
+const getLoginChallengeByHash = `-- name: GetLoginChallengeByHash :one
+SELECT id, username, hashed_token, attempts, expires_at, created_at FROM login_challenges
+WHERE hashed_token = $1 LIMIT 1
+`
+
+func (q *Queries) GetLoginChallengeByHash(ctx context.Context, hashedToken string) (LoginChallenge, error) {
+	row := q.db.QueryRowContext(ctx, getLoginChallengeByHash, hashedToken)
+	var i LoginChallenge
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.HashedToken,
+		&i.Attempts,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getUserTOTP = `-- name: GetUserTOTP :one
+SELECT username, secret, is_confirmed, last_used_step, created_at FROM user_totps
+WHERE username = $1 LIMIT 1
+`
+
+func (q *Queries) GetUserTOTP(ctx context.Context, username string) (UserTotp, error) {
+	row := q.db.QueryRowContext(ctx, getUserTOTP, username)
+	var i UserTotp
+	err := row.Scan(
+		&i.Username,
+		&i.Secret,
+		&i.IsConfirmed,
+		&i.LastUsedStep,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const incrementLoginChallengeAttempts = `-- name: IncrementLoginChallengeAttempts :one
+UPDATE login_challenges
+SET attempts = attempts + 1
+WHERE id = $1
+RETURNING id, username, hashed_token, attempts, expires_at, created_at
+`
+
+func (q *Queries) IncrementLoginChallengeAttempts(ctx context.Context, id uuid.UUID) (LoginChallenge, error) {
+	row := q.db.QueryRowContext(ctx, incrementLoginChallengeAttempts, id)
+	var i LoginChallenge
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.HashedToken,
+		&i.Attempts,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const upsertUserTOTP = `-- name: UpsertUserTOTP :one
+INSERT INTO user_totps (
+    username,
+    secret
+) VALUES (
+    $1, $2
+) ON CONFLICT (username) DO UPDATE
+SET secret = EXCLUDED.secret,
+    is_confirmed = false,
+    last_used_step = 0,
+    created_at = now()
+RETURNING username, secret, is_confirmed, last_used_step, created_at
+`
+
+type UpsertUserTOTPParams struct {
+	Username string `json:"username"`
+	Secret   string `json:"secret"`
+}
+
+func (q *Queries) UpsertUserTOTP(ctx context.Context, arg UpsertUserTOTPParams) (UserTotp, error) {
+	row := q.db.QueryRowContext(ctx, upsertUserTOTP, arg.Username, arg.Secret)
+	var i UserTotp
+	err := row.Scan(
+		&i.Username,
+		&i.Secret,
+		&i.IsConfirmed,
+		&i.LastUsedStep,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const useRecoveryCode = `-- name: UseRecoveryCode :one
+UPDATE recovery_codes
+SET is_used = true
+WHERE username = $1 AND hashed_code = $2 AND is_used = false
+RETURNING id, username, hashed_code, is_used, created_at
+`
+
+type UseRecoveryCodeParams struct {
+	Username   string `json:"username"`
+	HashedCode string `json:"hashed_code"`
+}
+
+func (q *Queries) UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (RecoveryCode, error) {
+	row := q.db.QueryRowContext(ctx, useRecoveryCode, arg.Username, arg.HashedCode)
+	var i RecoveryCode
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.HashedCode,
+		&i.IsUsed,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const useTOTPStep = `-- name: UseTOTPStep :one
+UPDATE user_totps
+SET last_used_step = $1
+WHERE username = $2 AND last_used_step < $1
+RETURNING username, secret, is_confirmed, last_used_step, created_at
+`
+
+type UseTOTPStepParams struct {
+	Step     int64  `json:"step"`
+	Username string `json:"username"`
+}
+
+func (q *Queries) UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (UserTotp, error) {
+	row := q.db.QueryRowContext(ctx, useTOTPStep, arg.Step, arg.Username)
+	var i UserTotp
+	err := row.Scan(
+		&i.Username,
+		&i.Secret,
+		&i.IsConfirmed,
+		&i.LastUsedStep,
+		&i.CreatedAt,
+	)
+	return i, err
+}
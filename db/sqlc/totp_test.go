@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func _createUserTOTP(t *testing.T) UserTotp {
+	user := _createUser(t)
+	arg := UpsertUserTOTPParams{
+		Username: user.Username,
+		Secret:   util.RandomString(32),
+	}
+
+	userTOTP, err := testQueries.UpsertUserTOTP(context.Background(), arg)
+
+	assert.NoError(t, err)
+	assert.Equal(t, arg.Username, userTOTP.Username)
+	assert.Equal(t, arg.Secret, userTOTP.Secret)
+	assert.False(t, userTOTP.IsConfirmed)
+	assert.Zero(t, userTOTP.LastUsedStep)
+	assert.NotZero(t, userTOTP.CreatedAt)
+
+	return userTOTP
+}
+
+func TestUseTOTPStep(t *testing.T) {
+	userTOTP := _createUserTOTP(t)
+
+	used, err := testQueries.UseTOTPStep(context.Background(), UseTOTPStepParams{
+		Step:     100,
+		Username: userTOTP.Username,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), used.LastUsedStep)
+
+	// a step can only be used once
+	_, err = testQueries.UseTOTPStep(context.Background(), UseTOTPStepParams{
+		Step:     100,
+		Username: userTOTP.Username,
+	})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestConfirmTOTPTx(t *testing.T) {
+	store := NewStore(testDB, testTxRetry)
+	userTOTP := _createUserTOTP(t)
+
+	hashedCodes := []string{util.HashSecret(util.RandomString(16)), util.HashSecret(util.RandomString(16))}
+
+	// a secret the user did not prove a code for is not enabled
+	_, err := store.ConfirmTOTPTx(context.Background(), ConfirmTOTPTxParams{
+		Username:            userTOTP.Username,
+		Secret:              util.RandomString(32),
+		HashedRecoveryCodes: hashedCodes,
+	})
+	assert.ErrorIs(t, err, ErrConflict)
+
+	result, err := store.ConfirmTOTPTx(context.Background(), ConfirmTOTPTxParams{
+		Username:            userTOTP.Username,
+		Secret:              userTOTP.Secret,
+		HashedRecoveryCodes: hashedCodes,
+	})
+	assert.NoError(t, err)
+	assert.True(t, result.UserTOTP.IsConfirmed)
+	assert.Len(t, result.RecoveryCodes, len(hashedCodes))
+
+	// a concurrent confirm of the same secret does not replace the recovery codes
+	_, err = store.ConfirmTOTPTx(context.Background(), ConfirmTOTPTxParams{
+		Username:            userTOTP.Username,
+		Secret:              userTOTP.Secret,
+		HashedRecoveryCodes: []string{util.HashSecret(util.RandomString(16))},
+	})
+	assert.ErrorIs(t, err, ErrConflict)
+
+	// recovery codes are single use
+	code, err := testQueries.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{
+		Username:   userTOTP.Username,
+		HashedCode: hashedCodes[0],
+	})
+	assert.NoError(t, err)
+	assert.True(t, code.IsUsed)
+
+	_, err = testQueries.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{
+		Username:   userTOTP.Username,
+		HashedCode: hashedCodes[0],
+	})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestLoginChallenge(t *testing.T) {
+	user := _createUser(t)
+	arg := CreateLoginChallengeParams{
+		ID:          uuid.New(),
+		Username:    user.Username,
+		HashedToken: util.HashSecret(util.RandomString(32)),
+		ExpiresAt:   time.Now().Add(time.Minute),
+	}
+
+	challenge1, err := testQueries.CreateLoginChallenge(context.Background(), arg)
+	assert.NoError(t, err)
+	assert.Equal(t, arg.ID, challenge1.ID)
+	assert.Zero(t, challenge1.Attempts)
+
+	challenge2, err := testQueries.GetLoginChallengeByHash(context.Background(), arg.HashedToken)
+	assert.NoError(t, err)
+	assert.Equal(t, challenge1.ID, challenge2.ID)
+
+	challenge2, err = testQueries.IncrementLoginChallengeAttempts(context.Background(), arg.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), challenge2.Attempts)
+
+	err = testQueries.DeleteLoginChallenge(context.Background(), arg.ID)
+	assert.NoError(t, err)
+
+	_, err = testQueries.GetLoginChallengeByHash(context.Background(), arg.HashedToken)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type ChangePasswordTxParams struct {
+	Username          string    `json:"username"`
+	HashedPassword    string    `json:"-"`
+	PasswordChangedAt time.Time `json:"password_changed_at"`
+}
+
+type ChangePasswordTxResult struct {
+	User User `json:"user"`
+}
+
+// ChangePasswordTx replaces the password of a user and blocks all of its sessions,
+// so no refresh token issued with the old password can be renewed
+func (store *SQLStore) ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (ChangePasswordTxResult, error) {
+	var result ChangePasswordTxResult
+
+	err := store.execTx(ctx, sql.LevelDefault, func(queries *Queries) error {
+		var err error
+		result.User, err = changePassword(ctx, queries, arg)
+		return err
+	})
+
+	return result, err
+}
+
+func changePassword(ctx context.Context, queries *Queries, arg ChangePasswordTxParams) (User, error) {
+	user, err := queries.UpdateUserPassword(ctx, UpdateUserPasswordParams{
+		Username:          arg.Username,
+		HashedPassword:    arg.HashedPassword,
+		PasswordChangedAt: arg.PasswordChangedAt,
+	})
+	if err != nil {
+		return user, err
+	}
+
+	err = queries.BlockUserSessions(ctx, arg.Username)
+	return user, err
+}
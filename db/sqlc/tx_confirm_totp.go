@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+type ConfirmTOTPTxParams struct {
+	Username string `json:"username"`
+	// the pending secret the user proved a code for
+	Secret string `json:"-"`
+	// replaces any previous recovery codes
+	HashedRecoveryCodes []string `json:"-"`
+}
+
+type ConfirmTOTPTxResult struct {
+	UserTOTP      UserTotp       `json:"user_totp"`
+	RecoveryCodes []RecoveryCode `json:"recovery_codes"`
+}
+
+// ConfirmTOTPTx enables the pending totp secret of a user and stores a fresh set of recovery codes.
+// It fails with ErrConflict if the secret was replaced or confirmed in the meantime.
+func (store *SQLStore) ConfirmTOTPTx(ctx context.Context, arg ConfirmTOTPTxParams) (ConfirmTOTPTxResult, error) {
+	var result ConfirmTOTPTxResult
+
+	err := store.execTx(ctx, sql.LevelDefault, func(queries *Queries) error {
+		var err error
+		result.UserTOTP, err = queries.ConfirmUserTOTP(ctx, ConfirmUserTOTPParams{
+			Username: arg.Username,
+			Secret:   arg.Secret,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return &Error{Kind: ErrConflict, Cause: err}
+		}
+		if err != nil {
+			return err
+		}
+
+		err = queries.DeleteRecoveryCodes(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		result.RecoveryCodes = make([]RecoveryCode, 0, len(arg.HashedRecoveryCodes))
+		for _, hashedCode := range arg.HashedRecoveryCodes {
+			code, err := queries.CreateRecoveryCode(ctx, CreateRecoveryCodeParams{
+				Username:   arg.Username,
+				HashedCode: hashedCode,
+			})
+			if err != nil {
+				return err
+			}
+			result.RecoveryCodes = append(result.RecoveryCodes, code)
+		}
+		return nil
+	})
+
+	return result, err
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CreateMagicLinkTxParams struct {
+	ID        uuid.UUID               `json:"id"`
+	Username  string                  `json:"username"`
+	ExpiresAt time.Time               `json:"expires_at"`
+	Email     CreateOutboxEmailParams `json:"email"`
+}
+
+type CreateMagicLinkTxResult struct {
+	MagicLink MagicLink   `json:"magic_link"`
+	Email     EmailOutbox `json:"email"`
+}
+
+// CreateMagicLinkTx replaces the unused magic links of a user with a new one
+// and enqueues the email carrying it, so the link never exists without its email
+func (store *SQLStore) CreateMagicLinkTx(ctx context.Context, arg CreateMagicLinkTxParams) (CreateMagicLinkTxResult, error) {
+	var result CreateMagicLinkTxResult
+
+	// serializable, so concurrent requests cannot both keep their new links
+	err := store.execTx(ctx, sql.LevelSerializable, func(queries *Queries) error {
+		err := queries.DeleteUnusedMagicLinks(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		result.MagicLink, err = queries.CreateMagicLink(ctx, CreateMagicLinkParams{
+			ID:        arg.ID,
+			Username:  arg.Username,
+			ExpiresAt: arg.ExpiresAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Email, err = queries.CreateOutboxEmail(ctx, arg.Email)
+		return err
+	})
+
+	return result, err
+}
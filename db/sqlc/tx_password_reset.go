@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CreatePasswordResetTxParams struct {
+	ID          uuid.UUID               `json:"id"`
+	Username    string                  `json:"username"`
+	HashedToken string                  `json:"-"`
+	ExpiresAt   time.Time               `json:"expires_at"`
+	Email       CreateOutboxEmailParams `json:"email"`
+}
+
+type CreatePasswordResetTxResult struct {
+	ResetToken PasswordResetToken `json:"reset_token"`
+	Email      EmailOutbox        `json:"email"`
+}
+
+// CreatePasswordResetTx replaces the unused reset tokens of a user with a new one
+// and enqueues the email carrying it, so the token never exists without its email
+func (store *SQLStore) CreatePasswordResetTx(ctx context.Context, arg CreatePasswordResetTxParams) (CreatePasswordResetTxResult, error) {
+	var result CreatePasswordResetTxResult
+
+	// serializable, so concurrent requests cannot both keep their new tokens
+	err := store.execTx(ctx, sql.LevelSerializable, func(queries *Queries) error {
+		err := queries.DeleteUnusedPasswordResetTokens(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		result.ResetToken, err = queries.CreatePasswordResetToken(ctx, CreatePasswordResetTokenParams{
+			ID:          arg.ID,
+			Username:    arg.Username,
+			HashedToken: arg.HashedToken,
+			ExpiresAt:   arg.ExpiresAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Email, err = queries.CreateOutboxEmail(ctx, arg.Email)
+		return err
+	})
+
+	return result, err
+}
+
+type ResetPasswordTxParams struct {
+	TokenID           uuid.UUID `json:"token_id"`
+	HashedPassword    string    `json:"-"`
+	PasswordChangedAt time.Time `json:"password_changed_at"`
+}
+
+type ResetPasswordTxResult struct {
+	User User `json:"user"`
+}
+
+// ResetPasswordTx consumes a reset token and replaces the password of its user.
+// It fails with ErrNotFound if the token was used or expired in the meantime.
+func (store *SQLStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (ResetPasswordTxResult, error) {
+	var result ResetPasswordTxResult
+
+	err := store.execTx(ctx, sql.LevelDefault, func(queries *Queries) error {
+		resetToken, err := queries.UsePasswordResetToken(ctx, arg.TokenID)
+		if err != nil {
+			return err
+		}
+
+		result.User, err = changePassword(ctx, queries, ChangePasswordTxParams{
+			Username:          resetToken.Username,
+			HashedPassword:    arg.HashedPassword,
+			PasswordChangedAt: arg.PasswordChangedAt,
+		})
+		return err
+	})
+
+	return result, err
+}
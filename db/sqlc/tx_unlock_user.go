@@ -0,0 +1,44 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// LoginThrottleUsername and LoginThrottleIP are the kinds of login_throttles rows
+const (
+	LoginThrottleUsername = "username"
+	LoginThrottleIP       = "ip"
+)
+
+type UnlockUserTxParams struct {
+	Username   string `json:"username"`
+	UnlockedBy string `json:"unlocked_by"`
+}
+
+type UnlockUserTxResult struct {
+	UnlockedLockouts int64 `json:"unlocked_lockouts"`
+}
+
+// UnlockUserTx clears the failed login attempts of a user and marks its open lockouts as unlocked
+func (store *SQLStore) UnlockUserTx(ctx context.Context, arg UnlockUserTxParams) (UnlockUserTxResult, error) {
+	var result UnlockUserTxResult
+
+	err := store.execTx(ctx, sql.LevelDefault, func(queries *Queries) error {
+		err := queries.DeleteLoginThrottle(ctx, DeleteLoginThrottleParams{
+			Kind: LoginThrottleUsername,
+			Key:  arg.Username,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.UnlockedLockouts, err = queries.UnlockLoginLockouts(ctx, UnlockLoginLockoutsParams{
+			Username:   arg.Username,
+			UnlockedBy: sql.NullString{String: arg.UnlockedBy, Valid: true},
+		})
+		return err
+	})
+
+	return result, err
+}
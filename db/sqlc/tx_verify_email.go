@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type CreateUserTxParams struct {
+	CreateUserParams
+	HashedVerifyCode    string                  `json:"-"`
+	VerifyCodeExpiresAt time.Time               `json:"verify_code_expires_at"`
+	VerifyEmail         CreateOutboxEmailParams `json:"verify_email"`
+}
+
+type CreateUserTxResult struct {
+	User        User        `json:"user"`
+	VerifyEmail VerifyEmail `json:"verify_email"`
+}
+
+// CreateUserTx creates a user together with the code verifying its email
+// and enqueues the email carrying the code
+func (store *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error) {
+	var result CreateUserTxResult
+
+	err := store.execTx(ctx, sql.LevelDefault, func(queries *Queries) error {
+		var err error
+		result.User, err = queries.CreateUser(ctx, arg.CreateUserParams)
+		if err != nil {
+			return err
+		}
+
+		result.VerifyEmail, err = queries.CreateVerifyEmail(ctx, CreateVerifyEmailParams{
+			Username:   result.User.Username,
+			Email:      result.User.Email,
+			HashedCode: arg.HashedVerifyCode,
+			ExpiresAt:  arg.VerifyCodeExpiresAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = queries.CreateOutboxEmail(ctx, arg.VerifyEmail)
+		return err
+	})
+
+	return result, err
+}
+
+type VerifyEmailTxParams struct {
+	VerifyEmailID int64 `json:"verify_email_id"`
+}
+
+type VerifyEmailTxResult struct {
+	User        User        `json:"user"`
+	VerifyEmail VerifyEmail `json:"verify_email"`
+}
+
+// VerifyEmailTx consumes a verification code and marks the address it was sent to as verified.
+// It fails with ErrNotFound if the code was used or expired, or the user changed its email since.
+func (store *SQLStore) VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error) {
+	var result VerifyEmailTxResult
+
+	err := store.execTx(ctx, sql.LevelDefault, func(queries *Queries) error {
+		var err error
+		result.VerifyEmail, err = queries.UseVerifyEmail(ctx, arg.VerifyEmailID)
+		if err != nil {
+			return err
+		}
+
+		result.User, err = queries.MarkUserEmailVerified(ctx, MarkUserEmailVerifiedParams{
+			Username: result.VerifyEmail.Username,
+			Email:    result.VerifyEmail.Email,
+		})
+		return err
+	})
+
+	return result, err
+}
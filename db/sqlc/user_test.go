@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/WanCodeBase/GinModule/util"
 	"github.com/stretchr/testify/assert"
@@ -29,6 +30,7 @@ func _createUser(t *testing.T) User {
 	assert.Equal(t, arg.HashedPassword, user.HashedPassword)
 	assert.Equal(t, arg.FullName, user.FullName)
 	assert.Equal(t, arg.Email, user.Email)
+	assert.Equal(t, util.DepositorRole, user.Role)
 
 	assert.NotZero(t, user.CreatedAt)
 	assert.Zero(t, user.PasswordChangedAt)
@@ -49,3 +51,61 @@ func TestGetUser(t *testing.T) {
 	assert.NotEmpty(t, user2)
 	assert.Equal(t, user1, user2)
 }
+
+
+func TestChangePasswordTx(t *testing.T) {
+	store := NewStore(testDB, testTxRetry)
+	session := _createSession(t)
+	user, err := testQueries.GetUser(context.Background(), session.Username)
+	assert.NoError(t, err)
+
+	hashedPassword, err := util.HashedPassword(util.RandomString(8))
+	assert.NoError(t, err)
+	changedAt := time.Now()
+
+	result, err := store.ChangePasswordTx(context.Background(), ChangePasswordTxParams{
+		Username:          user.Username,
+		HashedPassword:    hashedPassword,
+		PasswordChangedAt: changedAt,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, hashedPassword, result.User.HashedPassword)
+	assert.WithinDuration(t, changedAt, result.User.PasswordChangedAt, time.Millisecond)
+
+	session, err = testQueries.GetSession(context.Background(), session.ID)
+	assert.NoError(t, err)
+	assert.True(t, session.IsBlocked)
+
+	changes, err := testQueries.ListPasswordChanges(context.Background(), changedAt.Add(-time.Second))
+	assert.NoError(t, err)
+	assert.Contains(t, changes, ListPasswordChangesRow{
+		Username:          user.Username,
+		PasswordChangedAt: result.User.PasswordChangedAt,
+	})
+}
+
+func TestRehashUserPassword(t *testing.T) {
+	user := _createUser(t)
+
+	rows, err := testQueries.RehashUserPassword(context.Background(), RehashUserPasswordParams{
+		NewHashedPassword: "rehashed",
+		Username:          user.Username,
+		OldHashedPassword: user.HashedPassword,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), rows)
+
+	// the hash changed in the meantime
+	rows, err = testQueries.RehashUserPassword(context.Background(), RehashUserPasswordParams{
+		NewHashedPassword: "rehashed again",
+		Username:          user.Username,
+		OldHashedPassword: user.HashedPassword,
+	})
+	assert.NoError(t, err)
+	assert.Zero(t, rows)
+
+	user2, err := testQueries.GetUser(context.Background(), user.Username)
+	assert.NoError(t, err)
+	assert.Equal(t, "rehashed", user2.HashedPassword)
+	assert.Equal(t, user.PasswordChangedAt, user2.PasswordChangedAt)
+}
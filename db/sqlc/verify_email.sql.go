@@ -0,0 +1,91 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: verify_email.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const createVerifyEmail = `-- name: CreateVerifyEmail :one
+INSERT INTO verify_emails (
+    username,
+    email,
+    hashed_code,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING id, username, email, hashed_code, is_used, expires_at, created_at
+`
+
+type CreateVerifyEmailParams struct {
+	Username   string    `json:"username"`
+	Email      string    `json:"email"`
+	HashedCode string    `json:"hashed_code"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailParams) (VerifyEmail, error) {
+	row := q.db.QueryRowContext(ctx, createVerifyEmail,
+		arg.Username,
+		arg.Email,
+		arg.HashedCode,
+		arg.ExpiresAt,
+	)
+	var i VerifyEmail
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.HashedCode,
+		&i.IsUsed,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getVerifyEmailByHash = `-- name: GetVerifyEmailByHash :one
+SELECT id, username, email, hashed_code, is_used, expires_at, created_at FROM verify_emails
+WHERE hashed_code = $1 LIMIT 1
+`
+
+func (q *Queries) GetVerifyEmailByHash(ctx context.Context, hashedCode string) (VerifyEmail, error) {
+	row := q.db.QueryRowContext(ctx, getVerifyEmailByHash, hashedCode)
+	var i VerifyEmail
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.HashedCode,
+		&i.IsUsed,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const useVerifyEmail = `-- name: UseVerifyEmail :one
+UPDATE verify_emails
+SET is_used = true
+WHERE id = $1 AND is_used = false AND expires_at > now()
+RETURNING id, username, email, hashed_code, is_used, expires_at, created_at
+`
+
+func (q *Queries) UseVerifyEmail(ctx context.Context, id int64) (VerifyEmail, error) {
+	row := q.db.QueryRowContext(ctx, useVerifyEmail, id)
+	var i VerifyEmail
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.HashedCode,
+		&i.IsUsed,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyEmailTx(t *testing.T) {
+	store := NewStore(testDB, testTxRetry)
+
+	hashedPassword, err := util.HashedPassword(util.RandomString(8))
+	assert.NoError(t, err)
+	email := fmt.Sprintf("%s@example.com", util.RandomOwner())
+
+	created, err := store.CreateUserTx(context.Background(), CreateUserTxParams{
+		CreateUserParams: CreateUserParams{
+			Username:       util.RandomOwner(),
+			HashedPassword: hashedPassword,
+			FullName:       util.RandomOwner(),
+			Email:          email,
+		},
+		HashedVerifyCode:    util.HashSecret(util.RandomString(32)),
+		VerifyCodeExpiresAt: time.Now().Add(time.Hour),
+		VerifyEmail: CreateOutboxEmailParams{
+			ToAddress: email,
+			Subject:   "Verify your email",
+			Body:      util.RandomString(20),
+		},
+	})
+	assert.NoError(t, err)
+	assert.False(t, created.User.IsEmailVerified)
+	assert.Equal(t, email, created.VerifyEmail.Email)
+
+	arg := VerifyEmailTxParams{VerifyEmailID: created.VerifyEmail.ID}
+	result, err := store.VerifyEmailTx(context.Background(), arg)
+	assert.NoError(t, err)
+	assert.True(t, result.User.IsEmailVerified)
+	assert.True(t, result.VerifyEmail.IsUsed)
+
+	// codes are single use
+	_, err = store.VerifyEmailTx(context.Background(), arg)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
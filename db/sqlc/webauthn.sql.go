@@ -0,0 +1,297 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: webauthn.sql
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const createWebAuthnCeremony = `-- name: CreateWebAuthnCeremony :one
+INSERT INTO webauthn_ceremonies (
+    id,
+    kind,
+    username,
+    session_data,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4, $5
+) RETURNING id, kind, username, session_data, expires_at, created_at
+`
+
+type CreateWebAuthnCeremonyParams struct {
+	ID          uuid.UUID       `json:"id"`
+	Kind        string          `json:"kind"`
+	Username    string          `json:"username"`
+	SessionData json.RawMessage `json:"session_data"`
+	ExpiresAt   time.Time       `json:"expires_at"`
+}
+
+func (q *Queries) CreateWebAuthnCeremony(ctx context.Context, arg CreateWebAuthnCeremonyParams) (WebauthnCeremony, error) {
+	row := q.db.QueryRowContext(ctx, createWebAuthnCeremony,
+		arg.ID,
+		arg.Kind,
+		arg.Username,
+		arg.SessionData,
+		arg.ExpiresAt,
+	)
+	var i WebauthnCeremony
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.Username,
+		&i.SessionData,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createWebAuthnCredential = `-- name: CreateWebAuthnCredential :one
+INSERT INTO webauthn_credentials (
+    id,
+    username,
+    name,
+    public_key,
+    attestation_type,
+    transports,
+    aaguid,
+    sign_count,
+    backup_eligible,
+    backup_state
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+) RETURNING id, username, name, public_key, attestation_type, transports, aaguid, sign_count, backup_eligible, backup_state, last_used_at, created_at
+`
+
+type CreateWebAuthnCredentialParams struct {
+	ID              []byte   `json:"id"`
+	Username        string   `json:"username"`
+	Name            string   `json:"name"`
+	PublicKey       []byte   `json:"public_key"`
+	AttestationType string   `json:"attestation_type"`
+	Transports      []string `json:"transports"`
+	Aaguid          []byte   `json:"aaguid"`
+	SignCount       int64    `json:"sign_count"`
+	BackupEligible  bool     `json:"backup_eligible"`
+	BackupState     bool     `json:"backup_state"`
+}
+
+func (q *Queries) CreateWebAuthnCredential(ctx context.Context, arg CreateWebAuthnCredentialParams) (WebauthnCredential, error) {
+	row := q.db.QueryRowContext(ctx, createWebAuthnCredential,
+		arg.ID,
+		arg.Username,
+		arg.Name,
+		arg.PublicKey,
+		arg.AttestationType,
+		pq.Array(arg.Transports),
+		arg.Aaguid,
+		arg.SignCount,
+		arg.BackupEligible,
+		arg.BackupState,
+	)
+	var i WebauthnCredential
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Name,
+		&i.PublicKey,
+		&i.AttestationType,
+		pq.Array(&i.Transports),
+		&i.Aaguid,
+		&i.SignCount,
+		&i.BackupEligible,
+		&i.BackupState,
+		&i.LastUsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createWebAuthnUserHandle = `-- name: CreateWebAuthnUserHandle :one
+INSERT INTO webauthn_user_handles (
+    handle,
+    username
+) VALUES (
+    $1, $2
+) ON CONFLICT (username) DO UPDATE SET username = EXCLUDED.username
+RETURNING handle, username, created_at
+`
+
+type CreateWebAuthnUserHandleParams struct {
+	Handle   []byte `json:"handle"`
+	Username string `json:"username"`
+}
+
+// a user that has a handle keeps it
+func (q *Queries) CreateWebAuthnUserHandle(ctx context.Context, arg CreateWebAuthnUserHandleParams) (WebauthnUserHandle, error) {
+	row := q.db.QueryRowContext(ctx, createWebAuthnUserHandle, arg.Handle, arg.Username)
+	var i WebauthnUserHandle
+	err := row.Scan(&i.Handle, &i.Username, &i.CreatedAt)
+	return i, err
+}
+
+const deleteExpiredWebAuthnCeremonies = `-- name: DeleteExpiredWebAuthnCeremonies :execrows
+DELETE FROM webauthn_ceremonies
+WHERE expires_at < now()
+`
+
+func (q *Queries) DeleteExpiredWebAuthnCeremonies(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteExpiredWebAuthnCeremonies)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteWebAuthnCredential = `-- name: DeleteWebAuthnCredential :one
+DELETE FROM webauthn_credentials
+WHERE id = $1 AND username = $2
+RETURNING id, username, name, public_key, attestation_type, transports, aaguid, sign_count, backup_eligible, backup_state, last_used_at, created_at
+`
+
+type DeleteWebAuthnCredentialParams struct {
+	ID       []byte `json:"id"`
+	Username string `json:"username"`
+}
+
+func (q *Queries) DeleteWebAuthnCredential(ctx context.Context, arg DeleteWebAuthnCredentialParams) (WebauthnCredential, error) {
+	row := q.db.QueryRowContext(ctx, deleteWebAuthnCredential, arg.ID, arg.Username)
+	var i WebauthnCredential
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Name,
+		&i.PublicKey,
+		&i.AttestationType,
+		pq.Array(&i.Transports),
+		&i.Aaguid,
+		&i.SignCount,
+		&i.BackupEligible,
+		&i.BackupState,
+		&i.LastUsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getWebAuthnUserHandle = `-- name: GetWebAuthnUserHandle :one
+SELECT handle, username, created_at FROM webauthn_user_handles
+WHERE handle = $1 LIMIT 1
+`
+
+func (q *Queries) GetWebAuthnUserHandle(ctx context.Context, handle []byte) (WebauthnUserHandle, error) {
+	row := q.db.QueryRowContext(ctx, getWebAuthnUserHandle, handle)
+	var i WebauthnUserHandle
+	err := row.Scan(&i.Handle, &i.Username, &i.CreatedAt)
+	return i, err
+}
+
+const listWebAuthnCredentials = `-- name: ListWebAuthnCredentials :many
+SELECT id, username, name, public_key, attestation_type, transports, aaguid, sign_count, backup_eligible, backup_state, last_used_at, created_at FROM webauthn_credentials
+WHERE username = $1
+ORDER BY created_at
+`
+
+func (q *Queries) ListWebAuthnCredentials(ctx context.Context, username string) ([]WebauthnCredential, error) {
+	rows, err := q.db.QueryContext(ctx, listWebAuthnCredentials, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []WebauthnCredential{}
+	for rows.Next() {
+		var i WebauthnCredential
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.Name,
+			&i.PublicKey,
+			&i.AttestationType,
+			pq.Array(&i.Transports),
+			&i.Aaguid,
+			&i.SignCount,
+			&i.BackupEligible,
+			&i.BackupState,
+			&i.LastUsedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const takeWebAuthnCeremony = `-- name: TakeWebAuthnCeremony :one
+DELETE FROM webauthn_ceremonies
+WHERE id = $1 AND kind = $2
+RETURNING id, kind, username, session_data, expires_at, created_at
+`
+
+type TakeWebAuthnCeremonyParams struct {
+	ID   uuid.UUID `json:"id"`
+	Kind string    `json:"kind"`
+}
+
+func (q *Queries) TakeWebAuthnCeremony(ctx context.Context, arg TakeWebAuthnCeremonyParams) (WebauthnCeremony, error) {
+	row := q.db.QueryRowContext(ctx, takeWebAuthnCeremony, arg.ID, arg.Kind)
+	var i WebauthnCeremony
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.Username,
+		&i.SessionData,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const useWebAuthnCredential = `-- name: UseWebAuthnCredential :one
+UPDATE webauthn_credentials
+SET sign_count = $1,
+    backup_state = $2,
+    last_used_at = now()
+WHERE id = $3
+RETURNING id, username, name, public_key, attestation_type, transports, aaguid, sign_count, backup_eligible, backup_state, last_used_at, created_at
+`
+
+type UseWebAuthnCredentialParams struct {
+	SignCount   int64  `json:"sign_count"`
+	BackupState bool   `json:"backup_state"`
+	ID          []byte `json:"id"`
+}
+
+func (q *Queries) UseWebAuthnCredential(ctx context.Context, arg UseWebAuthnCredentialParams) (WebauthnCredential, error) {
+	row := q.db.QueryRowContext(ctx, useWebAuthnCredential, arg.SignCount, arg.BackupState, arg.ID)
+	var i WebauthnCredential
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Name,
+		&i.PublicKey,
+		&i.AttestationType,
+		pq.Array(&i.Transports),
+		&i.Aaguid,
+		&i.SignCount,
+		&i.BackupEligible,
+		&i.BackupState,
+		&i.LastUsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func _createWebAuthnCredential(t *testing.T, username string) WebauthnCredential {
+	arg := CreateWebAuthnCredentialParams{
+		ID:              []byte(util.RandomString(16)),
+		Username:        username,
+		Name:            util.RandomString(6),
+		PublicKey:       []byte(util.RandomString(64)),
+		AttestationType: "none",
+		Transports:      []string{"internal", "hybrid"},
+		Aaguid:          make([]byte, 16),
+		SignCount:       0,
+		BackupEligible:  true,
+		BackupState:     false,
+	}
+
+	credential, err := testQueries.CreateWebAuthnCredential(context.Background(), arg)
+	assert.NoError(t, err)
+	assert.Equal(t, arg.ID, credential.ID)
+	assert.Equal(t, arg.Username, credential.Username)
+	assert.Equal(t, arg.Name, credential.Name)
+	assert.Equal(t, arg.PublicKey, credential.PublicKey)
+	assert.Equal(t, arg.Transports, credential.Transports)
+	assert.True(t, credential.BackupEligible)
+	assert.NotZero(t, credential.CreatedAt)
+
+	return credential
+}
+
+func TestUseWebAuthnCredential(t *testing.T) {
+	user := _createUser(t)
+	credential := _createWebAuthnCredential(t, user.Username)
+
+	used, err := testQueries.UseWebAuthnCredential(context.Background(), UseWebAuthnCredentialParams{
+		SignCount:   3,
+		BackupState: true,
+		ID:          credential.ID,
+	})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3, used.SignCount)
+	assert.True(t, used.BackupState)
+	assert.WithinDuration(t, time.Now(), used.LastUsedAt, time.Minute)
+}
+
+func TestDeleteWebAuthnCredential(t *testing.T) {
+	user := _createUser(t)
+	credential := _createWebAuthnCredential(t, user.Username)
+	_createWebAuthnCredential(t, user.Username)
+
+	// only the owner can delete a passkey
+	_, err := testQueries.DeleteWebAuthnCredential(context.Background(), DeleteWebAuthnCredentialParams{
+		ID:       credential.ID,
+		Username: util.RandomOwner(),
+	})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = testQueries.DeleteWebAuthnCredential(context.Background(), DeleteWebAuthnCredentialParams{
+		ID:       credential.ID,
+		Username: user.Username,
+	})
+	assert.NoError(t, err)
+
+	credentials, err := testQueries.ListWebAuthnCredentials(context.Background(), user.Username)
+	assert.NoError(t, err)
+	assert.Len(t, credentials, 1)
+}
+
+func TestCreateWebAuthnUserHandle(t *testing.T) {
+	user := _createUser(t)
+
+	userHandle, err := testQueries.CreateWebAuthnUserHandle(context.Background(), CreateWebAuthnUserHandleParams{
+		Handle:   []byte(util.RandomString(32)),
+		Username: user.Username,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, user.Username, userHandle.Username)
+
+	// a user that has a handle keeps it
+	again, err := testQueries.CreateWebAuthnUserHandle(context.Background(), CreateWebAuthnUserHandleParams{
+		Handle:   []byte(util.RandomString(32)),
+		Username: user.Username,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, userHandle.Handle, again.Handle)
+
+	found, err := testQueries.GetWebAuthnUserHandle(context.Background(), userHandle.Handle)
+	assert.NoError(t, err)
+	assert.Equal(t, user.Username, found.Username)
+}
+
+func TestTakeWebAuthnCeremony(t *testing.T) {
+	arg := CreateWebAuthnCeremonyParams{
+		ID:          uuid.New(),
+		Kind:        "login",
+		SessionData: json.RawMessage(`{"challenge":"abc"}`),
+		ExpiresAt:   time.Now().Add(time.Minute),
+	}
+	_, err := testQueries.CreateWebAuthnCeremony(context.Background(), arg)
+	assert.NoError(t, err)
+
+	_, err = testQueries.TakeWebAuthnCeremony(context.Background(), TakeWebAuthnCeremonyParams{ID: arg.ID, Kind: "registration"})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	ceremony, err := testQueries.TakeWebAuthnCeremony(context.Background(), TakeWebAuthnCeremonyParams{ID: arg.ID, Kind: arg.Kind})
+	assert.NoError(t, err)
+	assert.Equal(t, arg.ID, ceremony.ID)
+	assert.JSONEq(t, string(arg.SessionData), string(ceremony.SessionData))
+
+	// a ceremony can be finished once
+	_, err = testQueries.TakeWebAuthnCeremony(context.Background(), TakeWebAuthnCeremonyParams{ID: arg.ID, Kind: arg.Kind})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,34 @@
+package mail
+
+import (
+	"context"
+	"sync"
+)
+
+// MemorySender keeps sent emails in memory, so tests can read them back
+type MemorySender struct {
+	mu     sync.Mutex
+	emails []Email
+}
+
+func NewMemorySender() *MemorySender {
+	return &MemorySender{}
+}
+
+func (s *MemorySender) Send(_ context.Context, email Email) error {
+	if err := validateHeaders(email.To, email.Subject); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.emails = append(s.emails, email)
+	s.mu.Unlock()
+	return nil
+}
+
+// Emails returns a copy of the emails sent so far
+func (s *MemorySender) Emails() []Email {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Email(nil), s.emails...)
+}
@@ -0,0 +1,117 @@
+package mail
+
+import (
+	"context"
+	"log"
+	"time"
+
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+)
+
+const (
+	defaultOutboxInterval    = 5 * time.Second
+	defaultOutboxBatchSize   = 10
+	defaultOutboxMaxAttempts = 5
+	defaultOutboxRetryDelay  = time.Minute
+	defaultOutboxRetention   = 24 * time.Hour
+	outboxPurgeInterval      = time.Hour
+)
+
+// Outbox sends the emails enqueued in the email_outbox table. Emails are written in the
+// same transaction as the change they announce, the outbox delivers them afterwards and
+// retries failures. Claimed rows are skipped by other instances, so several may run.
+// Bodies may carry secrets like reset tokens, they are cleared once sent and finished
+// emails are deleted after Retention.
+type Outbox struct {
+	store  db.Store
+	sender Sender
+
+	BatchSize   int32
+	MaxAttempts int32
+	RetryDelay  time.Duration
+	Retention   time.Duration
+}
+
+func NewOutbox(store db.Store, sender Sender) *Outbox {
+	return &Outbox{
+		store:       store,
+		sender:      sender,
+		BatchSize:   defaultOutboxBatchSize,
+		MaxAttempts: defaultOutboxMaxAttempts,
+		RetryDelay:  defaultOutboxRetryDelay,
+		Retention:   defaultOutboxRetention,
+	}
+}
+
+// Flush sends one batch of pending emails and returns how many were sent.
+// A failed email is retried after RetryDelay until it has been tried MaxAttempts times.
+func (o *Outbox) Flush(ctx context.Context) (int, error) {
+	emails, err := o.store.ClaimOutboxEmails(ctx, db.ClaimOutboxEmailsParams{
+		NextAttemptAt: time.Now().Add(o.RetryDelay),
+		MaxAttempts:   o.MaxAttempts,
+		BatchSize:     o.BatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, email := range emails {
+		err = o.sender.Send(ctx, Email{
+			To:      email.ToAddress,
+			Subject: email.Subject,
+			Body:    email.Body,
+		})
+		if err != nil {
+			log.Printf("send email %d failed, attempt %d: %v", email.ID, email.Attempts, err)
+			err = o.store.MarkOutboxEmailFailed(ctx, db.MarkOutboxEmailFailedParams{
+				ID:        email.ID,
+				LastError: err.Error(),
+			})
+			if err != nil {
+				return sent, err
+			}
+			continue
+		}
+
+		if err = o.store.MarkOutboxEmailSent(ctx, email.ID); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// Purge deletes the emails older than Retention that were sent or have used up their attempts
+func (o *Outbox) Purge(ctx context.Context) (int64, error) {
+	return o.store.DeleteFinishedOutboxEmails(ctx, db.DeleteFinishedOutboxEmailsParams{
+		CreatedBefore: time.Now().Add(-o.Retention),
+		MaxAttempts:   o.MaxAttempts,
+	})
+}
+
+// Run flushes the outbox every interval and purges it every hour until ctx is done
+func (o *Outbox) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultOutboxInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	purgeTicker := time.NewTicker(outboxPurgeInterval)
+	defer purgeTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := o.Flush(ctx); err != nil {
+				log.Println("flush email outbox failed:", err)
+			}
+		case <-purgeTicker.C:
+			if _, err := o.Purge(ctx); err != nil {
+				log.Println("purge email outbox failed:", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package mail
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	mockdb "github.com/WanCodeBase/GinModule/db/mock"
+	db "github.com/WanCodeBase/GinModule/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+type failingSender struct{}
+
+func (failingSender) Send(context.Context, Email) error {
+	return errors.New("relay unavailable")
+}
+
+func TestOutboxFlush(t *testing.T) {
+	emails := []db.EmailOutbox{
+		{ID: 1, ToAddress: "a@example.com", Subject: "hi", Body: "one", Attempts: 1},
+		{ID: 2, ToAddress: "b@example.com", Subject: "hi", Body: "two", Attempts: 1},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ClaimOutboxEmails(gomock.Any(), gomock.Any()).Times(1).
+		DoAndReturn(func(_ context.Context, arg db.ClaimOutboxEmailsParams) ([]db.EmailOutbox, error) {
+			require.Equal(t, int32(defaultOutboxBatchSize), arg.BatchSize)
+			require.Equal(t, int32(defaultOutboxMaxAttempts), arg.MaxAttempts)
+			return emails, nil
+		})
+	store.EXPECT().MarkOutboxEmailSent(gomock.Any(), int64(1)).Times(1).Return(nil)
+	store.EXPECT().MarkOutboxEmailSent(gomock.Any(), int64(2)).Times(1).Return(nil)
+
+	sender := NewMemorySender()
+	outbox := NewOutbox(store, sender)
+
+	sent, err := outbox.Flush(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, sent)
+	require.Equal(t, []Email{
+		{To: "a@example.com", Subject: "hi", Body: "one"},
+		{To: "b@example.com", Subject: "hi", Body: "two"},
+	}, sender.Emails())
+}
+
+func TestOutboxFlushSendError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ClaimOutboxEmails(gomock.Any(), gomock.Any()).Times(1).
+		Return([]db.EmailOutbox{{ID: 1, ToAddress: "a@example.com"}}, nil)
+	store.EXPECT().MarkOutboxEmailFailed(gomock.Any(), db.MarkOutboxEmailFailedParams{
+		ID:        1,
+		LastError: "relay unavailable",
+	}).Times(1).Return(nil)
+	store.EXPECT().MarkOutboxEmailSent(gomock.Any(), gomock.Any()).Times(0)
+
+	sent, err := NewOutbox(store, failingSender{}).Flush(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, sent)
+}
+
+func TestOutboxFlushClaimError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ClaimOutboxEmails(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+
+	_, err := NewOutbox(store, NewMemorySender()).Flush(context.Background())
+	require.ErrorIs(t, err, sql.ErrConnDone)
+}
+
+func TestOutboxPurge(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().DeleteFinishedOutboxEmails(gomock.Any(), gomock.Any()).Times(1).
+		DoAndReturn(func(_ context.Context, arg db.DeleteFinishedOutboxEmailsParams) (int64, error) {
+			require.WithinDuration(t, time.Now().Add(-defaultOutboxRetention), arg.CreatedBefore, time.Second)
+			require.Equal(t, int32(defaultOutboxMaxAttempts), arg.MaxAttempts)
+			return 3, nil
+		})
+
+	purged, err := NewOutbox(store, NewMemorySender()).Purge(context.Background())
+	require.NoError(t, err)
+	require.EqualValues(t, 3, purged)
+}
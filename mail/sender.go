@@ -0,0 +1,63 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// sender kinds accepted by the MAIL_SENDER setting
+const (
+	SenderSMTP   = "smtp"   // deliver through an SMTP relay
+	SenderFile   = "file"   // write .eml files to a directory, for local development
+	SenderMemory = "memory" // keep emails in memory, for tests
+)
+
+var ErrInvalidHeader = errors.New("email header must not contain line breaks")
+
+// Email is a plain text message to a single recipient
+type Email struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers emails, see NewSender
+type Sender interface {
+	Send(ctx context.Context, email Email) error
+}
+
+// Config is what a sender may need, depending on its kind
+type Config struct {
+	From         string
+	SMTPAddress  string
+	SMTPUsername string
+	SMTPPassword string
+	FileDir      string
+}
+
+// NewSender creates the sender of kind. The kind has no default: a server that silently
+// kept emails in memory would mark password resets as sent without ever delivering them.
+func NewSender(kind string, config Config) (Sender, error) {
+	switch kind {
+	case SenderSMTP:
+		return NewSMTPSender(config.SMTPAddress, config.SMTPUsername, config.SMTPPassword, config.From)
+	case SenderFile:
+		return NewFileSender(config.FileDir, config.From)
+	case SenderMemory:
+		return NewMemorySender(), nil
+	case "":
+		return nil, fmt.Errorf("mail sender is not set, use %s, %s or %s", SenderSMTP, SenderFile, SenderMemory)
+	}
+	return nil, fmt.Errorf("unsupported mail sender %q", kind)
+}
+
+func validateHeaders(values ...string) error {
+	for _, value := range values {
+		if strings.ContainsAny(value, "\r\n") {
+			return ErrInvalidHeader
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSender(t *testing.T) {
+	sender, err := NewSender(SenderMemory, Config{})
+	require.NoError(t, err)
+	require.IsType(t, &MemorySender{}, sender)
+
+	sender, err = NewSender(SenderFile, Config{FileDir: t.TempDir(), From: "bank@example.com"})
+	require.NoError(t, err)
+	require.IsType(t, &FileSender{}, sender)
+
+	sender, err = NewSender(SenderSMTP, Config{SMTPAddress: "localhost:25", From: "bank@example.com"})
+	require.NoError(t, err)
+	require.IsType(t, &SMTPSender{}, sender)
+
+	_, err = NewSender(SenderSMTP, Config{SMTPAddress: "localhost", From: "bank@example.com"})
+	require.Error(t, err)
+
+	_, err = NewSender("unknown", Config{})
+	require.Error(t, err)
+
+	// no silent fallback to memory when the setting is missing
+	_, err = NewSender("", Config{})
+	require.Error(t, err)
+}
+
+func TestBuildMessage(t *testing.T) {
+	date := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	msg, err := buildMessage("bank@example.com", Email{
+		To:      "user@example.com",
+		Subject: "Réinitialiser",
+		Body:    "hello",
+	}, date)
+	require.NoError(t, err)
+
+	text := string(msg)
+	require.Contains(t, text, "From: bank@example.com\r\n")
+	require.Contains(t, text, "To: user@example.com\r\n")
+	require.Contains(t, text, "Subject: =?utf-8?q?R=C3=A9initialiser?=\r\n")
+	require.Contains(t, text, "Date: Tue, 02 Jan 2024 03:04:05 +0000\r\n")
+	require.True(t, strings.HasSuffix(text, "\r\n\r\nhello"))
+
+	_, err = buildMessage("bank@example.com", Email{
+		To:      "user@example.com\r\nBcc: victim@example.com",
+		Subject: "hi",
+	}, date)
+	require.ErrorIs(t, err, ErrInvalidHeader)
+}
+
+func TestMemorySender(t *testing.T) {
+	sender := NewMemorySender()
+	email := Email{To: "user@example.com", Subject: "hi", Body: "hello"}
+
+	err := sender.Send(context.Background(), email)
+	require.NoError(t, err)
+	require.Equal(t, []Email{email}, sender.Emails())
+
+	err = sender.Send(context.Background(), Email{To: "user@example.com", Subject: "hi\nBcc: x"})
+	require.ErrorIs(t, err, ErrInvalidHeader)
+	require.Len(t, sender.Emails(), 1)
+}
+
+func TestFileSender(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	sender, err := NewFileSender(dir, "bank@example.com")
+	require.NoError(t, err)
+
+	err = sender.Send(context.Background(), Email{To: "user@example.com", Subject: "hi", Body: "hello"})
+	require.NoError(t, err)
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Equal(t, ".eml", filepath.Ext(files[0].Name()))
+
+	data, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	require.Contains(t, string(data), "To: user@example.com\r\n")
+	require.True(t, strings.HasSuffix(string(data), "hello"))
+}
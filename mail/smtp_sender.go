@@ -0,0 +1,48 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+)
+
+// SMTPSender delivers emails through an SMTP relay, with PLAIN auth when a username is set
+type SMTPSender struct {
+	address  string
+	from     string
+	fromAddr string
+	auth     smtp.Auth
+}
+
+func NewSMTPSender(address, username, password, from string) (*SMTPSender, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, errors.New("smtp address must be host:port")
+	}
+	fromAddr, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, errors.New("mail from is not a valid address")
+	}
+
+	sender := &SMTPSender{
+		address:  address,
+		from:     from,
+		fromAddr: fromAddr.Address,
+	}
+	if len(username) > 0 {
+		sender.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return sender, nil
+}
+
+// Send ignores ctx, net/smtp does not support cancellation
+func (s *SMTPSender) Send(_ context.Context, email Email) error {
+	msg, err := buildMessage(s.from, email, time.Now())
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(s.address, s.auth, s.fromAddr, []string{email.To}, msg)
+}
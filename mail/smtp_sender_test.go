@@ -0,0 +1,84 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// runFakeSMTPServer accepts one session on a random port and returns its address
+// and a channel delivering the commands and data the client sent
+func runFakeSMTPServer(t *testing.T) (string, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var session strings.Builder
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		reply("220 localhost ESMTP")
+		inData := false
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				break
+			}
+			session.WriteString(line)
+			switch {
+			case inData:
+				if line == ".\r\n" {
+					inData = false
+					reply("250 OK")
+				}
+			case strings.HasPrefix(line, "EHLO"):
+				reply("250 localhost")
+			case strings.HasPrefix(line, "DATA"):
+				inData = true
+				reply("354 go ahead")
+			case strings.HasPrefix(line, "QUIT"):
+				reply("221 bye")
+				received <- session.String()
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+		received <- session.String()
+	}()
+
+	return listener.Addr().String(), received
+}
+
+func TestSMTPSender(t *testing.T) {
+	address, received := runFakeSMTPServer(t)
+
+	sender, err := NewSMTPSender(address, "", "", "SimpleBank <bank@example.com>")
+	require.NoError(t, err)
+
+	err = sender.Send(context.Background(), Email{To: "user@example.com", Subject: "hi", Body: "hello"})
+	require.NoError(t, err)
+
+	session := <-received
+	require.Contains(t, session, "MAIL FROM:<bank@example.com>")
+	require.Contains(t, session, "RCPT TO:<user@example.com>")
+	require.Contains(t, session, "From: SimpleBank <bank@example.com>\r\n")
+	require.Contains(t, session, "hello")
+}
+
+func TestNewSMTPSenderInvalidFrom(t *testing.T) {
+	_, err := NewSMTPSender("localhost:25", "", "", "not an address")
+	require.Error(t, err)
+}
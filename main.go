@@ -42,7 +42,10 @@ func main() {
 		return
 	}
 
-	store := db.NewStore(conn)
+	store := db.NewStore(conn, db.TxRetryConfig{
+		MaxRetries: conf.DBTxMaxRetries,
+		Backoff:    conf.DBTxRetryBackoff,
+	})
 	server, err := api.NewServer(conf, store)
 	if err != nil {
 		log.Fatalln("server failed:", err)
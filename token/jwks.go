@@ -0,0 +1,107 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"sort"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	paserkPublicPrefix = "k4.public."
+	paserkPIDPrefix    = "k4.pid."
+)
+
+// JSONWebKey is an ed25519 public key as described by RFC 8037
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+}
+
+// JSONWebKeySet is the document served at /.well-known/jwks.json
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// PaserkKey is a PASETO v4 public key in PASERK format
+type PaserkKey struct {
+	KeyID  string `json:"kid"`
+	PID    string `json:"pid"`
+	Paserk string `json:"paserk"`
+}
+
+// PaserkKeySet is the document served at /.well-known/paserk.json
+type PaserkKeySet struct {
+	Keys []PaserkKey `json:"keys"`
+}
+
+// JWKS returns every verification key of the keyring as a JSON Web Key Set
+func (k *Keyring) JWKS() JSONWebKeySet {
+	keys := k.PublicKeys()
+	set := JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(keys))}
+	for _, kid := range sortedKeyIDs(keys) {
+		set.Keys = append(set.Keys, JSONWebKey{
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(keys[kid]),
+			KeyID:     kid,
+			Use:       "sig",
+			Algorithm: SigningMethodEdDSA.Alg(),
+		})
+	}
+	return set
+}
+
+// PASERK returns every verification key of the keyring as k4.public PASERKs
+func (k *Keyring) PASERK() PaserkKeySet {
+	keys := k.PublicKeys()
+	set := PaserkKeySet{Keys: make([]PaserkKey, 0, len(keys))}
+	for _, kid := range sortedKeyIDs(keys) {
+		paserk := paserkPublicPrefix + base64.RawURLEncoding.EncodeToString(keys[kid])
+		set.Keys = append(set.Keys, PaserkKey{
+			KeyID:  kid,
+			PID:    paserkID(paserk),
+			Paserk: paserk,
+		})
+	}
+	return set
+}
+
+// PublicKeys decodes the ed25519 keys of the set by id
+func (set JSONWebKeySet) PublicKeys() (map[string]ed25519.PublicKey, error) {
+	keys := make(map[string]ed25519.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.KeyType != "OKP" || jwk.Curve != "Ed25519" {
+			continue
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid key %s in key set", jwk.KeyID)
+		}
+		keys[jwk.KeyID] = x
+	}
+	return keys, nil
+}
+
+// paserkID computes the k4.pid of a k4.public PASERK
+func paserkID(paserk string) string {
+	hash, _ := blake2b.New(33, nil)
+	hash.Write([]byte(paserkPIDPrefix))
+	hash.Write([]byte(paserk))
+	return paserkPIDPrefix + base64.RawURLEncoding.EncodeToString(hash.Sum(nil))
+}
+
+func sortedKeyIDs(keys map[string]ed25519.PublicKey) []string {
+	kids := make([]string, 0, len(keys))
+	for kid := range keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+	return kids
+}
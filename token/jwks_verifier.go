@@ -0,0 +1,148 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultJWKSCacheTTL       = time.Hour
+	defaultJWKSRefreshBackoff = time.Minute
+)
+
+// JWKSVerifier verifies tokens issued by a remote server using the public keys
+// published at its /.well-known/jwks.json. The key set is cached for CacheTTL
+// and fetched again early when a token names an unknown key id.
+// Fetches run without holding the cache, concurrent verifications share one fetch
+// and a failed fetch is not retried before RefreshBackoff, so an unreachable issuer
+// does not stall every request.
+type JWKSVerifier struct {
+	URL            string
+	Client         *http.Client
+	CacheTTL       time.Duration
+	RefreshBackoff time.Duration
+	// Claims the remote issuer has to put in its tokens
+	Claims Claims
+
+	mu          sync.Mutex
+	keys        map[string]ed25519.PublicKey
+	fetchedAt   time.Time
+	attemptedAt time.Time // last fetch, successful or not
+	fetchErr    error     // error of the last fetch
+	inflight    *jwksFetch
+}
+
+// jwksFetch is a fetch of the key set in progress, done is closed when it finished
+type jwksFetch struct {
+	done chan struct{}
+}
+
+func NewJWKSVerifier(url string) *JWKSVerifier {
+	return &JWKSVerifier{
+		URL:            url,
+		Client:         &http.Client{Timeout: 10 * time.Second},
+		CacheTTL:       defaultJWKSCacheTTL,
+		RefreshBackoff: defaultJWKSRefreshBackoff,
+	}
+}
+
+// VerifyToken accepts both PASETO v4.public and EdDSA JWT tokens
+func (v *JWKSVerifier) VerifyToken(token string) (*Payload, error) {
+	if strings.HasPrefix(token, pasetoV4PublicHeader) {
+		return verifyPasetoPublic(token, v, v.Claims)
+	}
+	return verifyJWTEdDSA(token, v, v.Claims)
+}
+
+// PublicKey returns the cached key. A stale key is returned right away while the key set
+// is refreshed in the background, an unknown kid waits for the refresh.
+func (v *JWKSVerifier) PublicKey(kid string) (ed25519.PublicKey, error) {
+	v.mu.Lock()
+	key, ok := v.keys[kid]
+	if ok && time.Since(v.fetchedAt) < v.CacheTTL {
+		v.mu.Unlock()
+		return key, nil
+	}
+	// refresh at most once per RefreshBackoff so bad tokens cannot flood the issuer
+	// and a failing issuer is not asked on every request
+	backingOff := !v.attemptedAt.IsZero() && time.Since(v.attemptedAt) < v.RefreshBackoff
+	if ok {
+		// keep serving the stale key, also while the issuer is unreachable
+		if !backingOff {
+			v.startFetch()
+		}
+		v.mu.Unlock()
+		return key, nil
+	}
+	if backingOff && v.inflight == nil {
+		err := v.fetchErr
+		v.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrUnknownKeyID
+	}
+	fetch := v.startFetch()
+	v.mu.Unlock()
+
+	<-fetch.done
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if key, ok = v.keys[kid]; ok {
+		return key, nil
+	}
+	if v.fetchErr != nil {
+		return nil, v.fetchErr
+	}
+	return nil, ErrUnknownKeyID
+}
+
+// startFetch starts fetching the key set unless a fetch is in progress and returns it, v.mu must be held
+func (v *JWKSVerifier) startFetch() *jwksFetch {
+	if v.inflight != nil {
+		return v.inflight
+	}
+	fetch := &jwksFetch{done: make(chan struct{})}
+	v.inflight = fetch
+	v.attemptedAt = time.Now()
+
+	go func() {
+		keys, err := v.fetch()
+
+		v.mu.Lock()
+		if err == nil {
+			v.keys = keys
+			v.fetchedAt = time.Now()
+		}
+		v.fetchErr = err
+		v.attemptedAt = time.Now()
+		v.inflight = nil
+		v.mu.Unlock()
+		close(fetch.done)
+	}()
+	return fetch
+}
+
+func (v *JWKSVerifier) fetch() (map[string]ed25519.PublicKey, error) {
+	resp, err := v.Client.Get(v.URL)
+	if err != nil {
+		return nil, fmt.Errorf("fetch key set failed:%w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch key set failed: status %d", resp.StatusCode)
+	}
+
+	var set JSONWebKeySet
+	if err = json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decode key set failed:%w", err)
+	}
+	return set.PublicKeys()
+}
@@ -0,0 +1,173 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newJWKSServer(t *testing.T, keyring *Keyring) (*httptest.Server, *int32) {
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		err := json.NewEncoder(w).Encode(keyring.JWKS())
+		require.NoError(t, err)
+	}))
+	t.Cleanup(server.Close)
+	return server, &fetches
+}
+
+func TestJWKSVerifier(t *testing.T) {
+	keyring := newTestKeyring(t)
+	server, fetches := newJWKSServer(t, keyring)
+	verifier := NewJWKSVerifier(server.URL)
+
+	pasetoMaker, err := NewPasetoPublicMaker(keyring, Claims{})
+	require.NoError(t, err)
+	jwtMaker, err := NewJWTEdDSAMaker(keyring, Claims{})
+	require.NoError(t, err)
+
+	for _, maker := range []Maker{pasetoMaker, jwtMaker} {
+		username := util.RandomOwner()
+		token, _, err := maker.CreateToken(username, util.DepositorRole, time.Minute)
+		require.NoError(t, err)
+
+		payload, err := verifier.VerifyToken(token)
+		assert.NoError(t, err)
+		assert.Equal(t, username, payload.Username)
+	}
+	// the key set is cached between verifications
+	assert.Equal(t, int32(1), atomic.LoadInt32(fetches))
+}
+
+func TestJWKSVerifierRotation(t *testing.T) {
+	keyring := newTestKeyring(t)
+	server, fetches := newJWKSServer(t, keyring)
+	verifier := NewJWKSVerifier(server.URL)
+	verifier.RefreshBackoff = 0
+
+	maker, err := NewPasetoPublicMaker(keyring, Claims{})
+	require.NoError(t, err)
+
+	token, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	_, err = verifier.VerifyToken(token)
+	require.NoError(t, err)
+
+	_, newSigningKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	keyring.Rotate(newSigningKey)
+
+	// an unknown kid refreshes the cached key set
+	token, _, err = maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	_, err = verifier.VerifyToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(fetches))
+}
+
+func TestJWKSVerifierUnknownKey(t *testing.T) {
+	server, fetches := newJWKSServer(t, newTestKeyring(t))
+	verifier := NewJWKSVerifier(server.URL)
+
+	maker, err := NewJWTEdDSAMaker(newTestKeyring(t), Claims{})
+	require.NoError(t, err)
+	token, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err = verifier.VerifyToken(token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	}
+	// unknown key ids do not refetch within the back-off
+	assert.Equal(t, int32(1), atomic.LoadInt32(fetches))
+}
+
+func TestJWKSVerifierFailingIssuer(t *testing.T) {
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+	verifier := NewJWKSVerifier(server.URL)
+
+	maker, err := NewJWTEdDSAMaker(newTestKeyring(t), Claims{})
+	require.NoError(t, err)
+	token, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err = verifier.VerifyToken(token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	}
+	// a failed fetch is not retried within the back-off
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+}
+
+func TestJWKSVerifierSlowIssuer(t *testing.T) {
+	keyring := newTestKeyring(t)
+	var fetches int32
+	var slow atomic.Bool
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		if slow.Load() {
+			<-release
+		}
+		err := json.NewEncoder(w).Encode(keyring.JWKS())
+		require.NoError(t, err)
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+	verifier := NewJWKSVerifier(server.URL)
+	verifier.CacheTTL = time.Millisecond
+	verifier.RefreshBackoff = 0
+
+	maker, err := NewPasetoPublicMaker(keyring, Claims{})
+	require.NoError(t, err)
+	token, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	_, err = verifier.VerifyToken(token)
+	require.NoError(t, err)
+
+	// the cached key is stale and the issuer hangs, verifications keep using the stale key
+	slow.Store(true)
+	time.Sleep(2 * time.Millisecond)
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		_, err = verifier.VerifyToken(token)
+		assert.NoError(t, err)
+	}
+	assert.Less(t, time.Since(start), time.Second)
+	// one refresh is in flight, the others joined it
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&fetches) == 2
+	}, time.Second, time.Millisecond)
+	_, err = verifier.VerifyToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&fetches))
+}
+
+func TestKeyringPASERK(t *testing.T) {
+	keyring := newTestKeyring(t)
+	kid, signingKey := keyring.SigningKey()
+
+	set := keyring.PASERK()
+	require.Len(t, set.Keys, 1)
+	assert.Equal(t, kid, set.Keys[0].KeyID)
+	assert.Equal(t, paserkID(set.Keys[0].Paserk), set.Keys[0].PID)
+	assert.Regexp(t, "^k4\\.pid\\.[A-Za-z0-9_-]{44}$", set.Keys[0].PID)
+
+	keys, err := keyring.JWKS().PublicKeys()
+	require.NoError(t, err)
+	assert.Equal(t, signingKey.Public(), keys[kid])
+}
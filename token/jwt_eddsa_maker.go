@@ -0,0 +1,98 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// SigningMethodEd25519 implements the EdDSA (RFC 8037) jwt signing method,
+// which jwt-go v3 does not ship with
+type SigningMethodEd25519 struct{}
+
+var SigningMethodEdDSA = &SigningMethodEd25519{}
+
+func init() {
+	jwt.RegisterSigningMethod(SigningMethodEdDSA.Alg(), func() jwt.SigningMethod {
+		return SigningMethodEdDSA
+	})
+}
+
+func (m *SigningMethodEd25519) Alg() string {
+	return "EdDSA"
+}
+
+func (m *SigningMethodEd25519) Verify(signingString, signature string, key interface{}) error {
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return jwt.ErrInvalidKeyType
+	}
+	sig, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(publicKey, []byte(signingString), sig) {
+		return jwt.ErrSignatureInvalid
+	}
+	return nil
+}
+
+func (m *SigningMethodEd25519) Sign(signingString string, key interface{}) (string, error) {
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return "", jwt.ErrInvalidKeyType
+	}
+	return jwt.EncodeSegment(ed25519.Sign(privateKey, []byte(signingString))), nil
+}
+
+// JWTEdDSAMaker issues EdDSA signed JWTs with the key id in the `kid` header
+type JWTEdDSAMaker struct {
+	keyring *Keyring
+	claims  Claims
+}
+
+func (maker *JWTEdDSAMaker) CreateToken(username string, role string, duration time.Duration, opts ...PayloadOption) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, duration, opts...)
+	if err != nil {
+		return "", nil, err
+	}
+	maker.claims.apply(payload)
+	kid, signingKey := maker.keyring.SigningKey()
+
+	jwtToken := jwt.NewWithClaims(SigningMethodEdDSA, newJWTClaims(payload))
+	jwtToken.Header["kid"] = kid
+	token, err := jwtToken.SignedString(signingKey)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, payload, nil
+}
+
+func (maker *JWTEdDSAMaker) VerifyToken(token string) (*Payload, error) {
+	return verifyJWTEdDSA(token, maker.keyring, maker.claims)
+}
+
+// verifyJWTEdDSA checks an EdDSA jwt against the key named in its kid header
+func verifyJWTEdDSA(token string, keys publicKeyResolver, claims Claims) (*Payload, error) {
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*SigningMethodEd25519); !ok {
+			return nil, ErrInvalidToken
+		}
+		kid, ok := token.Header["kid"].(string)
+		if !ok {
+			return nil, ErrUnknownKeyID
+		}
+		return keys.PublicKey(kid)
+	}
+
+	return parseJWT(token, keyFunc, claims)
+}
+
+func NewJWTEdDSAMaker(keyring *Keyring, claims Claims) (Maker, error) {
+	if keyring == nil {
+		return nil, errors.New("keyring is required")
+	}
+	return &JWTEdDSAMaker{keyring: keyring, claims: claims}, nil
+}
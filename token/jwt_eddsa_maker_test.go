@@ -0,0 +1,88 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"testing"
+	"time"
+
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTEdDSAMaker(t *testing.T) {
+	maker, err := NewJWTEdDSAMaker(newTestKeyring(t), Claims{})
+	assert.NoError(t, err)
+
+	username := util.RandomOwner()
+	duration := time.Minute
+	issuedAt := time.Now()
+	expiredAt := time.Now().Add(time.Minute)
+
+	token, payload, err := maker.CreateToken(username, util.DepositorRole, duration)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+	assert.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, payload)
+	assert.NotZero(t, payload.ID)
+	assert.Equal(t, username, payload.Username)
+	assert.Equal(t, util.DepositorRole, payload.Role)
+	assert.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+	assert.WithinDuration(t, expiredAt, payload.ExpireAt, time.Second)
+}
+
+func TestExpiredJWTEdDSAMaker(t *testing.T) {
+	maker, err := NewJWTEdDSAMaker(newTestKeyring(t), Claims{})
+	assert.NoError(t, err)
+
+	token, payload, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, -time.Minute)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+	assert.NotEmpty(t, payload)
+
+	_, err = maker.VerifyToken(token)
+	assert.ErrorIs(t, err, ErrExpireToken)
+}
+
+func TestJWTEdDSAMakerRotation(t *testing.T) {
+	keyring := newTestKeyring(t)
+	maker, err := NewJWTEdDSAMaker(keyring, Claims{})
+	require.NoError(t, err)
+
+	oldToken, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	oldKID, _ := keyring.SigningKey()
+
+	_, newSigningKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	keyring.Rotate(newSigningKey)
+
+	newToken, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	_, err = maker.VerifyToken(oldToken)
+	assert.NoError(t, err)
+	_, err = maker.VerifyToken(newToken)
+	assert.NoError(t, err)
+
+	// once the old key is retired its tokens are rejected
+	require.NoError(t, keyring.RemoveVerificationKey(oldKID))
+	_, err = maker.VerifyToken(oldToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestJWTEdDSAMakerUnknownKey(t *testing.T) {
+	maker, err := NewJWTEdDSAMaker(newTestKeyring(t), Claims{})
+	require.NoError(t, err)
+	otherMaker, err := NewJWTEdDSAMaker(newTestKeyring(t), Claims{})
+	require.NoError(t, err)
+
+	token, _, err := otherMaker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	_, err = maker.VerifyToken(token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
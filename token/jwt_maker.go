@@ -1,30 +1,32 @@
 package token
 
 import (
-	"errors"
 	"fmt"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
 )
 
 var minSecretKeySize = 32
 
 type JWTMaker struct {
 	secretKey string
+	claims    Claims
 }
 
-func (maker *JWTMaker) CreateToken(username string, duration time.Duration) (string, error) {
-	payload, err := NewPayload(username, duration)
+func (maker *JWTMaker) CreateToken(username string, role string, duration time.Duration, opts ...PayloadOption) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, duration, opts...)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
+	maker.claims.apply(payload)
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, newJWTClaims(payload))
 	token, err := jwtToken.SignedString([]byte(maker.secretKey))
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
-	return token, nil
+	return token, payload, nil
 }
 
 func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
@@ -36,25 +38,108 @@ func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
 		return []byte(maker.secretKey), nil
 	}
 
-	jwtToken, err := jwt.ParseWithClaims(token, &Payload{}, keyFunc)
+	return parseJWT(token, keyFunc, maker.claims)
+}
+
+// parseJWT checks the signature with the key of keyFunc, then the claims.
+// jwt-go would check the expiry itself, but without clock skew.
+func parseJWT(token string, keyFunc jwt.Keyfunc, claims Claims) (*Payload, error) {
+	parser := &jwt.Parser{SkipClaimsValidation: true}
+	jwtToken, err := parser.ParseWithClaims(token, &jwtClaims{}, keyFunc)
 	if err != nil {
-		verr, ok := err.(*jwt.ValidationError)
-		if ok && errors.Is(verr.Inner, ErrExpireToken) {
-			return nil, ErrExpireToken
-		}
 		return nil, ErrInvalidToken
 	}
-	payload, ok := jwtToken.Claims.(*Payload)
+	tokenClaims, ok := jwtToken.Claims.(*jwtClaims)
 	if !ok {
 		return nil, ErrInvalidToken
 	}
+	payload, err := tokenClaims.payload()
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if err = payload.Verify(claims); err != nil {
+		return nil, err
+	}
 	return payload, nil
 }
 
-func NewJWTMaker(secretKey string) (Maker, error) {
+// jwtClaims is the payload under the registered claim names of RFC 7519,
+// so that other jwt libraries check the expiry, issuer and audience too
+type jwtClaims struct {
+	ID        string `json:"jti"`
+	Subject   string `json:"sub"`
+	Issuer    string `json:"iss,omitempty"`
+	Audience  string `json:"aud,omitempty"`
+	IssuedAt  int64  `json:"iat"`
+	NotBefore int64  `json:"nbf,omitempty"`
+	ExpireAt  int64  `json:"exp"`
+	Role      string `json:"role"`
+	SessionID string `json:"session_id,omitempty"`
+	Actor     string `json:"actor,omitempty"`
+	Purpose   string `json:"purpose,omitempty"`
+}
+
+func newJWTClaims(payload *Payload) *jwtClaims {
+	claims := &jwtClaims{
+		ID:       payload.ID.String(),
+		Subject:  payload.Username,
+		Issuer:   payload.Issuer,
+		Audience: payload.Audience,
+		IssuedAt: payload.IssuedAt.Unix(),
+		ExpireAt: payload.ExpireAt.Unix(),
+		Role:     payload.Role,
+		Actor:    payload.Actor,
+		Purpose:  payload.Purpose,
+	}
+	if !payload.NotBefore.IsZero() {
+		claims.NotBefore = payload.NotBefore.Unix()
+	}
+	if payload.SessionID != uuid.Nil {
+		claims.SessionID = payload.SessionID.String()
+	}
+	return claims
+}
+
+func (c *jwtClaims) payload() (*Payload, error) {
+	tokenID, err := uuid.Parse(c.ID)
+	if err != nil {
+		return nil, err
+	}
+	payload := &Payload{
+		ID:       tokenID,
+		Username: c.Subject,
+		Role:     c.Role,
+		Actor:    c.Actor,
+		Issuer:   c.Issuer,
+		Audience: c.Audience,
+		IssuedAt: time.Unix(c.IssuedAt, 0),
+		ExpireAt: time.Unix(c.ExpireAt, 0),
+		Purpose:  c.Purpose,
+	}
+	if c.NotBefore != 0 {
+		payload.NotBefore = time.Unix(c.NotBefore, 0)
+	}
+	if len(c.SessionID) > 0 {
+		if payload.SessionID, err = uuid.Parse(c.SessionID); err != nil {
+			return nil, err
+		}
+	}
+	return payload, nil
+}
+
+// Valid makes jwtClaims a jwt.Claims, parseJWT skips it to verify with clock skew
+func (c *jwtClaims) Valid() error {
+	payload, err := c.payload()
+	if err != nil {
+		return ErrInvalidToken
+	}
+	return payload.Valid()
+}
+
+func NewJWTMaker(secretKey string, claims Claims) (Maker, error) {
 	if len(secretKey) < minSecretKeySize {
 		return nil, fmt.Errorf("invalid key size: must be greater than %d length", minSecretKeySize)
 	}
 
-	return &JWTMaker{secretKey: secretKey}, nil
+	return &JWTMaker{secretKey: secretKey, claims: claims}, nil
 }
@@ -1,14 +1,19 @@
 package token
 
 import (
-	"github.com/WanCodeBase/GinModule/util"
-	"github.com/stretchr/testify/assert"
+	"encoding/json"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestJWTMaker(t *testing.T) {
-	maker, err := NewJWTMaker(util.RandomString(32))
+	maker, err := NewJWTMaker(util.RandomString(32), Claims{})
 	assert.NoError(t, err)
 
 	username := util.RandomOwner()
@@ -16,31 +21,70 @@ func TestJWTMaker(t *testing.T) {
 	issuedAt := time.Now()
 	expiredAt := time.Now().Add(time.Minute)
 
-	token, err := maker.CreateToken(username, duration)
+	token, payload, err := maker.CreateToken(username, util.DepositorRole, duration)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, token)
+	assert.NotEmpty(t, payload)
 
-	payload, err := maker.VerifyToken(token)
+	payload, err = maker.VerifyToken(token)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, payload)
 	assert.NotZero(t, payload.ID)
 	assert.Equal(t, username, payload.Username)
+	assert.Equal(t, util.DepositorRole, payload.Role)
 	assert.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
 	assert.WithinDuration(t, expiredAt, payload.ExpireAt, time.Second)
 }
 
 func TestExpiredJWTMaker(t *testing.T) {
-	maker, err := NewJWTMaker(util.RandomString(32))
+	maker, err := NewJWTMaker(util.RandomString(32), Claims{})
 	assert.NoError(t, err)
 
 	username := util.RandomOwner()
 	duration := time.Minute
 
-	token, err := maker.CreateToken(username, -duration)
+	token, payload, err := maker.CreateToken(username, util.DepositorRole, -duration)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, token)
+	assert.NotEmpty(t, payload)
 
-	payload, err := maker.VerifyToken(token)
+	payload, err = maker.VerifyToken(token)
 	assert.ErrorIs(t, ErrExpireToken, err)
 	assert.Empty(t, payload)
 }
+
+func TestJWTMakerRegisteredClaims(t *testing.T) {
+	claims := Claims{Issuer: "simplebank", Audience: "simplebank-api"}
+	maker, err := NewJWTMaker(util.RandomString(32), claims)
+	require.NoError(t, err)
+
+	username := util.RandomOwner()
+	token, payload, err := maker.CreateToken(username, util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+	segment, err := jwt.DecodeSegment(parts[1])
+	require.NoError(t, err)
+
+	var registered map[string]any
+	require.NoError(t, json.Unmarshal(segment, &registered))
+	require.Equal(t, payload.ID.String(), registered["jti"])
+	require.Equal(t, username, registered["sub"])
+	require.Equal(t, claims.Issuer, registered["iss"])
+	require.Equal(t, claims.Audience, registered["aud"])
+	require.EqualValues(t, payload.IssuedAt.Unix(), registered["iat"])
+	require.EqualValues(t, payload.NotBefore.Unix(), registered["nbf"])
+	require.EqualValues(t, payload.ExpireAt.Unix(), registered["exp"])
+	require.NotContains(t, registered, "expire_at")
+
+	// a plain jwt library reads the expiry from the registered claim
+	expired, _, err := maker.CreateToken(username, util.DepositorRole, -time.Minute)
+	require.NoError(t, err)
+	_, err = new(jwt.Parser).ParseWithClaims(expired, &jwt.StandardClaims{}, func(*jwt.Token) (interface{}, error) {
+		return []byte(maker.(*JWTMaker).secretKey), nil
+	})
+	var validationErr *jwt.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	require.NotZero(t, validationErr.Errors&jwt.ValidationErrorExpired)
+}
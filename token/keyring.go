@@ -0,0 +1,172 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+var ErrUnknownKeyID = errors.New("token key id is unknown")
+
+// publicKeyResolver finds the ed25519 key a token was signed with
+type publicKeyResolver interface {
+	PublicKey(kid string) (ed25519.PublicKey, error)
+}
+
+// Keyring holds the ed25519 key used to sign new tokens and every public key
+// that is still accepted for verification, so keys can be rotated without
+// invalidating tokens that were signed by the previous key.
+type Keyring struct {
+	mu         sync.RWMutex
+	signingKID string
+	signingKey ed25519.PrivateKey
+	publicKeys map[string]ed25519.PublicKey
+}
+
+func NewKeyring(signingKey ed25519.PrivateKey, verificationKeys ...ed25519.PublicKey) (*Keyring, error) {
+	if len(signingKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid key size: must be %d length", ed25519.PrivateKeySize)
+	}
+
+	keyring := &Keyring{
+		publicKeys: make(map[string]ed25519.PublicKey),
+	}
+	for _, key := range verificationKeys {
+		if err := keyring.AddVerificationKey(key); err != nil {
+			return nil, err
+		}
+	}
+	keyring.Rotate(signingKey)
+
+	return keyring, nil
+}
+
+// SigningKey returns the current signing key and its id
+func (k *Keyring) SigningKey() (string, ed25519.PrivateKey) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.signingKID, k.signingKey
+}
+
+// PublicKey returns the verification key with the given id
+func (k *Keyring) PublicKey(kid string) (ed25519.PublicKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	key, ok := k.publicKeys[kid]
+	if !ok {
+		return nil, ErrUnknownKeyID
+	}
+	return key, nil
+}
+
+// PublicKeys returns a copy of all verification keys by id
+func (k *Keyring) PublicKeys() map[string]ed25519.PublicKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	keys := make(map[string]ed25519.PublicKey, len(k.publicKeys))
+	for kid, key := range k.publicKeys {
+		keys[kid] = key
+	}
+	return keys
+}
+
+// AddVerificationKey accepts tokens signed by the private half of key
+func (k *Keyring) AddVerificationKey(key ed25519.PublicKey) error {
+	if len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid key size: must be %d length", ed25519.PublicKeySize)
+	}
+
+	k.mu.Lock()
+	k.publicKeys[KeyID(key)] = key
+	k.mu.Unlock()
+	return nil
+}
+
+// Rotate signs new tokens with signingKey, the previous key stays valid for verification
+func (k *Keyring) Rotate(signingKey ed25519.PrivateKey) string {
+	publicKey := signingKey.Public().(ed25519.PublicKey)
+	kid := KeyID(publicKey)
+
+	k.mu.Lock()
+	k.signingKID = kid
+	k.signingKey = signingKey
+	k.publicKeys[kid] = publicKey
+	k.mu.Unlock()
+
+	return kid
+}
+
+// RemoveVerificationKey stops accepting tokens signed with kid. The signing key cannot be removed.
+func (k *Keyring) RemoveVerificationKey(kid string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if kid == k.signingKID {
+		return errors.New("cannot remove the current signing key")
+	}
+	delete(k.publicKeys, kid)
+	return nil
+}
+
+// KeyID returns the RFC 7638 JWK thumbprint of an ed25519 public key
+func KeyID(key ed25519.PublicKey) string {
+	jwk := fmt.Sprintf(`{"crv":"Ed25519","kty":"OKP","x":"%s"}`, base64.RawURLEncoding.EncodeToString(key))
+	sum := sha256.Sum256([]byte(jwk))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// LoadKeyring reads a PKCS #8 PEM signing key and optional PKIX PEM verification keys,
+// e.g. generated by `openssl genpkey -algorithm ed25519`
+func LoadKeyring(privateKeyFile string, publicKeyFiles []string) (*Keyring, error) {
+	block, err := readPEM(privateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key %s failed:%w", privateKeyFile, err)
+	}
+	signingKey, ok := privateKey.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key %s is not an ed25519 key", privateKeyFile)
+	}
+
+	verificationKeys := make([]ed25519.PublicKey, 0, len(publicKeyFiles))
+	for _, file := range publicKeyFiles {
+		block, err := readPEM(file)
+		if err != nil {
+			return nil, err
+		}
+		publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse public key %s failed:%w", file, err)
+		}
+		verificationKey, ok := publicKey.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("public key %s is not an ed25519 key", file)
+		}
+		verificationKeys = append(verificationKeys, verificationKey)
+	}
+
+	return NewKeyring(signingKey, verificationKeys...)
+}
+
+func readPEM(file string) (*pem.Block, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read key file failed:%w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key file %s is not PEM encoded", file)
+	}
+	return block, nil
+}
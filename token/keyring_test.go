@@ -0,0 +1,81 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestKeyring(t *testing.T) *Keyring {
+	_, signingKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	keyring, err := NewKeyring(signingKey)
+	require.NoError(t, err)
+	return keyring
+}
+
+func TestKeyringRotate(t *testing.T) {
+	keyring := newTestKeyring(t)
+	oldKID, _ := keyring.SigningKey()
+
+	_, newSigningKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	newKID := keyring.Rotate(newSigningKey)
+	assert.NotEqual(t, oldKID, newKID)
+
+	kid, signingKey := keyring.SigningKey()
+	assert.Equal(t, newKID, kid)
+	assert.Equal(t, newSigningKey, signingKey)
+
+	// the old key still verifies tokens
+	_, err = keyring.PublicKey(oldKID)
+	assert.NoError(t, err)
+	assert.Len(t, keyring.PublicKeys(), 2)
+
+	assert.Error(t, keyring.RemoveVerificationKey(newKID))
+	assert.NoError(t, keyring.RemoveVerificationKey(oldKID))
+	_, err = keyring.PublicKey(oldKID)
+	assert.ErrorIs(t, err, ErrUnknownKeyID)
+}
+
+func TestLoadKeyring(t *testing.T) {
+	dir := t.TempDir()
+
+	_, signingKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(signingKey)
+	require.NoError(t, err)
+	privateKeyFile := filepath.Join(dir, "private.pem")
+	err = os.WriteFile(privateKeyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+	require.NoError(t, err)
+
+	oldPublicKey, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	der, err = x509.MarshalPKIXPublicKey(oldPublicKey)
+	require.NoError(t, err)
+	publicKeyFile := filepath.Join(dir, "public.pem")
+	err = os.WriteFile(publicKeyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600)
+	require.NoError(t, err)
+
+	keyring, err := LoadKeyring(privateKeyFile, []string{publicKeyFile})
+	require.NoError(t, err)
+
+	kid, key := keyring.SigningKey()
+	assert.Equal(t, KeyID(signingKey.Public().(ed25519.PublicKey)), kid)
+	assert.Equal(t, signingKey, key)
+
+	_, err = keyring.PublicKey(KeyID(oldPublicKey))
+	assert.NoError(t, err)
+
+	_, err = LoadKeyring(publicKeyFile, nil)
+	assert.Error(t, err)
+	_, err = LoadKeyring(filepath.Join(dir, "missing.pem"), nil)
+	assert.Error(t, err)
+}
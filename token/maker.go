@@ -3,8 +3,8 @@ package token
 import "time"
 
 type Maker interface {
-	// CreateToken returns token
-	CreateToken(username string, duration time.Duration) (string, error)
+	// CreateToken returns token and its payload
+	CreateToken(username string, role string, duration time.Duration, opts ...PayloadOption) (string, *Payload, error)
 
 	// VerifyToken verify token is valid
 	VerifyToken(token string) (*Payload, error)
@@ -0,0 +1,45 @@
+package token
+
+import (
+	"errors"
+	"time"
+)
+
+// MultiMaker creates tokens with its primary maker but also accepts tokens of the
+// other makers, e.g. while clients migrate from one token format to another
+type MultiMaker struct {
+	primary   Maker
+	verifiers []Maker
+}
+
+func (maker *MultiMaker) CreateToken(username string, role string, duration time.Duration, opts ...PayloadOption) (string, *Payload, error) {
+	return maker.primary.CreateToken(username, role, duration, opts...)
+}
+
+func (maker *MultiMaker) VerifyToken(token string) (*Payload, error) {
+	payload, err := maker.primary.VerifyToken(token)
+	if err == nil || isAuthenticTokenErr(err) {
+		return payload, err
+	}
+
+	for _, verifier := range maker.verifiers {
+		payload, err := verifier.VerifyToken(token)
+		if err == nil || isAuthenticTokenErr(err) {
+			return payload, err
+		}
+	}
+	return nil, ErrInvalidToken
+}
+
+func NewMultiMaker(primary Maker, verifiers ...Maker) Maker {
+	if len(verifiers) == 0 {
+		return primary
+	}
+	return &MultiMaker{primary: primary, verifiers: verifiers}
+}
+
+// isAuthenticTokenErr reports whether err rejects a token whose signature was valid,
+// so the other makers need not try it
+func isAuthenticTokenErr(err error) bool {
+	return errors.Is(err, ErrExpireToken) || errors.Is(err, ErrTokenNotValidYet)
+}
@@ -0,0 +1,75 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiMaker(t *testing.T) {
+	key := util.RandomString(32)
+	jwtMaker, err := NewJWTMaker(key, Claims{})
+	require.NoError(t, err)
+	pasetoMaker, err := NewPasetoMaker(key, Claims{})
+	require.NoError(t, err)
+
+	maker := NewMultiMaker(jwtMaker, pasetoMaker)
+
+	// new tokens use the primary format
+	token, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	_, err = jwtMaker.VerifyToken(token)
+	assert.NoError(t, err)
+
+	// tokens of the old format are still accepted
+	oldToken, _, err := pasetoMaker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	_, err = maker.VerifyToken(oldToken)
+	assert.NoError(t, err)
+
+	expiredToken, _, err := pasetoMaker.CreateToken(util.RandomOwner(), util.DepositorRole, -time.Minute)
+	require.NoError(t, err)
+	_, err = maker.VerifyToken(expiredToken)
+	assert.ErrorIs(t, err, ErrExpireToken)
+
+	_, err = maker.VerifyToken("invalid")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestNewMaker(t *testing.T) {
+	symmetricKey := util.RandomString(32)
+	keyring := newTestKeyring(t)
+
+	testCases := []struct {
+		name      string
+		tokenType string
+		keys      Keys
+		ok        bool
+	}{
+		{name: "Paseto", tokenType: TypePaseto, keys: Keys{SymmetricKey: symmetricKey}, ok: true},
+		{name: "PasetoShortKey", tokenType: TypePaseto, keys: Keys{SymmetricKey: "short"}},
+		{name: "JWT", tokenType: TypeJWT, keys: Keys{SymmetricKey: symmetricKey}, ok: true},
+		{name: "JWTShortKey", tokenType: TypeJWT, keys: Keys{SymmetricKey: "short"}},
+		{name: "PasetoPublic", tokenType: TypePasetoPublic, keys: Keys{Keyring: keyring}, ok: true},
+		{name: "PasetoPublicNoKeyring", tokenType: TypePasetoPublic, keys: Keys{SymmetricKey: symmetricKey}},
+		{name: "JWTEdDSA", tokenType: TypeJWTEdDSA, keys: Keys{Keyring: keyring}, ok: true},
+		{name: "JWTEdDSANoKeyring", tokenType: TypeJWTEdDSA},
+		{name: "Empty", tokenType: "", keys: Keys{SymmetricKey: symmetricKey}},
+		{name: "Unsupported", tokenType: "saml", keys: Keys{SymmetricKey: symmetricKey}},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			maker, err := NewMaker(c.tokenType, c.keys, Claims{})
+			if c.ok {
+				assert.NoError(t, err)
+				assert.NotNil(t, maker)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
@@ -10,19 +10,21 @@ import (
 type PasetoMaker struct {
 	paseto       *paseto.V2
 	symmetricKey string
+	claims       Claims
 }
 
-func (maker *PasetoMaker) CreateToken(username string, duration time.Duration) (string, error) {
-	payload, err := NewPayload(username, duration)
+func (maker *PasetoMaker) CreateToken(username string, role string, duration time.Duration, opts ...PayloadOption) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, duration, opts...)
 	if err != nil {
-		return "", nil
+		return "", nil, err
 	}
+	maker.claims.apply(payload)
 
 	token, err := maker.paseto.Encrypt([]byte(maker.symmetricKey), payload, nil)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
-	return token, nil
+	return token, payload, nil
 }
 
 func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error) {
@@ -33,11 +35,11 @@ func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error) {
 		return nil, ErrInvalidToken
 	}
 
-	err = payload.Valid()
+	err = payload.Verify(maker.claims)
 	return payload, err
 }
 
-func NewPasetoMaker(symmetricKey string) (Maker, error) {
+func NewPasetoMaker(symmetricKey string, claims Claims) (Maker, error) {
 	if len(symmetricKey) != chacha20poly1305.KeySize {
 		return nil, fmt.Errorf("invalid key size: must be %d length", chacha20poly1305.KeySize)
 	}
@@ -45,6 +47,7 @@ func NewPasetoMaker(symmetricKey string) (Maker, error) {
 	pasetoMaker := &PasetoMaker{
 		paseto:       paseto.NewV2(),
 		symmetricKey: symmetricKey,
+		claims:       claims,
 	}
 
 	return pasetoMaker, nil
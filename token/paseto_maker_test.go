@@ -2,13 +2,14 @@ package token
 
 import (
 	"github.com/WanCodeBase/GinModule/util"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"testing"
 	"time"
 )
 
 func TestPasetoMaker(t *testing.T) {
-	maker, err := NewPasetoMaker(util.RandomString(32))
+	maker, err := NewPasetoMaker(util.RandomString(32), Claims{})
 	assert.NoError(t, err)
 
 	username := util.RandomOwner()
@@ -16,29 +17,45 @@ func TestPasetoMaker(t *testing.T) {
 	issuedAt := time.Now()
 	expiredAt := time.Now().Add(time.Minute)
 
-	token, err := maker.CreateToken(username, duration)
+	token, payload, err := maker.CreateToken(username, util.DepositorRole, duration)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, token)
+	assert.NotEmpty(t, payload)
 
-	payload, err := maker.VerifyToken(token)
+	payload, err = maker.VerifyToken(token)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, payload)
 	assert.NotZero(t, payload.ID)
 	assert.Equal(t, username, payload.Username)
+	assert.Equal(t, util.DepositorRole, payload.Role)
 	assert.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
 	assert.WithinDuration(t, expiredAt, payload.ExpireAt, time.Second)
 }
 
+func TestPasetoMakerSessionID(t *testing.T) {
+	maker, err := NewPasetoMaker(util.RandomString(32), Claims{})
+	assert.NoError(t, err)
+
+	sessionID := uuid.New()
+	token, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute, WithSessionID(sessionID))
+	assert.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, sessionID, payload.SessionID)
+}
+
 func TestExpiredPasetoMaker(t *testing.T) {
-	maker, err := NewPasetoMaker(util.RandomString(32))
+	maker, err := NewPasetoMaker(util.RandomString(32), Claims{})
 	assert.NoError(t, err)
 
 	username := util.RandomOwner()
 	duration := time.Minute
 
-	token, err := maker.CreateToken(username, -duration)
+	token, payload, err := maker.CreateToken(username, util.DepositorRole, -duration)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, token)
+	assert.NotEmpty(t, payload)
 
 	_, err = maker.VerifyToken(token)
 	assert.ErrorIs(t, ErrExpireToken, err)
@@ -0,0 +1,139 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+const pasetoV4PublicHeader = "v4.public."
+
+// PasetoPublicMaker issues PASETO v4.public tokens signed with the ed25519 key of a Keyring.
+// The key id is carried in the footer as {"kid": "..."}.
+type PasetoPublicMaker struct {
+	keyring *Keyring
+	claims  Claims
+}
+
+type pasetoFooter struct {
+	KeyID string `json:"kid"`
+}
+
+func (maker *PasetoPublicMaker) CreateToken(username string, role string, duration time.Duration, opts ...PayloadOption) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, duration, opts...)
+	if err != nil {
+		return "", nil, err
+	}
+	maker.claims.apply(payload)
+
+	message, err := json.Marshal(payload)
+	if err != nil {
+		return "", nil, err
+	}
+	kid, signingKey := maker.keyring.SigningKey()
+	footer, err := json.Marshal(pasetoFooter{KeyID: kid})
+	if err != nil {
+		return "", nil, err
+	}
+
+	token := signPasetoPublic(signingKey, message, footer, nil)
+	return token, payload, nil
+}
+
+func (maker *PasetoPublicMaker) VerifyToken(token string) (*Payload, error) {
+	return verifyPasetoPublic(token, maker.keyring, maker.claims)
+}
+
+// verifyPasetoPublic checks a v4.public token against the key named in its footer
+func verifyPasetoPublic(token string, keys publicKeyResolver, claims Claims) (*Payload, error) {
+	footerKey := func(footer []byte) (ed25519.PublicKey, error) {
+		var f pasetoFooter
+		if err := json.Unmarshal(footer, &f); err != nil {
+			return nil, err
+		}
+		return keys.PublicKey(f.KeyID)
+	}
+	message, _, err := openPasetoPublic(token, footerKey, nil)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	payload := &Payload{}
+	if err = json.Unmarshal(message, payload); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	err = payload.Verify(claims)
+	return payload, err
+}
+
+// signPasetoPublic signs message as a v4.public token, an empty footer is left out of the token
+func signPasetoPublic(key ed25519.PrivateKey, message, footer, implicit []byte) string {
+	signature := ed25519.Sign(key, pae([]byte(pasetoV4PublicHeader), message, footer, implicit))
+	body := append(message[:len(message):len(message)], signature...)
+
+	token := pasetoV4PublicHeader + base64.RawURLEncoding.EncodeToString(body)
+	if len(footer) > 0 {
+		token += "." + base64.RawURLEncoding.EncodeToString(footer)
+	}
+	return token
+}
+
+// openPasetoPublic checks the signature of a v4.public token with the key that
+// keyFunc picks for its footer and returns the signed message and the footer
+func openPasetoPublic(token string, keyFunc func(footer []byte) (ed25519.PublicKey, error), implicit []byte) ([]byte, []byte, error) {
+	if !strings.HasPrefix(token, pasetoV4PublicHeader) {
+		return nil, nil, ErrInvalidToken
+	}
+	parts := strings.Split(strings.TrimPrefix(token, pasetoV4PublicHeader), ".")
+	if len(parts) > 2 {
+		return nil, nil, ErrInvalidToken
+	}
+
+	body, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || len(body) < ed25519.SignatureSize {
+		return nil, nil, ErrInvalidToken
+	}
+	var footer []byte
+	if len(parts) == 2 {
+		if footer, err = base64.RawURLEncoding.DecodeString(parts[1]); err != nil {
+			return nil, nil, ErrInvalidToken
+		}
+	}
+
+	publicKey, err := keyFunc(footer)
+	if err != nil {
+		return nil, nil, ErrInvalidToken
+	}
+
+	message := body[:len(body)-ed25519.SignatureSize]
+	signature := body[len(body)-ed25519.SignatureSize:]
+	if !ed25519.Verify(publicKey, pae([]byte(pasetoV4PublicHeader), message, footer, implicit), signature) {
+		return nil, nil, ErrInvalidToken
+	}
+	return message, footer, nil
+}
+
+// pae is the PASETO pre-authentication encoding
+func pae(pieces ...[]byte) []byte {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, uint64(len(pieces)))
+	for _, piece := range pieces {
+		size := make([]byte, 8)
+		binary.LittleEndian.PutUint64(size, uint64(len(piece)))
+		buf = append(buf, size...)
+		buf = append(buf, piece...)
+	}
+	return buf
+}
+
+func NewPasetoPublicMaker(keyring *Keyring, claims Claims) (Maker, error) {
+	if keyring == nil {
+		return nil, errors.New("keyring is required")
+	}
+	return &PasetoPublicMaker{keyring: keyring, claims: claims}, nil
+}
@@ -0,0 +1,189 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasetoPublicMaker(t *testing.T) {
+	maker, err := NewPasetoPublicMaker(newTestKeyring(t), Claims{})
+	assert.NoError(t, err)
+
+	username := util.RandomOwner()
+	duration := time.Minute
+	issuedAt := time.Now()
+	expiredAt := time.Now().Add(time.Minute)
+
+	token, payload, err := maker.CreateToken(username, util.DepositorRole, duration)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+	assert.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, payload)
+	assert.NotZero(t, payload.ID)
+	assert.Equal(t, username, payload.Username)
+	assert.Equal(t, util.DepositorRole, payload.Role)
+	assert.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+	assert.WithinDuration(t, expiredAt, payload.ExpireAt, time.Second)
+}
+
+func TestExpiredPasetoPublicMaker(t *testing.T) {
+	maker, err := NewPasetoPublicMaker(newTestKeyring(t), Claims{})
+	assert.NoError(t, err)
+
+	token, payload, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, -time.Minute)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+	assert.NotEmpty(t, payload)
+
+	_, err = maker.VerifyToken(token)
+	assert.ErrorIs(t, err, ErrExpireToken)
+}
+
+func TestPasetoPublicMakerRotation(t *testing.T) {
+	keyring := newTestKeyring(t)
+	maker, err := NewPasetoPublicMaker(keyring, Claims{})
+	require.NoError(t, err)
+
+	oldToken, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	oldKID, _ := keyring.SigningKey()
+
+	_, newSigningKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	keyring.Rotate(newSigningKey)
+
+	newToken, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	_, err = maker.VerifyToken(oldToken)
+	assert.NoError(t, err)
+	_, err = maker.VerifyToken(newToken)
+	assert.NoError(t, err)
+
+	// once the old key is retired its tokens are rejected
+	require.NoError(t, keyring.RemoveVerificationKey(oldKID))
+	_, err = maker.VerifyToken(oldToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestPasetoPublicMakerUnknownKey(t *testing.T) {
+	maker, err := NewPasetoPublicMaker(newTestKeyring(t), Claims{})
+	require.NoError(t, err)
+	otherMaker, err := NewPasetoPublicMaker(newTestKeyring(t), Claims{})
+	require.NoError(t, err)
+
+	token, _, err := otherMaker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	_, err = maker.VerifyToken(token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+// TestPasetoPublicVectors checks signing and verification against the official
+// v4.public test vectors, https://github.com/paseto-standard/test-vectors
+func TestPasetoPublicVectors(t *testing.T) {
+	seed, err := hex.DecodeString("b4cbfb43df4ce210727d953e4a713307fa19bb7d9f85041438d9e11b942a3774")
+	require.NoError(t, err)
+	secretKey := ed25519.NewKeyFromSeed(seed)
+	publicKey, err := hex.DecodeString("1eb9dbbbbc047c03fd70604e0071f0987e16b28b757225c11f00415d0e20b1a2")
+	require.NoError(t, err)
+	require.Equal(t, ed25519.PublicKey(publicKey), secretKey.Public())
+
+	testCases := []struct {
+		name       string
+		token      string
+		payload    string
+		footer     string
+		implicit   string
+		expectFail bool
+	}{
+		{
+			name:     "4-S-1",
+			token:    "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9bg_XBBzds8lTZShVlwwKSgeKpLT3yukTw6JUz3W4h_ExsQV-P0V54zemZDcAxFaSeef1QlXEFtkqxT1ciiQEDA",
+			payload:  "{\"data\":\"this is a signed message\",\"exp\":\"2022-01-01T00:00:00+00:00\"}",
+			footer:   "",
+			implicit: "",
+		},
+		{
+			name:     "4-S-2",
+			token:    "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9v3Jt8mx_TdM2ceTGoqwrh4yDFn0XsHvvV_D0DtwQxVrJEBMl0F2caAdgnpKlt4p7xBnx1HcO-SPo8FPp214HDw.eyJraWQiOiJ6VmhNaVBCUDlmUmYyc25FY1Q3Z0ZUaW9lQTlDT2NOeTlEZmdMMVc2MGhhTiJ9",
+			payload:  "{\"data\":\"this is a signed message\",\"exp\":\"2022-01-01T00:00:00+00:00\"}",
+			footer:   "{\"kid\":\"zVhMiPBP9fRf2snEcT7gFTioeA9COcNy9DfgL1W60haN\"}",
+			implicit: "",
+		},
+		{
+			name:     "4-S-3",
+			token:    "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9NPWciuD3d0o5eXJXG5pJy-DiVEoyPYWs1YSTwWHNJq6DZD3je5gf-0M4JR9ipdUSJbIovzmBECeaWmaqcaP0DQ.eyJraWQiOiJ6VmhNaVBCUDlmUmYyc25FY1Q3Z0ZUaW9lQTlDT2NOeTlEZmdMMVc2MGhhTiJ9",
+			payload:  "{\"data\":\"this is a signed message\",\"exp\":\"2022-01-01T00:00:00+00:00\"}",
+			footer:   "{\"kid\":\"zVhMiPBP9fRf2snEcT7gFTioeA9COcNy9DfgL1W60haN\"}",
+			implicit: "{\"test-vector\":\"4-S-3\"}",
+		},
+		{
+			name:       "4-F-1",
+			token:      "v4.local.vngXfCISbnKgiP6VWGuOSlYrFYU300fy9ijW33rznDYgxHNPwWluAY2Bgb0z54CUs6aYYkIJ-bOOOmJHPuX_34Agt_IPlNdGDpRdGNnBz2MpWJvB3cttheEc1uyCEYltj7wBQQYX.YXJiaXRyYXJ5LXN0cmluZy10aGF0LWlzbid0LWpzb24",
+			implicit:   "{\"test-vector\":\"4-F-1\"}",
+			expectFail: true,
+		},
+		{
+			name:       "4-F-2",
+			token:      "v4.public.eyJpbnZhbGlkIjoidGhpcyBzaG91bGQgbmV2ZXIgZGVjb2RlIn22Sp4gjCaUw0c7EH84ZSm_jN_Qr41MrgLNu5LIBCzUr1pn3Z-Wukg9h3ceplWigpoHaTLcwxj0NsI1vjTh67YB.eyJraWQiOiJ6VmhNaVBCUDlmUmYyc25FY1Q3Z0ZUaW9lQTlDT2NOeTlEZmdMMVc2MGhhTiJ9",
+			implicit:   "{\"test-vector\":\"4-F-2\"}",
+			expectFail: true,
+		},
+		{
+			name:       "4-F-3",
+			token:      "v3.local.23e_2PiqpQBPvRFKzB0zHhjmxK3sKo2grFZRRLM-U7L0a8uHxuF9RlVz3Ic6WmdUUWTxCaYycwWV1yM8gKbZB2JhygDMKvHQ7eBf8GtF0r3K0Q_gF1PXOxcOgztak1eD1dPe9rLVMSgR0nHJXeIGYVuVrVoLWQ.YXJiaXRyYXJ5LXN0cmluZy10aGF0LWlzbid0LWpzb24",
+			implicit:   "{\"test-vector\":\"4-F-3\"}",
+			expectFail: true,
+		},
+		{
+			name:       "4-F-4",
+			token:      "v4.local.AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAQAr68PS4AXe7If_ZgesdkUMvSwscFlAl1pk5HC0e8kApeaqMfGo_7OpBnwJOAbY9V7WU6abu74MmcUE8YWAiaArVI8XJ5hOb_4v9RmDkneN0S92dx0OW4pgy7omxgf3S8c3LlQh",
+			implicit:   "",
+			expectFail: true,
+		},
+		{
+			name:       "4-F-5",
+			token:      "v4.local.32VIErrEkmY4JVILovbmfPXKW9wT1OdQepjMTC_MOtjA4kiqw7_tcaOM5GNEcnTxl60WkwMsYXw6FSNb_UdJPXjpzm0KW9ojM5f4O2mRvE2IcweP-PRdoHjd5-RHCiExR1IK6t4x-RMNXtQNbz7FvFZ_G-lFpk5RG3EOrwDL6CgDqcerSQ==.eyJraWQiOiJ6VmhNaVBCUDlmUmYyc25FY1Q3Z0ZUaW9lQTlDT2NOeTlEZmdMMVc2MGhhTiJ9",
+			implicit:   "",
+			expectFail: true,
+		},
+	}
+
+	keyFunc := func([]byte) (ed25519.PublicKey, error) {
+		return publicKey, nil
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			message, footer, err := openPasetoPublic(c.token, keyFunc, []byte(c.implicit))
+			if c.expectFail {
+				require.ErrorIs(t, err, ErrInvalidToken)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.payload, string(message))
+			require.Equal(t, c.footer, string(footer))
+
+			token := signPasetoPublic(secretKey, []byte(c.payload), []byte(c.footer), []byte(c.implicit))
+			require.Equal(t, c.token, token)
+
+			_, _, err = openPasetoPublic(c.token, keyFunc, []byte("{\"test-vector\":\"other\"}"))
+			require.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+}
+
+func TestPAE(t *testing.T) {
+	require.Equal(t, []byte("\x00\x00\x00\x00\x00\x00\x00\x00"), pae())
+	require.Equal(t, []byte("\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"), pae([]byte{}))
+	require.Equal(t, []byte("\x01\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00test"), pae([]byte("test")))
+}
@@ -8,35 +8,113 @@ import (
 )
 
 var (
-	ErrInvalidToken = errors.New("token is invalid")
-	ErrExpireToken  = errors.New("token has expired")
+	ErrInvalidToken     = errors.New("token is invalid")
+	ErrExpireToken      = errors.New("token has expired")
+	ErrTokenNotValidYet = errors.New("token is not valid yet")
 )
 
 type Payload struct {
-	ID       uuid.UUID `json:"id"`
-	Username string    `json:"username"`
-	IssuedAt time.Time `json:"issued_at"`
-	ExpireAt time.Time `json:"expire_at"`
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	SessionID uuid.UUID `json:"session_id"`
+	// Actor is the admin acting as Username in an impersonation token, empty otherwise
+	Actor     string    `json:"actor,omitempty"`
+	Issuer    string    `json:"issuer"`
+	Audience  string    `json:"audience"`
+	IssuedAt  time.Time `json:"issued_at"`
+	NotBefore time.Time `json:"not_before"`
+	ExpireAt  time.Time `json:"expire_at"`
+	// Purpose restricts the token to one use such as a refresh token or a magic link, access tokens have none
+	Purpose string `json:"purpose,omitempty"`
 }
 
+// Claims are the registered claims a maker puts in every token it creates
+// and requires in every token it verifies. Empty values are neither set nor checked.
+type Claims struct {
+	Issuer   string
+	Audience string
+	// ClockSkew is how far the clocks of the issuer and the verifier may drift apart,
+	// it extends the expiry and advances the not-before time
+	ClockSkew time.Duration
+}
+
+// apply sets the issuer and audience of a new token
+func (c Claims) apply(payload *Payload) {
+	payload.Issuer = c.Issuer
+	payload.Audience = c.Audience
+}
+
+// PayloadOption sets an optional claim of a new token
+type PayloadOption func(*Payload)
+
+// WithSessionID ties the token to a login session, revoking the session revokes the token
+func WithSessionID(sessionID uuid.UUID) PayloadOption {
+	return func(p *Payload) {
+		p.SessionID = sessionID
+	}
+}
+
+// WithActor makes an impersonation token, actor acts as the username of the token
+func WithActor(actor string) PayloadOption {
+	return func(p *Payload) {
+		p.Actor = actor
+	}
+}
+
+// WithPurpose makes a token that is only good for purpose and never authorizes a request
+func WithPurpose(purpose string) PayloadOption {
+	return func(p *Payload) {
+		p.Purpose = purpose
+	}
+}
+
+// IsImpersonated reports whether an admin acts as the user of the token
+func (p Payload) IsImpersonated() bool {
+	return len(p.Actor) > 0
+}
+
+// Valid checks the time claims without tolerance, makers verify with Verify
 func (p Payload) Valid() error {
-	if time.Now().After(p.ExpireAt) {
+	return p.Verify(Claims{})
+}
+
+// Verify checks the time claims with the clock skew of claims and that the token
+// was issued by and for the expected parties
+func (p Payload) Verify(claims Claims) error {
+	now := time.Now()
+	if now.After(p.ExpireAt.Add(claims.ClockSkew)) {
 		return ErrExpireToken
 	}
-
+	// tokens issued before not-before was introduced have none
+	if !p.NotBefore.IsZero() && now.Add(claims.ClockSkew).Before(p.NotBefore) {
+		return ErrTokenNotValidYet
+	}
+	if len(claims.Issuer) > 0 && p.Issuer != claims.Issuer {
+		return ErrInvalidToken
+	}
+	if len(claims.Audience) > 0 && p.Audience != claims.Audience {
+		return ErrInvalidToken
+	}
 	return nil
 }
 
-func NewPayload(username string, duration time.Duration) (*Payload, error) {
+func NewPayload(username string, role string, duration time.Duration, opts ...PayloadOption) (*Payload, error) {
 	tokenID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
 	}
+	now := time.Now()
 	payload := &Payload{
-		ID:       tokenID,
-		Username: username,
-		IssuedAt: time.Now(),
-		ExpireAt: time.Now().Add(duration),
+		ID:        tokenID,
+		Username:  username,
+		Role:      role,
+		IssuedAt:  now,
+		NotBefore: now,
+		ExpireAt:  now.Add(duration),
+	}
+	for _, opt := range opts {
+		opt(payload)
 	}
 
 	return payload, nil
@@ -0,0 +1,119 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WanCodeBase/GinModule/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakerClaims(t *testing.T) {
+	keys := Keys{SymmetricKey: util.RandomString(32), Keyring: newTestKeyring(t)}
+	claims := Claims{Issuer: "simplebank", Audience: "simplebank-api", ClockSkew: 30 * time.Second}
+
+	notBefore := func(nbf time.Time) PayloadOption {
+		return func(p *Payload) {
+			p.NotBefore = nbf
+		}
+	}
+
+	testCases := []struct {
+		name        string
+		issueClaims Claims
+		duration    time.Duration
+		opts        []PayloadOption
+		err         error
+	}{
+		{
+			name:        "OK",
+			issueClaims: claims,
+			duration:    time.Minute,
+		},
+		{
+			name:        "OtherIssuer",
+			issueClaims: Claims{Issuer: "other", Audience: claims.Audience},
+			duration:    time.Minute,
+			err:         ErrInvalidToken,
+		},
+		{
+			name:        "OtherAudience",
+			issueClaims: Claims{Issuer: claims.Issuer, Audience: "other-api"},
+			duration:    time.Minute,
+			err:         ErrInvalidToken,
+		},
+		{
+			name:        "NoClaims",
+			issueClaims: Claims{},
+			duration:    time.Minute,
+			err:         ErrInvalidToken,
+		},
+		{
+			name:        "ExpiredWithinClockSkew",
+			issueClaims: claims,
+			duration:    -10 * time.Second,
+		},
+		{
+			name:        "Expired",
+			issueClaims: claims,
+			duration:    -time.Minute,
+			err:         ErrExpireToken,
+		},
+		{
+			name:        "NotBeforeWithinClockSkew",
+			issueClaims: claims,
+			duration:    time.Minute,
+			opts:        []PayloadOption{notBefore(time.Now().Add(10 * time.Second))},
+		},
+		{
+			name:        "NotValidYet",
+			issueClaims: claims,
+			duration:    time.Minute,
+			opts:        []PayloadOption{notBefore(time.Now().Add(time.Minute))},
+			err:         ErrTokenNotValidYet,
+		},
+	}
+
+	for _, tokenType := range []string{TypePaseto, TypeJWT, TypePasetoPublic, TypeJWTEdDSA} {
+		for _, c := range testCases {
+			t.Run(tokenType+"/"+c.name, func(t *testing.T) {
+				issuer, err := NewMaker(tokenType, keys, c.issueClaims)
+				require.NoError(t, err)
+				verifier, err := NewMaker(tokenType, keys, claims)
+				require.NoError(t, err)
+
+				token, payload, err := issuer.CreateToken(util.RandomOwner(), util.DepositorRole, c.duration, c.opts...)
+				require.NoError(t, err)
+				require.Equal(t, c.issueClaims.Issuer, payload.Issuer)
+				require.Equal(t, c.issueClaims.Audience, payload.Audience)
+
+				payload, err = verifier.VerifyToken(token)
+				if c.err != nil {
+					require.ErrorIs(t, err, c.err)
+					return
+				}
+				require.NoError(t, err)
+				require.Equal(t, claims.Issuer, payload.Issuer)
+				require.Equal(t, claims.Audience, payload.Audience)
+				require.False(t, payload.NotBefore.IsZero())
+			})
+		}
+	}
+}
+
+func TestPayloadValid(t *testing.T) {
+	payload, err := NewPayload(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, payload.IssuedAt, payload.NotBefore)
+	require.NoError(t, payload.Valid())
+
+	// Valid has no tolerance
+	payload.ExpireAt = time.Now().Add(-time.Second)
+	require.ErrorIs(t, payload.Valid(), ErrExpireToken)
+	require.NoError(t, payload.Verify(Claims{ClockSkew: time.Minute}))
+
+	// tokens issued before not-before existed
+	payload.ExpireAt = time.Now().Add(time.Minute)
+	payload.NotBefore = time.Time{}
+	require.NoError(t, payload.Valid())
+}
@@ -0,0 +1,47 @@
+package token
+
+import (
+	"errors"
+	"fmt"
+)
+
+// token types accepted by the TOKEN_TYPE setting
+const (
+	TypePaseto       = "paseto"        // PASETO v2.local, symmetric key
+	TypeJWT          = "jwt"           // JWT HS256, symmetric key
+	TypePasetoPublic = "paseto_public" // PASETO v4.public, ed25519 keyring
+	TypeJWTEdDSA     = "jwt_eddsa"     // JWT EdDSA, ed25519 keyring
+)
+
+// Keys is the key material a maker may need, depending on its type
+type Keys struct {
+	SymmetricKey string
+	Keyring      *Keyring
+}
+
+// IsAsymmetricType reports whether tokenType signs with the ed25519 keyring
+func IsAsymmetricType(tokenType string) bool {
+	return tokenType == TypePasetoPublic || tokenType == TypeJWTEdDSA
+}
+
+// NewMaker creates the maker of tokenType and checks that its key material is usable.
+// The maker sets claims in new tokens and requires them in verified ones.
+func NewMaker(tokenType string, keys Keys, claims Claims) (Maker, error) {
+	if IsAsymmetricType(tokenType) && keys.Keyring == nil {
+		return nil, fmt.Errorf("token type %s requires a private key file", tokenType)
+	}
+
+	switch tokenType {
+	case TypePaseto:
+		return NewPasetoMaker(keys.SymmetricKey, claims)
+	case TypeJWT:
+		return NewJWTMaker(keys.SymmetricKey, claims)
+	case TypePasetoPublic:
+		return NewPasetoPublicMaker(keys.Keyring, claims)
+	case TypeJWTEdDSA:
+		return NewJWTEdDSAMaker(keys.Keyring, claims)
+	case "":
+		return nil, errors.New("token type is required")
+	}
+	return nil, fmt.Errorf("unsupported token type %q", tokenType)
+}
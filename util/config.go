@@ -6,11 +6,66 @@ import (
 )
 
 type Config struct {
-	DBDriver             string        `mapstructure:"DB_DRIVER"`
-	DBSource             string        `mapstructure:"DB_SOURCE"`
-	ServerAddress        string        `mapstructure:"SERVER_ADDRESS"`
-	TokenSymmetricKey    string        `mapstructure:"Token_SYMMETRIC_Key"`
-	TokenExpiredDuration time.Duration `mapstructure:"Token_EXPRIED_DURATION"`
+	DBDriver                 string        `mapstructure:"DB_DRIVER"`
+	DBSource                 string        `mapstructure:"DB_SOURCE"`
+	DBTxMaxRetries           int           `mapstructure:"DB_TX_MAX_RETRIES"`
+	DBTxRetryBackoff         time.Duration `mapstructure:"DB_TX_RETRY_BACKOFF"`
+	ServerAddress            string        `mapstructure:"SERVER_ADDRESS"`
+	TokenType                string        `mapstructure:"TOKEN_TYPE"`
+	TokenVerifyTypes         []string      `mapstructure:"TOKEN_VERIFY_TYPES"`
+	TokenSymmetricKey        string        `mapstructure:"Token_SYMMETRIC_Key"`
+	TokenPrivateKeyFile      string        `mapstructure:"TOKEN_PRIVATE_KEY_FILE"`
+	TokenPublicKeyFiles      []string      `mapstructure:"TOKEN_PUBLIC_KEY_FILES"`
+	TokenIssuer              string        `mapstructure:"TOKEN_ISSUER"`
+	TokenAudience            string        `mapstructure:"TOKEN_AUDIENCE"`
+	TokenClockSkew           time.Duration `mapstructure:"TOKEN_CLOCK_SKEW"`
+	AuthCookieEnabled        bool          `mapstructure:"AUTH_COOKIE_ENABLED"`
+	AuthCookieName           string        `mapstructure:"AUTH_COOKIE_NAME"`
+	AuthCookieDomain         string        `mapstructure:"AUTH_COOKIE_DOMAIN"`
+	AuthCookieSameSite       string        `mapstructure:"AUTH_COOKIE_SAME_SITE"`
+	ImpersonationDuration    time.Duration `mapstructure:"IMPERSONATION_DURATION"`
+	ImpersonationCanTransfer bool          `mapstructure:"IMPERSONATION_CAN_TRANSFER"`
+	WebAuthnRPID             string        `mapstructure:"WEBAUTHN_RP_ID"`
+	WebAuthnRPDisplayName    string        `mapstructure:"WEBAUTHN_RP_DISPLAY_NAME"`
+	WebAuthnRPOrigins        []string      `mapstructure:"WEBAUTHN_RP_ORIGINS"`
+	TokenExpiredDuration     time.Duration `mapstructure:"Token_EXPRIED_DURATION"`
+	RefreshTokenDuration     time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	RevocationPurgeInterval  time.Duration `mapstructure:"REVOCATION_PURGE_INTERVAL"`
+	// RevocationReloadInterval is how stale the revocations of other server instances may be:
+	// a token revoked on one instance is still accepted by the others for up to this long
+	RevocationReloadInterval time.Duration `mapstructure:"REVOCATION_RELOAD_INTERVAL"`
+	TOTPIssuer               string        `mapstructure:"TOTP_ISSUER"`
+	LoginChallengeDuration   time.Duration `mapstructure:"LOGIN_CHALLENGE_DURATION"`
+	LoginMaxFailedAttempts   int32         `mapstructure:"LOGIN_MAX_FAILED_ATTEMPTS"`
+	LoginIPMaxFailedAttempts int32         `mapstructure:"LOGIN_IP_MAX_FAILED_ATTEMPTS"`
+	LoginBackoffBase         time.Duration `mapstructure:"LOGIN_BACKOFF_BASE"`
+	LoginLockoutDuration     time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION"`
+	MailSender               string        `mapstructure:"MAIL_SENDER"`
+	MailFrom                 string        `mapstructure:"MAIL_FROM"`
+	MailFileDir              string        `mapstructure:"MAIL_FILE_DIR"`
+	SMTPAddress              string        `mapstructure:"SMTP_ADDRESS"`
+	SMTPUsername             string        `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword             string        `mapstructure:"SMTP_PASSWORD"`
+	OutboxInterval           time.Duration `mapstructure:"OUTBOX_INTERVAL"`
+	PasswordResetURL         string        `mapstructure:"PASSWORD_RESET_URL"`
+	PasswordResetDuration    time.Duration `mapstructure:"PASSWORD_RESET_DURATION"`
+	VerifyEmailURL           string        `mapstructure:"VERIFY_EMAIL_URL"`
+	VerifyEmailDuration      time.Duration `mapstructure:"VERIFY_EMAIL_DURATION"`
+	MagicLinkURL             string        `mapstructure:"MAGIC_LINK_URL"`
+	MagicLinkDuration        time.Duration `mapstructure:"MAGIC_LINK_DURATION"`
+	RequireVerifiedEmail     bool          `mapstructure:"REQUIRE_VERIFIED_EMAIL"`
+	PasswordHasher           string        `mapstructure:"PASSWORD_HASHER"`
+	BcryptCost               int           `mapstructure:"BCRYPT_COST"`
+	Argon2Memory             uint32        `mapstructure:"ARGON2_MEMORY"`
+	Argon2Iterations         uint32        `mapstructure:"ARGON2_ITERATIONS"`
+	Argon2Parallelism        uint8         `mapstructure:"ARGON2_PARALLELISM"`
+	PasswordMinLength        int           `mapstructure:"PASSWORD_MIN_LENGTH"`
+	PasswordRequireUpper     bool          `mapstructure:"PASSWORD_REQUIRE_UPPER"`
+	PasswordRequireLower     bool          `mapstructure:"PASSWORD_REQUIRE_LOWER"`
+	PasswordRequireDigit     bool          `mapstructure:"PASSWORD_REQUIRE_DIGIT"`
+	PasswordRequireSymbol    bool          `mapstructure:"PASSWORD_REQUIRE_SYMBOL"`
+	PasswordBannedListFile   string        `mapstructure:"PASSWORD_BANNED_LIST_FILE"`
+	LoginNotifier            string        `mapstructure:"LOGIN_NOTIFIER"`
 }
 
 func LoadConfig(path string) (c Config, err error) {
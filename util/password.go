@@ -1,19 +1,192 @@
 package util
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// HashedPassword returns a bcrypt hash password
+// password hashing algorithms accepted by the PASSWORD_HASHER setting
+const (
+	BcryptAlgorithm   = "bcrypt"
+	Argon2idAlgorithm = "argon2id"
+)
+
+var (
+	// ErrMismatchedPassword is returned by CheckPassword for a wrong password, whatever the algorithm
+	ErrMismatchedPassword  = bcrypt.ErrMismatchedHashAndPassword
+	ErrUnknownPasswordHash = errors.New("unknown password hash format")
+)
+
+// PasswordHasher hashes new passwords with one algorithm and its parameters.
+// The algorithm and parameters are encoded in the hash, so CheckPassword can verify
+// hashes of any hasher and a policy change only needs NeedsRehash on the next login.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	// NeedsRehash reports whether hashedPassword was made with another algorithm or other parameters
+	NeedsRehash(hashedPassword string) bool
+}
+
+// NewPasswordHasher creates the hasher configured by PASSWORD_HASHER, bcrypt by default
+func NewPasswordHasher(config Config) (PasswordHasher, error) {
+	switch config.PasswordHasher {
+	case BcryptAlgorithm, "":
+		cost := config.BcryptCost
+		if cost == 0 {
+			cost = bcrypt.DefaultCost
+		}
+		if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+		return BcryptHasher{Cost: cost}, nil
+	case Argon2idAlgorithm:
+		hasher := DefaultArgon2idHasher
+		if config.Argon2Memory > 0 {
+			hasher.Memory = config.Argon2Memory
+		}
+		if config.Argon2Iterations > 0 {
+			hasher.Iterations = config.Argon2Iterations
+		}
+		if config.Argon2Parallelism > 0 {
+			hasher.Parallelism = config.Argon2Parallelism
+		}
+		return hasher, nil
+	}
+	return nil, fmt.Errorf("unsupported password hasher %q", config.PasswordHasher)
+}
+
+// HashedPassword returns a bcrypt hash password at the default cost.
+// Servers hash with the PasswordHasher of their config.
 func HashedPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return BcryptHasher{Cost: bcrypt.DefaultCost}.Hash(password)
+}
+
+// CheckPassword compares password with a hash of any supported algorithm
+func CheckPassword(password, hashedPassword string) error {
+	switch {
+	case isBcryptHash(hashedPassword):
+		return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	case strings.HasPrefix(hashedPassword, "$argon2id$"):
+		return checkArgon2id(password, hashedPassword)
+	}
+	return ErrUnknownPasswordHash
+}
+
+// BcryptHasher hashes with bcrypt, the cost is part of the hash
+type BcryptHasher struct {
+	Cost int
+}
+
+func (h BcryptHasher) Hash(password string) (string, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate hashed password:%s", err)
 	}
 	return string(hashedPassword), nil
 }
 
-func CheckPassword(password, hashedPassword string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+func (h BcryptHasher) NeedsRehash(hashedPassword string) bool {
+	if !isBcryptHash(hashedPassword) {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hashedPassword))
+	return err != nil || cost != h.Cost
+}
+
+func isBcryptHash(hashedPassword string) bool {
+	return strings.HasPrefix(hashedPassword, "$2a$") ||
+		strings.HasPrefix(hashedPassword, "$2b$") ||
+		strings.HasPrefix(hashedPassword, "$2y$")
+}
+
+// Argon2idHasher hashes with argon2id into the PHC string format:
+// $argon2id$v=19$m=<memory KiB>,t=<iterations>,p=<parallelism>$<salt>$<key>
+type Argon2idHasher struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2idHasher follows the second recommended option of RFC 9106
+var DefaultArgon2idHasher = Argon2idHasher{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 4,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+func (h Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate hashed password:%s", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, h.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.Memory, h.Iterations, h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h Argon2idHasher) NeedsRehash(hashedPassword string) bool {
+	params, salt, key, err := decodeArgon2id(hashedPassword)
+	if err != nil {
+		return true
+	}
+	return params.Memory != h.Memory ||
+		params.Iterations != h.Iterations ||
+		params.Parallelism != h.Parallelism ||
+		uint32(len(salt)) != h.SaltLength ||
+		uint32(len(key)) != h.KeyLength
+}
+
+func checkArgon2id(password, hashedPassword string) error {
+	params, salt, key, err := decodeArgon2id(hashedPassword)
+	if err != nil {
+		return err
+	}
+	otherKey := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, otherKey) != 1 {
+		return ErrMismatchedPassword
+	}
+	return nil
+}
+
+func decodeArgon2id(hashedPassword string) (params Argon2idHasher, salt, key []byte, err error) {
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 || parts[1] != Argon2idAlgorithm {
+		err = ErrUnknownPasswordHash
+		return
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		err = fmt.Errorf("unsupported argon2 version %q", parts[2])
+		return
+	}
+	if _, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		err = fmt.Errorf("invalid argon2 parameters %q", parts[3])
+		return
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return
+	}
+	// an empty key would match any password
+	if len(salt) == 0 || len(key) == 0 || params.Iterations == 0 || params.Parallelism == 0 {
+		err = ErrUnknownPasswordHash
+	}
+	return
 }
@@ -0,0 +1,170 @@
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	DefaultPasswordMinLength = 8
+	// MaxPasswordLength is the longest password bcrypt hashes without truncating it
+	MaxPasswordLength = 72
+
+	// shorter personal values would match too many passwords by accident
+	minPersonalInfoLength = 3
+)
+
+// PasswordPolicyError lists every rule a password breaks, each worded to follow the field name
+type PasswordPolicyError struct {
+	Violations []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return strings.Join(e.Violations, ", ")
+}
+
+// PasswordPolicy decides whether a new password is strong enough.
+// Logging in is never checked against it, so tightening the policy does not lock anybody out.
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+
+	banned map[string]struct{}
+}
+
+// NewPasswordPolicy creates the policy of config and loads its banned password list, if any
+func NewPasswordPolicy(config Config) (*PasswordPolicy, error) {
+	policy := &PasswordPolicy{
+		MinLength:     config.PasswordMinLength,
+		RequireUpper:  config.PasswordRequireUpper,
+		RequireLower:  config.PasswordRequireLower,
+		RequireDigit:  config.PasswordRequireDigit,
+		RequireSymbol: config.PasswordRequireSymbol,
+	}
+	if policy.MinLength <= 0 {
+		policy.MinLength = DefaultPasswordMinLength
+	}
+	if policy.MinLength > MaxPasswordLength {
+		return nil, fmt.Errorf("password min length must not exceed %d", MaxPasswordLength)
+	}
+
+	if len(config.PasswordBannedListFile) > 0 {
+		banned, err := LoadBannedPasswords(config.PasswordBannedListFile)
+		if err != nil {
+			return nil, err
+		}
+		policy.banned = banned
+	}
+	return policy, nil
+}
+
+// LoadBannedPasswords reads a file of one password per line, blank lines and lines starting with # are skipped
+func LoadBannedPasswords(file string) (map[string]struct{}, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("open banned password list failed:%w", err)
+	}
+	defer f.Close()
+
+	banned := make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		banned[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read banned password list failed:%w", err)
+	}
+	return banned, nil
+}
+
+// Ban adds passwords to the banned list
+func (p *PasswordPolicy) Ban(passwords ...string) {
+	if p.banned == nil {
+		p.banned = make(map[string]struct{}, len(passwords))
+	}
+	for _, password := range passwords {
+		p.banned[strings.ToLower(password)] = struct{}{}
+	}
+}
+
+// Check returns a *PasswordPolicyError if password breaks the policy.
+// personalInfo are values of the account, like the username and email, the password must not contain.
+func (p *PasswordPolicy) Check(password string, personalInfo ...string) error {
+	var violations []string
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+	if len(password) > MaxPasswordLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d bytes long", MaxPasswordLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireUpper && !hasUpper {
+		violations = append(violations, "must contain an uppercase letter")
+	}
+	if p.RequireLower && !hasLower {
+		violations = append(violations, "must contain a lowercase letter")
+	}
+	if p.RequireDigit && !hasDigit {
+		violations = append(violations, "must contain a digit")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		violations = append(violations, "must contain a symbol")
+	}
+
+	lowerPassword := strings.ToLower(password)
+	if _, ok := p.banned[lowerPassword]; ok {
+		violations = append(violations, "is too common")
+	}
+	if containsPersonalInfo(lowerPassword, personalInfo) {
+		violations = append(violations, "must not contain the username or email")
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// containsPersonalInfo reports whether lowerPassword contains one of the values,
+// for an email its local part is enough
+func containsPersonalInfo(lowerPassword string, personalInfo []string) bool {
+	for _, info := range personalInfo {
+		info = strings.ToLower(info)
+		values := []string{info}
+		if at := strings.LastIndex(info, "@"); at > 0 {
+			values = append(values, info[:at])
+		}
+		for _, value := range values {
+			if len(value) >= minPersonalInfoLength && strings.Contains(lowerPassword, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	policy, err := NewPasswordPolicy(Config{
+		PasswordMinLength:     10,
+		PasswordRequireUpper:  true,
+		PasswordRequireLower:  true,
+		PasswordRequireDigit:  true,
+		PasswordRequireSymbol: true,
+	})
+	require.NoError(t, err)
+	policy.Ban("Correct-Horse-42")
+
+	testCases := []struct {
+		name       string
+		password   string
+		violations []string
+	}{
+		{
+			name:     "OK",
+			password: "Tr0ub4dor&3x",
+		},
+		{
+			name:       "TooShort",
+			password:   "Ab1!",
+			violations: []string{"must be at least 10 characters long"},
+		},
+		{
+			name:       "TooLong",
+			password:   "Ab1!" + RandomString(MaxPasswordLength),
+			violations: []string{"must be at most 72 bytes long"},
+		},
+		{
+			name:     "MissingClasses",
+			password: "onlylowercase",
+			violations: []string{
+				"must contain an uppercase letter",
+				"must contain a digit",
+				"must contain a symbol",
+			},
+		},
+		{
+			name:       "Banned",
+			password:   "correct-horse-42",
+			violations: []string{"must contain an uppercase letter", "is too common"},
+		},
+		{
+			name:       "ContainsUsername",
+			password:   "My-Alice-Pass-1",
+			violations: []string{"must not contain the username or email"},
+		},
+		{
+			name:       "ContainsEmailLocalPart",
+			password:   "Bob.Smith-2024",
+			violations: []string{"must not contain the username or email"},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			err := policy.Check(c.password, "alice", "bob.smith@example.com")
+			if len(c.violations) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			var policyErr *PasswordPolicyError
+			require.ErrorAs(t, err, &policyErr)
+			require.Equal(t, c.violations, policyErr.Violations)
+		})
+	}
+}
+
+func TestPasswordPolicyShortPersonalInfo(t *testing.T) {
+	policy, err := NewPasswordPolicy(Config{})
+	require.NoError(t, err)
+	require.Equal(t, DefaultPasswordMinLength, policy.MinLength)
+
+	// a two letter username would forbid too many passwords
+	require.NoError(t, policy.Check("jostlingly", "jo", "jo@example.com"))
+}
+
+func TestLoadBannedPasswords(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "banned.txt")
+	err := os.WriteFile(file, []byte("# comment\n\nPassword1\n  letmein  \n"), 0o600)
+	require.NoError(t, err)
+
+	policy, err := NewPasswordPolicy(Config{PasswordMinLength: 6, PasswordBannedListFile: file})
+	require.NoError(t, err)
+	require.EqualError(t, policy.Check("PASSWORD1"), "is too common")
+	require.EqualError(t, policy.Check("LetMeIn"), "is too common")
+	require.NoError(t, policy.Check("# comment"))
+
+	_, err = NewPasswordPolicy(Config{PasswordBannedListFile: filepath.Join(t.TempDir(), "missing.txt")})
+	require.Error(t, err)
+
+	// the list shipped with the repository
+	banned, err := LoadBannedPasswords("../banned_passwords.txt")
+	require.NoError(t, err)
+	require.Contains(t, banned, "password123")
+}
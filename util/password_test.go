@@ -1,6 +1,7 @@
 package util
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -20,3 +21,78 @@ func TestPassword(t *testing.T) {
 	err = CheckPassword(password2, hashedPassword)
 	assert.EqualError(t, err, bcrypt.ErrMismatchedHashAndPassword.Error())
 }
+
+// small parameters keep the tests fast
+var testArgon2idHasher = Argon2idHasher{
+	Memory:      1024,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+func TestArgon2idPassword(t *testing.T) {
+	password1 := RandomString(6)
+	hashedPassword, err := testArgon2idHasher.Hash(password1)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hashedPassword, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+	err = CheckPassword(password1, hashedPassword)
+	assert.NoError(t, err)
+
+	err = CheckPassword(RandomString(6), hashedPassword)
+	assert.ErrorIs(t, err, ErrMismatchedPassword)
+
+	// salted, the same password never hashes the same
+	hashedPassword2, err := testArgon2idHasher.Hash(password1)
+	assert.NoError(t, err)
+	assert.NotEqual(t, hashedPassword, hashedPassword2)
+}
+
+func TestCheckPasswordInvalidHash(t *testing.T) {
+	for _, hashedPassword := range []string{
+		"",
+		"plaintext",
+		"$argon2id$v=19$m=1024,t=1,p=1$c29tZXNhbHQ$",
+		"$argon2id$v=18$m=1024,t=1,p=1$c29tZXNhbHQ$c29tZWtleQ",
+		"$argon2id$v=19$m=1024,t=0,p=1$c29tZXNhbHQ$c29tZWtleQ",
+		"$argon2id$v=19$garbage$c29tZXNhbHQ$c29tZWtleQ",
+	} {
+		assert.Error(t, CheckPassword("password", hashedPassword), hashedPassword)
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	bcryptHash, err := BcryptHasher{Cost: bcrypt.MinCost}.Hash("password")
+	assert.NoError(t, err)
+	argon2idHash, err := testArgon2idHasher.Hash("password")
+	assert.NoError(t, err)
+
+	assert.False(t, BcryptHasher{Cost: bcrypt.MinCost}.NeedsRehash(bcryptHash))
+	assert.True(t, BcryptHasher{Cost: bcrypt.MinCost + 1}.NeedsRehash(bcryptHash))
+	assert.True(t, BcryptHasher{Cost: bcrypt.MinCost}.NeedsRehash(argon2idHash))
+
+	assert.False(t, testArgon2idHasher.NeedsRehash(argon2idHash))
+	assert.True(t, testArgon2idHasher.NeedsRehash(bcryptHash))
+	stronger := testArgon2idHasher
+	stronger.Iterations = 2
+	assert.True(t, stronger.NeedsRehash(argon2idHash))
+}
+
+func TestNewPasswordHasher(t *testing.T) {
+	hasher, err := NewPasswordHasher(Config{})
+	assert.NoError(t, err)
+	assert.Equal(t, BcryptHasher{Cost: bcrypt.DefaultCost}, hasher)
+
+	hasher, err = NewPasswordHasher(Config{PasswordHasher: Argon2idAlgorithm, Argon2Iterations: 5})
+	assert.NoError(t, err)
+	expected := DefaultArgon2idHasher
+	expected.Iterations = 5
+	assert.Equal(t, expected, hasher)
+
+	_, err = NewPasswordHasher(Config{PasswordHasher: BcryptAlgorithm, BcryptCost: 100})
+	assert.Error(t, err)
+
+	_, err = NewPasswordHasher(Config{PasswordHasher: "md5"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,19 @@
+package util
+
+// scopes an api key can be granted
+const (
+	AccountsReadScope   = "accounts:read"
+	AccountsWriteScope  = "accounts:write"
+	TransfersWriteScope = "transfers:write"
+	UsersReadScope      = "users:read"
+	// TokensIntrospectScope is for api clients like the gateway, only admins can grant it
+	TokensIntrospectScope = "tokens:introspect"
+)
+
+func IsSupportScope(scope string) bool {
+	switch scope {
+	case AccountsReadScope, AccountsWriteScope, TransfersWriteScope, UsersReadScope, TokensIntrospectScope:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package util
+
+import (
+	"crypto/subtle"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	totpPeriod = 30
+	totpSkew   = 1
+)
+
+// GenerateTOTP returns a new base32 secret and its otpauth:// uri for authenticator apps
+func GenerateTOTP(issuer, accountName string) (secret string, uri string, err error) {
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      issuer,
+		AccountName: accountName,
+		Period:      totpPeriod,
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return key.Secret(), key.URL(), nil
+}
+
+// TOTPCode returns the code of secret at time t
+func TOTPCode(secret string, t time.Time) (string, error) {
+	return totp.GenerateCodeCustom(secret, t, totp.ValidateOpts{
+		Period:    totpPeriod,
+		Digits:    otp.DigitsSix,
+		Algorithm: otp.AlgorithmSHA1,
+	})
+}
+
+// ValidateTOTP checks code against secret at time t, allowing one period of clock skew.
+// It returns the time step the code belongs to, so callers can reject a code used twice.
+func ValidateTOTP(code, secret string, t time.Time) (int64, bool) {
+	for skew := -totpSkew; skew <= totpSkew; skew++ {
+		at := t.Add(time.Duration(skew*totpPeriod) * time.Second)
+		expected, err := TOTPCode(secret, at)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return at.Unix() / totpPeriod, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,45 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTP(t *testing.T) {
+	secret, uri, err := GenerateTOTP("SimpleBank", "alice")
+	require.NoError(t, err)
+	assert.NotEmpty(t, secret)
+	assert.Contains(t, uri, "otpauth://totp/SimpleBank:alice")
+
+	now := time.Now()
+	code, err := TOTPCode(secret, now)
+	require.NoError(t, err)
+
+	step, ok := ValidateTOTP(code, secret, now)
+	assert.True(t, ok)
+	assert.Equal(t, now.Unix()/30, step)
+
+	// one period of clock skew is tolerated
+	_, ok = ValidateTOTP(code, secret, now.Add(30*time.Second))
+	assert.True(t, ok)
+	_, ok = ValidateTOTP(code, secret, now.Add(2*time.Minute))
+	assert.False(t, ok)
+
+	_, ok = ValidateTOTP("000000", RandomString(6), now)
+	assert.False(t, ok)
+}
+
+func TestTOTPRFC6238(t *testing.T) {
+	// RFC 6238 appendix B, SHA1, truncated to 6 digits
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	code, err := TOTPCode(secret, time.Unix(59, 0))
+	require.NoError(t, err)
+	assert.Equal(t, "287082", code)
+
+	code, err = TOTPCode(secret, time.Unix(1111111109, 0))
+	require.NoError(t, err)
+	assert.Equal(t, "081804", code)
+}